@@ -0,0 +1,216 @@
+// Package goxcel renders .gxl templates into Excel .xlsx workbooks.
+//
+// It is the stable entry point for programs that embed goxcel:
+//
+//	f, _ := os.Open("invoice.gxl")
+//	defer f.Close()
+//	err := goxcel.Render(ctx, f, data, w, goxcel.WithBaseDir("templates"))
+//
+// The packages under pkg/ back the goxcel command and may change between releases. Programs
+// should only use the identifiers of this package; the types it re-exports from pkg/ (Book,
+// Logger, the error types, ...) are covered by its compatibility promise.
+package goxcel
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+
+	"github.com/ryo-arima/goxcel/pkg/config"
+	"github.com/ryo-arima/goxcel/pkg/model"
+	parser "github.com/ryo-arima/goxcel/pkg/repository"
+	"github.com/ryo-arima/goxcel/pkg/usecase"
+	"github.com/ryo-arima/goxcel/pkg/util"
+)
+
+// Errors returned by Render that callers may want to inspect with errors.As
+type (
+	UnresolvedError = usecase.UnresolvedError // A {{ expression }} did not resolve (WithStrict only)
+	ParamError      = usecase.ParamError      // The data does not satisfy the template's <Params>
+	LimitError      = config.LimitError       // The render exceeded one of its Limits
+	SandboxError    = parser.SandboxError     // A template referenced a file outside the sandbox (WithSandbox only)
+)
+
+// Types in the signatures of Render, RenderBook and WithLogger
+type (
+	Book    = model.Book  // A rendered workbook, as returned by RenderBook
+	Sheet   = model.Sheet // A sheet of a Book
+	Cell    = model.Cell  // A cell of a Sheet
+	Logger  = util.Logger // Receives parse and render log entries (see WithLogger)
+	LogCode = util.MCode  // Identifies the kind of entry passed to a Logger
+)
+
+// Limits bounds the work done by a single render. Zero values use the defaults: an import
+// depth of 10 and no other limit.
+type Limits = config.Limits
+
+// Render parses the .gxl template read from tmpl, renders it with data and writes the
+// resulting .xlsx package to w.
+//
+// data may be nil, a map[string]any, or any value that encodes to a JSON object (such as a
+// struct with json tags). Relative paths in the template (imports, includes, base templates)
+// resolve from the base directory, which defaults to the current directory.
+//
+// Rendering stops when ctx is cancelled. Exceeding a limit set with WithLimits fails with a
+// *LimitError.
+func Render(ctx context.Context, tmpl io.Reader, data any, w io.Writer, opts ...Option) error {
+	conf := newConfig(opts)
+	book, err := renderBook(ctx, conf, tmpl, data)
+	if err != nil {
+		return err
+	}
+	if err := parser.WriteBookWithConfig(book, w, conf); err != nil {
+		return fmt.Errorf("goxcel: write xlsx: %w", err)
+	}
+	return nil
+}
+
+// RenderFS renders the template name (a slash-separated path) read from fsys, such as an
+// embed.FS or a zip archive. Imports, includes and base templates are read from fsys as well,
+// relative to the template's directory.
+func RenderFS(ctx context.Context, fsys fs.FS, name string, data any, w io.Writer, opts ...Option) error {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return fmt.Errorf("goxcel: open template: %w", err)
+	}
+	defer f.Close()
+
+	opts = append([]Option{WithFS(fsys), WithBaseDir(path.Dir(name))}, opts...)
+	return Render(ctx, f, data, w, opts...)
+}
+
+// RenderBook is like Render but returns the rendered workbook model instead of writing it.
+func RenderBook(ctx context.Context, tmpl io.Reader, data any, opts ...Option) (*Book, error) {
+	return renderBook(ctx, newConfig(opts), tmpl, data)
+}
+
+// renderBook parses and renders a template with the given configuration
+func renderBook(ctx context.Context, conf config.BaseConfig, tmpl io.Reader, data any) (*Book, error) {
+	gxl, err := parser.ReadGxlFromReader(tmpl, conf.Logger)
+	if err != nil {
+		return nil, fmt.Errorf("goxcel: parse template: %w", err)
+	}
+
+	normalized, err := normalizeData(data)
+	if err != nil {
+		return nil, err
+	}
+
+	book, err := usecase.NewBookUsecase(conf).Render(ctx, &gxl, normalized)
+	if err != nil {
+		return nil, fmt.Errorf("goxcel: render: %w", err)
+	}
+	return book, nil
+}
+
+// normalizeData converts data to the map form used by the renderer
+func normalizeData(data any) (any, error) {
+	switch data.(type) {
+	case nil, map[string]any:
+		return data, nil
+	}
+
+	b, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("goxcel: encode data: %w", err)
+	}
+	var m map[string]any
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("goxcel: data must encode to a JSON object, got %T", data)
+	}
+	return m, nil
+}
+
+// Option configures Render and RenderBook
+type Option func(*options)
+
+// options holds the settings collected from Option values
+type options struct {
+	baseDir         string
+	fsys            fs.FS
+	logger          Logger
+	strict          bool
+	limits          Limits
+	sheetNamePolicy string
+	sandbox         string
+}
+
+// newConfig applies opts to the defaults and builds the renderer configuration
+func newConfig(opts []Option) config.BaseConfig {
+	o := options{baseDir: ".", logger: util.NewNopLogger()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return config.BaseConfig{
+		Logger:          o.logger,
+		BaseDir:         o.baseDir,
+		SheetNamePolicy: o.sheetNamePolicy,
+		Strict:          o.strict,
+		Limits:          o.limits,
+		FS:              o.fsys,
+		Sandbox:         o.sandbox,
+	}
+}
+
+// WithBaseDir sets the directory that relative paths in the template resolve from.
+func WithBaseDir(dir string) Option {
+	return func(o *options) {
+		if dir != "" {
+			o.baseDir = dir
+		}
+	}
+}
+
+// WithFS reads imported, included and base templates from fsys instead of the OS filesystem.
+// The base directory is then a slash-separated path within fsys, and paths cannot leave it.
+func WithFS(fsys fs.FS) Option {
+	return func(o *options) {
+		o.fsys = fsys
+	}
+}
+
+// WithLogger sets the logger for parsing and rendering. By default nothing is logged.
+func WithLogger(logger Logger) Option {
+	return func(o *options) {
+		if logger != nil {
+			o.logger = logger
+		}
+	}
+}
+
+// WithStrict makes the render fail with an *UnresolvedError when any {{ expression }} in
+// a cell, sheet name or style attribute does not resolve, instead of leaving it empty.
+func WithStrict(strict bool) Option {
+	return func(o *options) {
+		o.strict = strict
+	}
+}
+
+// WithLimits sets resource limits for the render: import depth and files, cells, rows, loop
+// iterations, output size and a timeout.
+func WithLimits(limits Limits) Option {
+	return func(o *options) {
+		o.limits = limits
+	}
+}
+
+// WithSheetNamePolicy sets how invalid or duplicate sheet names are handled:
+// "error" (the default) or "suffix".
+func WithSheetNamePolicy(policy string) Option {
+	return func(o *options) {
+		o.sheetNamePolicy = policy
+	}
+}
+
+// WithSandbox confines the files a template references on the OS filesystem (imports,
+// includes, base templates and images) to the directory root. A path that leaves it, also
+// through a symlink, fails the render with a *SandboxError. It has no effect with WithFS,
+// whose paths cannot leave the filesystem anyway.
+func WithSandbox(root string) Option {
+	return func(o *options) {
+		o.sandbox = root
+	}
+}
@@ -1,6 +1,7 @@
 package command
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
@@ -16,16 +17,31 @@ func NewRootCmd() *cobra.Command {
 		Long:  "goxcel is a CLI to render .gxl templates with data into Excel .xlsx files.",
 	}
 
+	controller.AddGlobalFlags(root)
+
 	// Subcommands
 	root.AddCommand(controller.InitGenerateCmd())
 	root.AddCommand(controller.InitFormatCmd())
 	root.AddCommand(controller.InitGetCmd())
+	root.AddCommand(controller.InitDescribeCmd())
+	root.AddCommand(controller.InitConvertCmd())
+	root.AddCommand(controller.InitExtractCmd())
+	root.AddCommand(controller.InitDiffCmd())
+	root.AddCommand(controller.InitNewCmd())
 	return root
 }
 
 // Execute runs the CLI.
 func Execute() {
 	if err := NewRootCmd().Execute(); err != nil {
+		// Commands such as diff choose their exit code
+		var exitErr *controller.ExitError
+		if errors.As(err, &exitErr) {
+			if exitErr.Err != nil {
+				fmt.Fprintln(os.Stderr, exitErr.Err)
+			}
+			os.Exit(exitErr.Code)
+		}
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
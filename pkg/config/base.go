@@ -1,12 +1,96 @@
 package config
 
-import "github.com/ryo-arima/goxcel/pkg/util"
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"time"
+
+	"github.com/ryo-arima/goxcel/pkg/util"
+)
+
+// Sheet name policies control how invalid or duplicate sheet names are handled at render time.
+const (
+	SheetNamePolicyError  = "error"  // Reject invalid or duplicate names (default)
+	SheetNamePolicySuffix = "suffix" // Sanitize invalid names and de-duplicate with " (2)", " (3)", ...
+)
+
+// DefaultMaxImportDepth is the import nesting limit used when Limits.MaxImportDepth is zero.
+const DefaultMaxImportDepth = 10
+
+// Limits bounds the work done by a single render. Zero values use the defaults: an import
+// depth of DefaultMaxImportDepth and no other limit.
+type Limits struct {
+	MaxImportDepth    int           `yaml:"max_import_depth"`    // Maximum nesting of imports, includes and extends (default DefaultMaxImportDepth)
+	MaxCells          int           `yaml:"max_cells"`           // Maximum number of cells in the workbook
+	MaxRows           int           `yaml:"max_rows"`            // Maximum row number on any sheet
+	MaxLoopIterations int           `yaml:"max_loop_iterations"` // Maximum iterations of all loops together
+	MaxImportFiles    int           `yaml:"max_import_files"`    // Maximum number of distinct imported, included and base template files
+	MaxOutputBytes    int64         `yaml:"max_output_bytes"`    // Maximum size of the written .xlsx package
+	Timeout           time.Duration `yaml:"timeout"`             // Wall-clock limit for the render (e.g. "30s" in YAML)
+}
+
+// ImportDepth returns the effective import nesting limit.
+func (l Limits) ImportDepth() int {
+	if l.MaxImportDepth > 0 {
+		return l.MaxImportDepth
+	}
+	return DefaultMaxImportDepth
+}
+
+// Names of the limits reported by LimitError
+const (
+	LimitImportDepth    = "import depth"
+	LimitCells          = "cell"
+	LimitRows           = "row"
+	LimitLoopIterations = "loop iteration"
+	LimitImportFiles    = "import file"
+	LimitOutputBytes    = "output size"
+	LimitTimeout        = "time"
+)
+
+// LimitError reports that a render exceeded one of its Limits.
+type LimitError struct {
+	Limit string // One of the Limit* names
+	Max   int64  // The configured maximum (bytes for LimitOutputBytes, nanoseconds for LimitTimeout)
+}
+
+func (e *LimitError) Error() string {
+	switch e.Limit {
+	case LimitOutputBytes:
+		return fmt.Sprintf("%s limit exceeded (max %d bytes)", e.Limit, e.Max)
+	case LimitTimeout:
+		return fmt.Sprintf("%s limit exceeded (max %s)", e.Limit, time.Duration(e.Max))
+	}
+	return fmt.Sprintf("%s limit exceeded (max %d)", e.Limit, e.Max)
+}
+
+// Unwrap lets errors.Is(err, context.DeadlineExceeded) match a timeout
+func (e *LimitError) Unwrap() error {
+	if e.Limit == LimitTimeout {
+		return context.DeadlineExceeded
+	}
+	return nil
+}
 
 // BaseConfig is a placeholder configuration root. Extend as needed.
 type BaseConfig struct {
-	FilePath string      // Path to the .gxl template file
-	Logger   util.Logger // Logger instance
-	BaseDir  string      // Base directory for resolving relative imports
+	FilePath        string      // Path to the .gxl template file
+	Logger          util.Logger // Logger instance
+	BaseDir         string      // Base directory for resolving relative imports
+	SheetNamePolicy string      // How invalid or duplicate sheet names are handled (empty means "error")
+	Strict          bool        // Fail the render when a {{ expression }} does not resolve
+	Limits          Limits      // Resource limits for rendering
+	FS              fs.FS       // Filesystem for templates and imports (nil means the OS filesystem); paths are slash-separated
+	Sandbox         string      // Directory that every file referenced by a template must stay inside (empty disables the sandbox)
+
+	// Project settings, usually read from .goxcel.yaml (see ProjectConfig)
+	TemplatesDir string         // Directory of user templates (empty means the default user templates directory)
+	DataPaths    []string       // Directories searched for relative data files not found in the working directory
+	OutputDir    string         // Directory for relative output paths
+	Locale       string         // Language tag recorded in the workbook
+	Location     *time.Location // Time zone for timestamps in the data (nil keeps their own zone)
+	Writer       WriterOptions  // Workbook writer options
 }
 
 // NewBaseConfig returns a default config instance.
@@ -17,7 +101,7 @@ func NewBaseConfig() BaseConfig {
 		Level:        "INFO",
 		Structured:   false,
 		EnableCaller: false,
-		Output:       "stdout",
+		Output:       "stderr",
 	})
 	return BaseConfig{Logger: logger, BaseDir: "."}
 }
@@ -30,7 +114,7 @@ func NewBaseConfigWithFile(filePath string) BaseConfig {
 		Level:        "INFO",
 		Structured:   false,
 		EnableCaller: false,
-		Output:       "stdout",
+		Output:       "stderr",
 	})
 	return BaseConfig{FilePath: filePath, Logger: logger, BaseDir: extractBaseDirFromPath(filePath)}
 }
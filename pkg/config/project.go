@@ -0,0 +1,187 @@
+package config
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProjectConfigFile is the name of the project configuration file, looked up from the template
+// directory towards the filesystem root.
+const ProjectConfigFile = ".goxcel.yaml"
+
+// Compression levels accepted by WriterOptions.Compression
+const (
+	CompressionDefault = "default"
+	CompressionNone    = "none"
+	CompressionFast    = "fast"
+	CompressionBest    = "best"
+)
+
+// WriterOptions controls how workbooks are written.
+type WriterOptions struct {
+	Compression string `yaml:"compression"` // Zip compression: default, none, fast or best (empty means default)
+	Creator     string `yaml:"creator"`     // Author recorded in the document properties
+}
+
+// CompressionLevel returns the compress/flate level for the compression setting.
+func (o WriterOptions) CompressionLevel() (int, error) {
+	switch strings.ToLower(o.Compression) {
+	case "", CompressionDefault:
+		return flate.DefaultCompression, nil
+	case CompressionNone:
+		return flate.NoCompression, nil
+	case CompressionFast:
+		return flate.BestSpeed, nil
+	case CompressionBest:
+		return flate.BestCompression, nil
+	}
+	return 0, fmt.Errorf("unknown compression %q (want %s, %s, %s or %s)", o.Compression, CompressionDefault, CompressionNone, CompressionFast, CompressionBest)
+}
+
+// ProjectLogConfig holds the log settings of a project configuration
+type ProjectLogConfig struct {
+	Level  string `yaml:"level"`  // debug, info, warn or error
+	Format string `yaml:"format"` // text or json
+}
+
+// ProjectConfig holds the defaults read from a .goxcel.yaml file. Command-line flags take
+// precedence over every setting. Relative paths are resolved from the file's directory.
+type ProjectConfig struct {
+	Path            string           `yaml:"-"`             // File the configuration was read from (empty if none)
+	TemplatesDir    string           `yaml:"templates_dir"` // Replaces the user templates directory
+	DataPaths       []string         `yaml:"data_paths"`    // Directories searched for relative data files
+	Strict          bool             `yaml:"strict"`
+	SheetNamePolicy string           `yaml:"sheet_names"`
+	Locale          string           `yaml:"locale"`   // Language tag recorded in the workbook (e.g. ja-JP)
+	Timezone        string           `yaml:"timezone"` // IANA zone that timestamps in the data are shown in
+	Log             ProjectLogConfig `yaml:"log"`
+	OutputDir       string           `yaml:"output_dir"` // Directory for relative output paths
+	Writer          WriterOptions    `yaml:"writer"`
+	Sandbox         string           `yaml:"sandbox"` // Directory that template file references must stay inside (only from --config or the working directory)
+	Limits          Limits           `yaml:"limits"`
+}
+
+// FindProjectConfig returns the path of the nearest .goxcel.yaml in dir or one of its parents,
+// or "" if there is none.
+func FindProjectConfig(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		candidate := filepath.Join(dir, ProjectConfigFile)
+		st, err := os.Stat(candidate)
+		switch {
+		case err == nil && !st.IsDir():
+			return candidate, nil
+		case err != nil && !errors.Is(err, fs.ErrNotExist):
+			return "", err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// LoadProjectConfig reads and validates a project configuration file.
+func LoadProjectConfig(path string) (ProjectConfig, error) {
+	var pc ProjectConfig
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return pc, fmt.Errorf("read config: %w", err)
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(&pc); err != nil && !errors.Is(err, io.EOF) {
+		return pc, fmt.Errorf("parse %s: %w", path, err)
+	}
+	pc.Path = path
+
+	// Paths in the file are relative to the file itself, not to the working directory
+	dir := filepath.Dir(path)
+	rel := func(p string) string {
+		if p == "" || filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(dir, p)
+	}
+	pc.TemplatesDir = rel(pc.TemplatesDir)
+	pc.OutputDir = rel(pc.OutputDir)
+	pc.Sandbox = rel(pc.Sandbox)
+	for i, p := range pc.DataPaths {
+		pc.DataPaths[i] = rel(p)
+	}
+
+	if err := pc.validate(); err != nil {
+		return pc, fmt.Errorf("%s: %w", path, err)
+	}
+	return pc, nil
+}
+
+// validate checks the settings that have a fixed set of values
+func (pc ProjectConfig) validate() error {
+	switch pc.SheetNamePolicy {
+	case "", SheetNamePolicyError, SheetNamePolicySuffix:
+	default:
+		return fmt.Errorf("sheet_names must be %s or %s, got %q", SheetNamePolicyError, SheetNamePolicySuffix, pc.SheetNamePolicy)
+	}
+	switch strings.ToLower(pc.Log.Level) {
+	case "", "debug", "info", "warn", "error":
+	default:
+		return fmt.Errorf("log.level must be debug, info, warn or error, got %q", pc.Log.Level)
+	}
+	switch strings.ToLower(pc.Log.Format) {
+	case "", "text", "json":
+	default:
+		return fmt.Errorf("log.format must be text or json, got %q", pc.Log.Format)
+	}
+	if _, err := pc.Location(); err != nil {
+		return err
+	}
+	if _, err := pc.Writer.CompressionLevel(); err != nil {
+		return fmt.Errorf("writer: %w", err)
+	}
+	return nil
+}
+
+// Location returns the configured time zone (nil when none is set).
+func (pc ProjectConfig) Location() (*time.Location, error) {
+	if pc.Timezone == "" {
+		return nil, nil
+	}
+	loc, err := time.LoadLocation(pc.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("timezone: %w", err)
+	}
+	return loc, nil
+}
+
+// Apply copies the settings that have no command-line flag into conf.
+func (pc ProjectConfig) Apply(conf *BaseConfig) error {
+	loc, err := pc.Location()
+	if err != nil {
+		return err
+	}
+	conf.TemplatesDir = pc.TemplatesDir
+	conf.DataPaths = pc.DataPaths
+	conf.OutputDir = pc.OutputDir
+	conf.Locale = pc.Locale
+	conf.Location = loc
+	conf.Writer = pc.Writer
+	conf.Limits = pc.Limits
+	conf.Sandbox = pc.Sandbox
+	return nil
+}
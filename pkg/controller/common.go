@@ -4,9 +4,15 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/ryo-arima/goxcel/pkg/config"
 	"github.com/ryo-arima/goxcel/pkg/model"
@@ -22,9 +28,18 @@ func InitGenerateCmd() *cobra.Command {
 	var (
 		templatePath string
 		templateName string
-		dataPath     string
+		dataPaths    []string
+		sets         []string
+		setStrings   []string
 		outputPath   string
 		dryRun       bool
+		sheetNames   string
+		schemaPath   string
+		strict       bool
+		timeout      time.Duration
+		sandbox      string
+		basePath     string
+		csvOpts      CSVOptions
 	)
 
 	cmd := &cobra.Command{
@@ -32,116 +47,194 @@ func InitGenerateCmd() *cobra.Command {
 		Short: "Generate a .gxl template to .xlsx",
 		Long:  "Generate a .gxl template with optional JSON or YAML data into an Excel .xlsx file.",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			// Handle template name (from .etc/templates)
-			if templateName != "" {
-				templatesDir := ".etc/templates"
-				templatePath = filepath.Join(templatesDir, templateName, "base.gxl")
-				
-				// Check if template exists
-				if _, err := os.Stat(templatePath); err != nil {
-					return fmt.Errorf("template '%s' not found in %s", templateName, templatesDir)
-				}
-				
-				// Auto-load data file if exists
-				if dataPath == "" {
-					dataFile := filepath.Join(templatesDir, templateName, "base.yaml")
-					if _, err := os.Stat(dataFile); err == nil {
-						dataPath = dataFile
-					}
-				}
-			} else if templatePath == "" && len(args) > 0 {
+			if templateName == "" && templatePath == "" && len(args) > 0 {
 				templatePath = args[0]
 			}
-			
-			if strings.TrimSpace(templatePath) == "" {
+
+			if templateName == "" && strings.TrimSpace(templatePath) == "" {
 				return fmt.Errorf("template path is required (pass as arg, --template, or --template-name)")
 			}
-			if err := RunGenerate(templatePath, dataPath, outputPath, dryRun); err != nil {
+
+			// Project settings apply where no flag was given
+			configDir := "."
+			if templateName == "" && templatePath != StdioPath {
+				configDir = filepath.Dir(templatePath)
+			}
+			project, err := loadTemplateProjectConfig(cmd, configDir)
+			if err != nil {
+				return err
+			}
+			logOpts, err := logOptions(cmd)
+			if err != nil {
+				return err
+			}
+			if !cmd.Flags().Changed("strict") {
+				strict = project.Strict
+			}
+			if !cmd.Flags().Changed("sheet-names") && project.SheetNamePolicy != "" {
+				sheetNames = project.SheetNamePolicy
+			}
+
+			opts := GenerateOptions{
+				TemplatePath:    templatePath,
+				TemplateName:    templateName,
+				DataPaths:       dataPaths,
+				Sets:            sets,
+				SetStrings:      setStrings,
+				OutputPath:      outputPath,
+				DryRun:          dryRun,
+				SheetNamePolicy: sheetNames,
+				SchemaPath:      schemaPath,
+				Strict:          strict,
+				Timeout:         timeout,
+				Sandbox:         sandbox,
+				BasePath:        basePath,
+				CSV:             csvOpts,
+				Project:         project,
+				Log:             logOpts,
+				Stdin:           cmd.InOrStdin(),
+				Stdout:          cmd.OutOrStdout(),
+			}
+			if err := RunGenerateWithOptions(opts); err != nil {
 				return err
 			}
 			return nil
 		},
 	}
 
-	cmd.Flags().StringVarP(&templatePath, "template", "t", "", "path to .gxl template file")
-	cmd.Flags().StringVar(&templateName, "template-name", "", "template name from .etc/templates (e.g., 'b4-landscape')")
-	cmd.Flags().StringVarP(&dataPath, "data", "d", "", "path to JSON or YAML data file (optional)")
-	cmd.Flags().StringVarP(&outputPath, "output", "o", "", "output .xlsx file path (optional; if empty with --dry-run prints summary)")
+	cmd.Flags().StringVarP(&templatePath, "template", "t", "", "path to .gxl template file (\"-\" reads it from stdin)")
+	cmd.Flags().StringVar(&templateName, "template-name", "", "built-in or user template name (e.g., 'b4-landscape'; see 'goxcel get templates'); its preview data is used when no data is given")
+	cmd.Flags().StringArrayVarP(&dataPaths, "data", "d", nil, "JSON, YAML, CSV or TSV data file (\"-\" reads JSON or YAML from stdin); repeat to merge several files, or use key=path to mount a file under a key")
+	cmd.Flags().StringArrayVar(&sets, "set", nil, "override a data value (e.g. invoice.total=120); the value is parsed as YAML")
+	cmd.Flags().StringArrayVar(&setStrings, "set-string", nil, "override a data value with a string (e.g. invoice.number=0012)")
+	cmd.Flags().StringVarP(&outputPath, "output", "o", "", "output .xlsx file path (\"-\" writes to stdout; if empty with --dry-run prints summary)")
 	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "do not write .xlsx; print a summary instead")
+	cmd.Flags().StringVar(&sheetNames, "sheet-names", config.SheetNamePolicyError, "how to handle invalid or duplicate sheet names: error or suffix")
+	cmd.Flags().StringVar(&csvOpts.Delimiter, "csv-delimiter", "", "field delimiter for .csv/.tsv data (default ',' for CSV, tab for TSV; 'tab' and '\\t' mean tab)")
+	cmd.Flags().StringVar(&csvOpts.Encoding, "csv-encoding", "", "text encoding of .csv/.tsv data: utf-8 (default) or shift_jis")
+	cmd.Flags().StringVar(&csvOpts.Key, "csv-key", gxlrepo.DefaultCSVKey, "data key under which .csv/.tsv rows are exposed")
+	cmd.Flags().BoolVar(&csvOpts.Raw, "csv-raw", false, "keep .csv/.tsv fields as strings instead of inferring numbers, booleans and dates")
+	cmd.Flags().BoolVar(&strict, "strict", false, "fail when a {{ expression }} does not resolve instead of leaving it empty")
+	cmd.Flags().DurationVar(&timeout, "timeout", 0, "abort rendering after this long (e.g. 30s; overrides limits.timeout in .goxcel.yaml)")
+	cmd.Flags().StringVar(&basePath, "base", "", "existing .xlsx workbook to write the rendered sheets into (overrides the template's <Book base>)")
+	cmd.Flags().StringVar(&sandbox, "sandbox", "", "confine every file read (template, data, schema, imports, includes, images) to this directory (overrides sandbox in .goxcel.yaml)")
+	cmd.Flags().StringVar(&schemaPath, "schema", "", "JSON Schema file to validate the data against before rendering (overrides the template's <Header schema>)")
 	return cmd
 }
 
-// RunGenerate executes the generate command logic
-func RunGenerate(templatePath, dataPath, outputPath string, dryRun bool) error {
-	// Create config with file path
-	conf := config.NewBaseConfigWithFile(templatePath)
-	conf.Logger.DEBUG(util.CI1, "Starting generate command", map[string]interface{}{"template": templatePath, "data": dataPath, "output": outputPath, "dry_run": dryRun})
+// GenerateOptions holds the inputs of the generate command
+type GenerateOptions struct {
+	TemplatePath    string        // Path to the .gxl template ("-" reads it from Stdin)
+	TemplateName    string        // Built-in or user template to render instead of TemplatePath
+	DataPath        string        // Optional data file, loaded before DataPaths
+	DataPaths       []string      // Further data files ("path" or "key=path"), deep-merged in order
+	Sets            []string      // "path=value" overrides applied after the data files (values parsed as YAML)
+	SetStrings      []string      // "path=value" overrides applied last, values kept as strings
+	OutputPath      string        // Output .xlsx path ("-" writes to Stdout; empty prints a summary)
+	DryRun          bool          // Print a summary instead of writing
+	SheetNamePolicy string        // Sheet name policy (config.SheetNamePolicyError or config.SheetNamePolicySuffix)
+	SchemaPath      string        // Optional JSON Schema for the data (defaults to the template's <Header schema>)
+	Strict          bool          // Fail on unresolved {{ expressions }}
+	Timeout         time.Duration // Render time limit (zero keeps the project setting)
+	Sandbox         string        // Directory that all files read must stay inside (empty keeps the project setting)
+	BasePath        string        // Optional .xlsx workbook the sheets are written into (overrides <Book base>)
+	CSV             CSVOptions
+	Project         config.ProjectConfig // Settings from .goxcel.yaml without a flag of their own
+	Log             LogOptions           // Global logging flags (logs go to stderr by default)
+	Stdin           io.Reader            // Source for a "-" template or data path (nil means os.Stdin)
+	Stdout          io.Writer            // Destination for a "-" output path (nil means os.Stdout)
+}
+
+// StdioPath is the path that stands for standard input (template, data) or standard output (output)
+const StdioPath = "-"
 
-	// Validate template file existence early for clearer error
-	if _, statErr := os.Stat(templatePath); statErr != nil {
-		conf.Logger.ERROR(util.FSR2, "Template file not found")
-		return fmt.Errorf("template not found: %w", statErr)
+// stdin returns the reader used for "-" inputs
+func (opts GenerateOptions) stdin() io.Reader {
+	if opts.Stdin != nil {
+		return opts.Stdin
 	}
+	return os.Stdin
+}
 
-	// Read and parse template via repository
-	repo := gxlrepo.NewGxlRepository(conf)
-	gt, err := repo.ReadGxl()
-	if err != nil {
-		conf.Logger.ERROR(util.RP2, "Failed to read GXL template")
-		return fmt.Errorf("read gxl via repository: %w", err)
+// stdout returns the writer used for a "-" output
+func (opts GenerateOptions) stdout() io.Writer {
+	if opts.Stdout != nil {
+		return opts.Stdout
 	}
-	conf.Logger.DEBUG(util.GXLP1, "GXL template parsed successfully", map[string]interface{}{"sheets": len(gt.Sheets)})
+	return os.Stdout
+}
 
-	// Load data (optional)
-	var data any
-	if strings.TrimSpace(dataPath) != "" {
-		conf.Logger.DEBUG(util.FSR1, "Reading data file", map[string]interface{}{"file": dataPath})
-		db, err := os.ReadFile(dataPath)
-		if err != nil {
-			conf.Logger.ERROR(util.FSR2, "Failed to read data file")
-			return fmt.Errorf("read data: %w", err)
+// stdinUsers counts the inputs that read from standard input
+func (opts GenerateOptions) stdinUsers() int {
+	n := 0
+	if opts.TemplatePath == StdioPath {
+		n++
+	}
+	for _, source := range opts.dataSources() {
+		if _, path := splitDataSource(source); path == StdioPath {
+			n++
 		}
-		var m map[string]any
+	}
+	return n
+}
 
-		// Determine file format by extension
-		ext := strings.ToLower(filepath.Ext(dataPath))
-		switch ext {
-		case ".yaml", ".yml":
-			if err := yaml.Unmarshal(db, &m); err != nil {
-				conf.Logger.ERROR(util.FSR2, "Failed to parse data YAML")
-				return fmt.Errorf("parse data yaml: %w", err)
-			}
-			conf.Logger.DEBUG(util.FSR1, "YAML data loaded successfully", nil)
-		case ".json":
-			if err := json.Unmarshal(db, &m); err != nil {
-				conf.Logger.ERROR(util.FSR2, "Failed to parse data JSON")
-				return fmt.Errorf("parse data json: %w", err)
-			}
-			conf.Logger.DEBUG(util.FSR1, "JSON data loaded successfully", nil)
-		default:
-			// Try JSON first, then YAML
-			if err := json.Unmarshal(db, &m); err != nil {
-				if err := yaml.Unmarshal(db, &m); err != nil {
-					conf.Logger.ERROR(util.FSR2, "Failed to parse data as JSON or YAML")
-					return fmt.Errorf("parse data (tried JSON and YAML): %w", err)
-				}
-				conf.Logger.DEBUG(util.FSR1, "Data loaded successfully as YAML", nil)
-			} else {
-				conf.Logger.DEBUG(util.FSR1, "Data loaded successfully as JSON", nil)
-			}
-		}
-		data = m
+// CSVOptions controls how .csv and .tsv data files are read
+type CSVOptions struct {
+	Delimiter string // Single character, "tab" or "\t" (empty uses the format's default)
+	Encoding  string // utf-8 (default) or shift_jis
+	Key       string // Data key for the rows (empty means "rows")
+	Raw       bool   // Disable type inference
+}
+
+// dataOptions converts the CSV options to repository data options
+func (opts GenerateOptions) dataOptions() (gxlrepo.DataOptions, error) {
+	dataOpts := gxlrepo.DataOptions{
+		Encoding: opts.CSV.Encoding,
+		Key:      opts.CSV.Key,
+		NoInfer:  opts.CSV.Raw,
+	}
+	switch d := opts.CSV.Delimiter; {
+	case d == "":
+	case d == "tab" || d == `\t`:
+		dataOpts.Delimiter = '\t'
+	case utf8.RuneCountInString(d) == 1:
+		dataOpts.Delimiter, _ = utf8.DecodeRuneInString(d)
+	default:
+		return dataOpts, fmt.Errorf("csv delimiter must be a single character, got %q", d)
 	}
+	return dataOpts, nil
+}
 
-	// Generate
-	conf.Logger.DEBUG(util.UR1, "Rendering template")
-	bookUsecase := usecase.NewBookUsecase(conf)
-	book, err := bookUsecase.Render(context.Background(), &gt, data)
+// RunGenerate executes the generate command logic
+func RunGenerate(templatePath, dataPath, outputPath string, dryRun bool) error {
+	return RunGenerateWithOptions(GenerateOptions{
+		TemplatePath: templatePath,
+		DataPath:     dataPath,
+		OutputPath:   outputPath,
+		DryRun:       dryRun,
+	})
+}
+
+// RunGenerateWithOptions executes the generate command logic with the full option set
+func RunGenerateWithOptions(opts GenerateOptions) error {
+	templatePath, outputPath, dryRun := opts.TemplatePath, opts.OutputPath, opts.DryRun
+
+	conf, err := newGenerateConfig(opts, "cli", "INFO")
 	if err != nil {
-		conf.Logger.ERROR(util.UR2, "Failed to render template")
-		return fmt.Errorf("generate: %w", err)
+		return err
+	}
+	if conf.OutputDir != "" && outputPath != "" && outputPath != StdioPath && !filepath.IsAbs(outputPath) {
+		outputPath = filepath.Join(conf.OutputDir, outputPath)
+	}
+	if opts.Project.Path != "" {
+		conf.Logger.DEBUG(util.FSR1, "Using project configuration", map[string]interface{}{"file": opts.Project.Path})
+	}
+	conf.Logger.DEBUG(util.CI1, "Starting generate command", map[string]interface{}{"template": templatePath, "data": opts.dataSources(), "output": outputPath, "dry_run": dryRun})
+
+	book, conf, err := renderBook(conf, opts)
+	if err != nil {
+		return err
 	}
-	conf.Logger.DEBUG(util.UR1, "Template rendered successfully", map[string]interface{}{"sheets": len(book.Sheets)})
 
 	// Dry run summary or write
 	if dryRun || strings.TrimSpace(outputPath) == "" {
@@ -150,6 +243,16 @@ func RunGenerate(templatePath, dataPath, outputPath string, dryRun bool) error {
 		return nil
 	}
 
+	if outputPath == StdioPath {
+		conf.Logger.DEBUG(util.RW1, "Writing XLSX to stdout")
+		if err := gxlrepo.WriteBookWithConfig(book, opts.stdout(), conf); err != nil {
+			conf.Logger.ERROR(util.RW2, "Failed to write XLSX to stdout")
+			return fmt.Errorf("write xlsx: %w", err)
+		}
+		conf.Logger.INFO(util.CC1, "Successfully generated workbook on stdout")
+		return nil
+	}
+
 	// Write XLSX file
 	conf.Logger.DEBUG(util.RW1, "Writing XLSX file", map[string]interface{}{"output": outputPath})
 	// Ensure output directory exists
@@ -162,7 +265,7 @@ func RunGenerate(templatePath, dataPath, outputPath string, dryRun bool) error {
 		conf.Logger.DEBUG(util.FSM1, "Created output directory", map[string]interface{}{"dir": outDir})
 	}
 
-	if err := gxlrepo.WriteBookToFile(book, outputPath); err != nil {
+	if err := gxlrepo.WriteBookToFileWithConfig(book, outputPath, conf); err != nil {
 		conf.Logger.ERROR(util.RW2, "Failed to write XLSX file")
 		return fmt.Errorf("write xlsx: %w", err)
 	}
@@ -171,6 +274,309 @@ func RunGenerate(templatePath, dataPath, outputPath string, dryRun bool) error {
 	return nil
 }
 
+// newGenerateConfig creates the render config of a generate run: the template path, the
+// project settings and the flags that override them, and a logger for service
+func newGenerateConfig(opts GenerateOptions, service, defaultLevel string) (config.BaseConfig, error) {
+	// Create config with file path (a template on stdin resolves imports from the working directory)
+	conf := config.NewBaseConfigWithFile(opts.TemplatePath)
+	conf.SheetNamePolicy = opts.SheetNamePolicy
+	conf.Strict = opts.Strict
+	if err := opts.Project.Apply(&conf); err != nil {
+		return conf, err
+	}
+	if opts.Timeout > 0 {
+		conf.Limits.Timeout = opts.Timeout
+	}
+	if opts.Sandbox != "" {
+		conf.Sandbox = opts.Sandbox
+	}
+	conf.Logger = opts.Log.newLogger(service, defaultLevel, opts.Project.Log)
+	return conf, nil
+}
+
+// renderBook reads the template and the data and renders the workbook. The returned config
+// is the one to write the workbook with (a named template or a base workbook changes its filesystem).
+func renderBook(conf config.BaseConfig, opts GenerateOptions) (*model.Book, config.BaseConfig, error) {
+	templatePath := opts.TemplatePath
+	if opts.stdinUsers() > 1 {
+		return nil, conf, fmt.Errorf("only one of the template and data files can be read from stdin (%q)", StdioPath)
+	}
+
+	var (
+		gt       model.GXL
+		defaults map[string]any
+		err      error
+	)
+	if opts.TemplateName != "" {
+		// Render a built-in or user template from its own filesystem
+		templateFS, info, err := gxlrepo.NewTemplateRepository(conf).OpenTemplate(opts.TemplateName)
+		if err != nil {
+			conf.Logger.ERROR(util.FSR2, "Template not found")
+			return nil, conf, err
+		}
+		conf.FS, conf.FilePath, conf.BaseDir = templateFS, gxlrepo.TemplateBaseFile, "."
+		conf.Logger.DEBUG(util.FSR1, "Using named template", map[string]interface{}{"template": info.Name, "source": info.Source})
+		if gt, err = gxlrepo.NewGxlRepository(conf).ReadGxl(); err != nil {
+			conf.Logger.ERROR(util.RP2, "Failed to read GXL template")
+			return nil, conf, fmt.Errorf("read template %q: %w", opts.TemplateName, err)
+		}
+
+		// The template's sample data is used when no data is given
+		if len(opts.dataSources()) == 0 {
+			if defaults, err = readTemplateDefaults(templateFS, info.Manifest); err != nil {
+				return nil, conf, fmt.Errorf("read template %q: %w", opts.TemplateName, err)
+			}
+		}
+	} else if templatePath == StdioPath {
+		conf.Logger.DEBUG(util.FSR1, "Reading GXL template from stdin")
+		if gt, err = gxlrepo.ReadGxlFromReader(opts.stdin(), conf.Logger); err != nil {
+			conf.Logger.ERROR(util.RP2, "Failed to read GXL template")
+			return nil, conf, fmt.Errorf("read gxl from stdin: %w", err)
+		}
+	} else {
+		// Validate template file existence early for clearer error
+		if _, statErr := os.Stat(templatePath); statErr != nil {
+			conf.Logger.ERROR(util.FSR2, "Template file not found")
+			return nil, conf, fmt.Errorf("template not found: %w", statErr)
+		}
+		if err := checkSandbox(conf, templatePath); err != nil {
+			return nil, conf, err
+		}
+
+		// Read and parse template via repository
+		repo := gxlrepo.NewGxlRepository(conf)
+		if gt, err = repo.ReadGxl(); err != nil {
+			conf.Logger.ERROR(util.RP2, "Failed to read GXL template")
+			return nil, conf, fmt.Errorf("read gxl via repository: %w", err)
+		}
+	}
+	conf.Logger.DEBUG(util.GXLP1, "GXL template parsed successfully", map[string]interface{}{"sheets": len(gt.Sheets)})
+
+	// Merge the template with its base template(s), which may declare parameters and the schema
+	bookUsecase := usecase.NewBookUsecase(conf)
+	merged, err := bookUsecase.ResolveExtends(&gt)
+	if err != nil {
+		conf.Logger.ERROR(util.UR2, "Failed to render template")
+		return nil, conf, fmt.Errorf("generate: %w", err)
+	}
+
+	// Load and merge data files and overrides (optional)
+	data, err := loadData(conf, opts, defaults, merged.HeaderTag.Params)
+	if err != nil {
+		return nil, conf, err
+	}
+
+	// Validate data against the JSON Schema, if any; a schema named by the template is read
+	// from the template's filesystem
+	schemaPath := opts.SchemaPath
+	var schemaFS fs.FS
+	if schemaPath == "" && merged.HeaderTag.Schema != "" {
+		schemaPath = merged.HeaderTag.Schema
+		switch {
+		case conf.FS != nil && strings.HasPrefix(schemaPath, "/"):
+			// Paths of base templates are rebased to the root of the filesystem
+			schemaPath, schemaFS = strings.TrimPrefix(schemaPath, "/"), conf.FS
+		case conf.FS != nil:
+			schemaPath, schemaFS = path.Join(conf.BaseDir, schemaPath), conf.FS
+		case !filepath.IsAbs(schemaPath):
+			schemaPath = filepath.Join(conf.BaseDir, schemaPath)
+		}
+	}
+	if schemaPath != "" && schemaFS == nil {
+		if err := checkSandbox(conf, schemaPath); err != nil {
+			return nil, conf, err
+		}
+	}
+	if schemaPath != "" {
+		if err := validateDataWithSchema(conf, schemaFS, schemaPath, data); err != nil {
+			return nil, conf, err
+		}
+	}
+
+	// Generate
+	conf.Logger.DEBUG(util.UR1, "Rendering template")
+	book, err := bookUsecase.Render(context.Background(), merged, data)
+	if err != nil {
+		conf.Logger.ERROR(util.UR2, "Failed to render template")
+		return nil, conf, fmt.Errorf("generate: %w", err)
+	}
+	conf.Logger.DEBUG(util.UR1, "Template rendered successfully", map[string]interface{}{"sheets": len(book.Sheets)})
+
+	// A base workbook given on the command line is read from the OS filesystem, even for a
+	// named template
+	if opts.BasePath != "" {
+		if err := checkSandbox(conf, opts.BasePath); err != nil {
+			return nil, conf, err
+		}
+		book.Base, conf.FS = opts.BasePath, nil
+	}
+	if book.Base != "" {
+		conf.Logger.DEBUG(util.XLSXR1, "Using base workbook", map[string]interface{}{"base": book.Base})
+	}
+
+	return book, conf, nil
+}
+
+// dataSources lists the data files in merge order
+func (opts GenerateOptions) dataSources() []string {
+	var sources []string
+	if strings.TrimSpace(opts.DataPath) != "" {
+		sources = append(sources, opts.DataPath)
+	}
+	return append(sources, opts.DataPaths...)
+}
+
+// readTemplateDefaults reads the preview data of a named template (nil if it has none)
+func readTemplateDefaults(templateFS fs.FS, manifest gxlrepo.TemplateManifest) (map[string]any, error) {
+	if manifest.Preview == "" {
+		return nil, nil
+	}
+	f, err := templateFS.Open(manifest.Preview)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return gxlrepo.ReadData(f, gxlrepo.DataOptions{Format: gxlrepo.DataFormatFromPath(manifest.Preview)})
+}
+
+// loadData reads the data files and applies the overrides on top of defaults. Files are
+// deep-merged in order (later files win), then --set and --set-string overrides are applied;
+// a --set value for a string or date parameter of params is kept as text.
+// The result is nil when no data was given at all.
+func loadData(conf config.BaseConfig, opts GenerateOptions, defaults map[string]any, params []model.ParamTag) (any, error) {
+	sources := opts.dataSources()
+	if defaults == nil && len(sources) == 0 && len(opts.Sets) == 0 && len(opts.SetStrings) == 0 {
+		return nil, nil
+	}
+
+	dataOpts, err := opts.dataOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	merged := usecase.MergeData(map[string]any{}, defaults)
+	for _, source := range sources {
+		key, path := splitDataSource(source)
+		path = resolveDataPath(conf.DataPaths, path)
+		if path != StdioPath {
+			if err := checkSandbox(conf, path); err != nil {
+				return nil, err
+			}
+		}
+		conf.Logger.DEBUG(util.FSR1, "Reading data file", map[string]interface{}{"file": path, "key": key})
+		var m map[string]any
+		if path == StdioPath {
+			// No extension to go by: JSON, falling back to YAML
+			m, err = gxlrepo.ReadData(opts.stdin(), dataOpts)
+		} else {
+			m, err = gxlrepo.ReadDataFromFile(path, dataOpts)
+		}
+		if err != nil {
+			conf.Logger.ERROR(util.FSR2, "Failed to load data file")
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		conf.Logger.DEBUG(util.FSR1, "Data loaded successfully", map[string]interface{}{"format": gxlrepo.DataFormatFromPath(path)})
+
+		if key == "" {
+			merged = usecase.MergeData(merged, m)
+			continue
+		}
+		// A mounted CSV or TSV file exposes its rows directly under the key
+		var value any = m
+		switch gxlrepo.DataFormatFromPath(path) {
+		case gxlrepo.DataFormatCSV, gxlrepo.DataFormatTSV:
+			rowsKey := dataOpts.Key
+			if rowsKey == "" {
+				rowsKey = gxlrepo.DefaultCSVKey
+			}
+			value = m[rowsKey]
+		}
+		mount, err := usecase.SetDataValue(map[string]any{}, key, value)
+		if err != nil {
+			return nil, err
+		}
+		merged = usecase.MergeData(merged, mount)
+	}
+
+	for _, override := range []struct {
+		exprs []string
+		raw   bool
+	}{{opts.Sets, false}, {opts.SetStrings, true}} {
+		for _, expr := range override.exprs {
+			var (
+				path  string
+				value any
+			)
+			if override.raw {
+				path, value, err = usecase.ParseSetExpression(expr, true)
+			} else {
+				path, value, err = usecase.ParseSetExpressionForParams(expr, params)
+			}
+			if err != nil {
+				return nil, err
+			}
+			if merged, err = usecase.SetDataValue(merged, path, value); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return merged, nil
+}
+
+// dataKeyRe matches the key of a "key=path" data source
+var dataKeyRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*(\.[A-Za-z_][A-Za-z0-9_-]*)*$`)
+
+// splitDataSource splits "key=path" into its parts. The source is keyed exactly when the text
+// before the first "=" is a key (dotted identifiers, no path separators) and a path follows;
+// anything else is a plain path, so "./a=b.json" names a file called "a=b.json".
+func splitDataSource(source string) (key, path string) {
+	if k, p, ok := strings.Cut(source, "="); ok && dataKeyRe.MatchString(k) && p != "" {
+		return k, p
+	}
+	return "", source
+}
+
+// validateDataWithSchema loads a JSON (or YAML) Schema file (from fsys when it is not nil)
+// and validates data against it
+func validateDataWithSchema(conf config.BaseConfig, fsys fs.FS, schemaPath string, data any) error {
+	conf.Logger.DEBUG(util.FSR1, "Reading schema file", map[string]interface{}{"file": schemaPath})
+	var (
+		sb  []byte
+		err error
+	)
+	if fsys != nil {
+		sb, err = fs.ReadFile(fsys, schemaPath)
+	} else {
+		sb, err = os.ReadFile(schemaPath)
+	}
+	if err != nil {
+		conf.Logger.ERROR(util.FSR2, "Failed to read schema file")
+		return fmt.Errorf("read schema: %w", err)
+	}
+	var schema any
+	switch strings.ToLower(filepath.Ext(schemaPath)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(sb, &schema)
+	default:
+		err = json.Unmarshal(sb, &schema)
+	}
+	if err != nil {
+		conf.Logger.ERROR(util.FSR2, "Failed to parse schema file")
+		return fmt.Errorf("parse schema %s: %w", schemaPath, err)
+	}
+
+	// Without a data file the document is an empty object
+	if data == nil {
+		data = map[string]any{}
+	}
+	if err := usecase.NewSchemaUsecase(conf).Validate(schema, data); err != nil {
+		conf.Logger.ERROR(util.MV2, "Data does not match schema")
+		return fmt.Errorf("validate data: %w", err)
+	}
+	conf.Logger.DEBUG(util.MV1, "Data matches schema", map[string]interface{}{"schema": schemaPath})
+	return nil
+}
+
 // PrintBookSummary prints a summary of the book contents
 func PrintBookSummary(b *model.Book) {
 	logger := util.NewLogger(util.LoggerConfig{
@@ -0,0 +1,188 @@
+package controller
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ryo-arima/goxcel/pkg/config"
+	gxlrepo "github.com/ryo-arima/goxcel/pkg/repository"
+	"github.com/ryo-arima/goxcel/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+// Global flags, registered on the root command by AddGlobalFlags
+const (
+	configFlag    = "config"
+	logLevelFlag  = "log-level"
+	logFormatFlag = "log-format"
+	logFileFlag   = "log-file"
+	quietFlag     = "quiet"
+)
+
+// AddGlobalFlags registers the flags shared by every subcommand on the root command.
+func AddGlobalFlags(root *cobra.Command) {
+	root.PersistentFlags().String(configFlag, "", "project configuration file (default: the nearest "+config.ProjectConfigFile+" in the template directory or above)")
+	root.PersistentFlags().String(logLevelFlag, "", "log level: debug, info, warn or error (default info)")
+	root.PersistentFlags().String(logFormatFlag, "", "log format: text or json (default text)")
+	root.PersistentFlags().String(logFileFlag, "", "append logs to this file instead of stderr")
+	root.PersistentFlags().BoolP(quietFlag, "q", false, "disable logging")
+}
+
+// LogOptions holds the logging settings of a command. Empty fields fall back to the project
+// configuration, then to the command's defaults.
+type LogOptions struct {
+	Level  string // debug, info, warn or error
+	Format string // text or json
+	File   string // File to append logs to (empty means stderr)
+	Quiet  bool   // Discard all log output
+}
+
+// logOptions reads the global logging flags of cmd
+func logOptions(cmd *cobra.Command) (LogOptions, error) {
+	var opts LogOptions
+	flags := cmd.Flags()
+	if f := flags.Lookup(logLevelFlag); f != nil {
+		opts.Level = f.Value.String()
+	}
+	if f := flags.Lookup(logFormatFlag); f != nil {
+		opts.Format = f.Value.String()
+	}
+	if f := flags.Lookup(logFileFlag); f != nil {
+		opts.File = f.Value.String()
+	}
+	if f := flags.Lookup(quietFlag); f != nil {
+		opts.Quiet = f.Value.String() == "true"
+	}
+
+	switch strings.ToLower(opts.Level) {
+	case "", "debug", "info", "warn", "error":
+	default:
+		return opts, fmt.Errorf("--%s must be debug, info, warn or error, got %q", logLevelFlag, opts.Level)
+	}
+	switch strings.ToLower(opts.Format) {
+	case "", "text", "json":
+	default:
+		return opts, fmt.Errorf("--%s must be text or json, got %q", logFormatFlag, opts.Format)
+	}
+	return opts, nil
+}
+
+// newLogger creates the logger of a command. Flags take precedence over the project log
+// settings; defaultLevel applies when neither sets a level.
+func (opts LogOptions) newLogger(service, defaultLevel string, project config.ProjectLogConfig) util.Logger {
+	if opts.Quiet {
+		return util.NewNopLogger()
+	}
+	level := firstNonEmpty(opts.Level, project.Level, defaultLevel)
+	format := firstNonEmpty(opts.Format, project.Format)
+	return util.NewLogger(util.LoggerConfig{
+		Component:  "goxcel",
+		Service:    service,
+		Level:      level,
+		Structured: strings.EqualFold(format, "json"),
+		Output:     firstNonEmpty(opts.File, "stderr"),
+	})
+}
+
+// firstNonEmpty returns the first non-empty string
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// loadProjectConfig reads the file given with --config, or the nearest .goxcel.yaml found
+// from dir upwards. Without either the configuration is empty.
+func loadProjectConfig(cmd *cobra.Command, dir string) (config.ProjectConfig, error) {
+	var path string
+	if f := cmd.Flags().Lookup(configFlag); f != nil {
+		path = f.Value.String()
+	}
+	if path == "" {
+		found, err := config.FindProjectConfig(dir)
+		if err != nil || found == "" {
+			return config.ProjectConfig{}, err
+		}
+		path = found
+	}
+	return config.LoadProjectConfig(path)
+}
+
+// loadTemplateProjectConfig reads the project configuration for a template in dir. The
+// sandbox and the limits guard against the template itself, so a .goxcel.yaml next to the
+// template may not set or lift the sandbox, and may only lower the limits: both come from
+// --config or from the nearest .goxcel.yaml of the working directory.
+func loadTemplateProjectConfig(cmd *cobra.Command, dir string) (config.ProjectConfig, error) {
+	project, err := loadProjectConfig(cmd, dir)
+	if err != nil {
+		return project, err
+	}
+	caller, err := loadProjectConfig(cmd, ".")
+	if err != nil {
+		return project, err
+	}
+	project.Sandbox = caller.Sandbox
+	project.Limits = lowerLimits(caller.Limits, project.Limits)
+	return project, nil
+}
+
+// lowerLimits returns the limits of caller, lowered to those of template where they are
+// stricter. A zero limit is unlimited, except for the import depth, which defaults.
+func lowerLimits(caller, template config.Limits) config.Limits {
+	lower := func(c, t int64) int64 {
+		if t > 0 && (c == 0 || t < c) {
+			return t
+		}
+		return c
+	}
+	limits := config.Limits{
+		MaxCells:          int(lower(int64(caller.MaxCells), int64(template.MaxCells))),
+		MaxRows:           int(lower(int64(caller.MaxRows), int64(template.MaxRows))),
+		MaxLoopIterations: int(lower(int64(caller.MaxLoopIterations), int64(template.MaxLoopIterations))),
+		MaxImportFiles:    int(lower(int64(caller.MaxImportFiles), int64(template.MaxImportFiles))),
+		MaxOutputBytes:    lower(caller.MaxOutputBytes, template.MaxOutputBytes),
+		Timeout:           time.Duration(lower(int64(caller.Timeout), int64(template.Timeout))),
+		MaxImportDepth:    caller.MaxImportDepth,
+	}
+	if template.MaxImportDepth > 0 && template.MaxImportDepth < caller.ImportDepth() {
+		limits.MaxImportDepth = template.MaxImportDepth
+	}
+	return limits
+}
+
+// checkSandbox fails when conf has a sandbox and path (relative to the working directory)
+// lies outside it
+func checkSandbox(conf config.BaseConfig, path string) error {
+	if conf.Sandbox == "" {
+		return nil
+	}
+	if _, err := gxlrepo.SandboxPath(conf.Sandbox, ".", path); err != nil {
+		conf.Logger.ERROR(util.FSR2, "File outside the sandbox")
+		return err
+	}
+	return nil
+}
+
+// resolveDataPath finds a relative data file in the working directory or, failing that, in
+// the first search directory that contains it. Unfound paths are returned unchanged.
+func resolveDataPath(searchPaths []string, path string) string {
+	if path == StdioPath || filepath.IsAbs(path) {
+		return path
+	}
+	if _, err := os.Stat(path); err == nil {
+		return path
+	}
+	for _, dir := range searchPaths {
+		candidate := filepath.Join(dir, path)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+	}
+	return path
+}
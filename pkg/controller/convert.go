@@ -0,0 +1,55 @@
+package controller
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ryo-arima/goxcel/pkg/config"
+	"github.com/ryo-arima/goxcel/pkg/usecase"
+	"github.com/spf13/cobra"
+)
+
+// InitConvertCmd creates the 'convert' subcommand which turns an .xlsx workbook into a starting .gxl template.
+func InitConvertCmd() *cobra.Command {
+	var output string
+
+	cmd := &cobra.Command{
+		Use:   "convert <workbook.xlsx>",
+		Short: "Convert an .xlsx workbook into a starting .gxl template",
+		Long: "Read an existing .xlsx workbook and write a .gxl template that generates an equivalent workbook:\n" +
+			"one <Sheet> per sheet, <Grid> blocks grouped by cell style, <Merge> tags and column and row sizes.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := args[0]
+
+			logOpts, err := logOptions(cmd)
+			if err != nil {
+				return err
+			}
+			project, err := loadProjectConfig(cmd, filepath.Dir(path))
+			if err != nil {
+				return err
+			}
+			conf := config.NewBaseConfig()
+			// Report what could not be converted, but nothing else, unless a log level is asked for
+			conf.Logger = logOpts.newLogger("convert", "WARN", project.Log)
+			gxl, err := usecase.NewConvertUsecase(conf).Convert(path)
+			if err != nil {
+				return fmt.Errorf("convert: %w", err)
+			}
+
+			if output != "" && output != "-" {
+				if err := os.WriteFile(output, gxl, 0644); err != nil {
+					return fmt.Errorf("write output: %w", err)
+				}
+				return nil
+			}
+			_, err = cmd.OutOrStdout().Write(gxl)
+			return err
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "", "write the template to this file instead of stdout")
+	return cmd
+}
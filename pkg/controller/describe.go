@@ -0,0 +1,81 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ryo-arima/goxcel/pkg/config"
+	"github.com/ryo-arima/goxcel/pkg/usecase"
+	"github.com/spf13/cobra"
+)
+
+// InitDescribeCmd creates the 'describe' subcommand which prints the parameters a template expects.
+func InitDescribeCmd() *cobra.Command {
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "describe <template.gxl>",
+		Short: "Describe the data parameters of a .gxl template",
+		Long:  "Print the parameters declared in the <Params> section of a .gxl template as a table or as JSON Schema.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logOpts, err := logOptions(cmd)
+			if err != nil {
+				return err
+			}
+			project, err := loadProjectConfig(cmd, filepath.Dir(args[0]))
+			if err != nil {
+				return err
+			}
+			conf := config.NewBaseConfigWithFile(args[0])
+			// Only problems are worth reporting unless a log level is asked for
+			conf.Logger = logOpts.newLogger("describe", "WARN", project.Log)
+			u := usecase.NewDescribeUsecase(conf)
+			desc, err := u.Describe(args[0])
+			if err != nil {
+				return fmt.Errorf("describe: %w", err)
+			}
+
+			switch format {
+			case "text":
+				return printParamTable(cmd.OutOrStdout(), desc)
+			case "json":
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				return enc.Encode(u.JSONSchema(desc))
+			default:
+				return fmt.Errorf("unknown format %q (expected text or json)", format)
+			}
+		},
+	}
+
+	cmd.Flags().StringVarP(&format, "format", "f", "text", "output format: text or json (JSON Schema)")
+	return cmd
+}
+
+// printParamTable writes the parameters as an aligned table
+func printParamTable(w io.Writer, desc *usecase.TemplateDescription) error {
+	if len(desc.Params) == 0 {
+		_, err := fmt.Fprintln(w, "No parameters declared.")
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tTYPE\tREQUIRED\tDEFAULT\tENUM\tDESCRIPTION")
+	for _, p := range desc.Params {
+		typ := p.Type
+		if typ == "" {
+			typ = "any"
+		}
+		required := "no"
+		if p.Required && p.Default == "" {
+			required = "yes"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", p.Name, typ, required, p.Default, strings.Join(p.Enum, "|"), p.Description)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,171 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/ryo-arima/goxcel/pkg/config"
+	"github.com/ryo-arima/goxcel/pkg/model"
+	gxlrepo "github.com/ryo-arima/goxcel/pkg/repository"
+	"github.com/ryo-arima/goxcel/pkg/usecase"
+	"github.com/spf13/cobra"
+)
+
+// Exit codes of the diff command, as with diff(1)
+const (
+	DiffExitSame    = 0
+	DiffExitChanged = 1
+	DiffExitError   = 2
+)
+
+// ExitError ends the CLI with a specific exit code. Err is printed when set.
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("exit status %d", e.Code)
+	}
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// InitDiffCmd creates the 'diff' subcommand which compares two workbooks.
+func InitDiffCmd() *cobra.Command {
+	var (
+		format     string
+		dataPaths  []string
+		dataPathsA []string
+		dataPathsB []string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "diff <a.xlsx|a.gxl> <b.xlsx|b.gxl>",
+		Short: "Compare two workbooks or the workbooks two templates generate",
+		Long: "Compare sheets, cell values, types, formulas, styles, merges and dimensions of two workbooks.\n" +
+			"A .gxl argument is rendered first, with the data given by --data (every .gxl argument), --data-a or --data-b.\n" +
+			"Exits with 0 when the workbooks are the same, 1 when they differ and 2 on errors.",
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != "text" && format != "json" {
+				return &ExitError{Code: DiffExitError, Err: fmt.Errorf("unknown format %q (expected text or json)", format)}
+			}
+			logOpts, err := logOptions(cmd)
+			if err != nil {
+				return &ExitError{Code: DiffExitError, Err: err}
+			}
+
+			sides := [2][]string{dataPathsA, dataPathsB}
+			var books [2]*model.Book
+			for i, path := range args {
+				if books[i], err = loadDiffBook(cmd, path, dataPaths, sides[i], logOpts); err != nil {
+					return &ExitError{Code: DiffExitError, Err: fmt.Errorf("diff: %s: %w", path, err)}
+				}
+			}
+
+			conf := config.NewBaseConfig()
+			conf.Logger = logOpts.newLogger("diff", "WARN", config.ProjectLogConfig{})
+			diff, err := usecase.NewDiffUsecase(conf).Diff(books[0], books[1])
+			if err != nil {
+				return &ExitError{Code: DiffExitError, Err: fmt.Errorf("diff: %w", err)}
+			}
+
+			if format == "json" {
+				err = printDiffJSON(cmd.OutOrStdout(), args, diff)
+			} else {
+				err = printDiffText(cmd.OutOrStdout(), args, diff)
+			}
+			if err != nil {
+				return &ExitError{Code: DiffExitError, Err: err}
+			}
+			if !diff.Equal() {
+				// The report says it all; only the exit code is left to set
+				cmd.SilenceErrors, cmd.SilenceUsage = true, true
+				return &ExitError{Code: DiffExitChanged}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&format, "format", "f", "text", "report format: text or json")
+	cmd.Flags().StringArrayVarP(&dataPaths, "data", "d", nil, "data file for the .gxl arguments; repeat to merge several files, or use key=path")
+	cmd.Flags().StringArrayVar(&dataPathsA, "data-a", nil, "data file for the first argument only, merged after --data")
+	cmd.Flags().StringArrayVar(&dataPathsB, "data-b", nil, "data file for the second argument only, merged after --data")
+	return cmd
+}
+
+// loadDiffBook reads a workbook, or renders a template with the shared and its own data files
+// and reads back the workbook it generates, so that both sides are compared the way they are stored
+func loadDiffBook(cmd *cobra.Command, path string, shared, own []string, logOpts LogOptions) (*model.Book, error) {
+	if !strings.EqualFold(filepath.Ext(path), ".gxl") {
+		if len(own) > 0 {
+			return nil, fmt.Errorf("data files only apply to .gxl templates")
+		}
+		return gxlrepo.ReadBookFromFile(path)
+	}
+	dataPaths := append(append([]string{}, shared...), own...)
+
+	project, err := loadTemplateProjectConfig(cmd, filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+	opts := GenerateOptions{
+		TemplatePath:    path,
+		DataPaths:       dataPaths,
+		SheetNamePolicy: project.SheetNamePolicy,
+		Strict:          project.Strict,
+		Project:         project,
+		Log:             logOpts,
+		Stdin:           cmd.InOrStdin(),
+	}
+	conf, err := newGenerateConfig(opts, "diff", "WARN")
+	if err != nil {
+		return nil, err
+	}
+	book, conf, err := renderBook(conf, opts)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := gxlrepo.WriteBookWithConfig(book, &buf, conf); err != nil {
+		return nil, fmt.Errorf("write xlsx: %w", err)
+	}
+	return gxlrepo.ReadBook(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+}
+
+// printDiffText writes the report one change per line, like a unified diff header
+func printDiffText(w io.Writer, names []string, diff *usecase.BookDiff) error {
+	if diff.Equal() {
+		_, err := fmt.Fprintf(w, "%s and %s are the same\n", names[0], names[1])
+		return err
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", names[0], names[1])
+	for _, change := range diff.Changes {
+		b.WriteString(change.String() + "\n")
+	}
+	fmt.Fprintf(&b, "%d difference(s)\n", len(diff.Changes))
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// printDiffJSON writes the report as a JSON object
+func printDiffJSON(w io.Writer, names []string, diff *usecase.BookDiff) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(map[string]any{
+		"a":       names[0],
+		"b":       names[1],
+		"equal":   diff.Equal(),
+		"changes": diff.Changes,
+	})
+}
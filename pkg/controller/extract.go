@@ -0,0 +1,80 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ryo-arima/goxcel/pkg/config"
+	"github.com/ryo-arima/goxcel/pkg/usecase"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// InitExtractCmd creates the 'extract' subcommand which reads the data back out of a filled-in workbook.
+func InitExtractCmd() *cobra.Command {
+	var templatePath, format, output string
+
+	cmd := &cobra.Command{
+		Use:   "extract --template <template.gxl> <workbook.xlsx>",
+		Short: "Extract the data of a workbook generated from a .gxl template",
+		Long: "Read the cells where the template places its {{ expressions }} and print the data as JSON or YAML.\n" +
+			"Loops read rows (or sheets) until the first one without values.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format == "" {
+				format = "json"
+				if ext := strings.ToLower(filepath.Ext(output)); ext == ".yaml" || ext == ".yml" {
+					format = "yaml"
+				}
+			}
+			if format != "json" && format != "yaml" {
+				return fmt.Errorf("unknown format %q (expected json or yaml)", format)
+			}
+
+			logOpts, err := logOptions(cmd)
+			if err != nil {
+				return err
+			}
+			project, err := loadProjectConfig(cmd, filepath.Dir(templatePath))
+			if err != nil {
+				return err
+			}
+			conf := config.NewBaseConfigWithFile(templatePath)
+			// Report cells that do not match the template, but nothing else, unless a log level is asked for
+			conf.Logger = logOpts.newLogger("extract", "WARN", project.Log)
+			data, err := usecase.NewExtractUsecase(conf).Extract(templatePath, args[0])
+			if err != nil {
+				return fmt.Errorf("extract: %w", err)
+			}
+
+			var out []byte
+			if format == "yaml" {
+				out, err = yaml.Marshal(data)
+			} else {
+				out, err = json.MarshalIndent(data, "", "  ")
+				out = append(out, '\n')
+			}
+			if err != nil {
+				return fmt.Errorf("encode data: %w", err)
+			}
+
+			if output != "" && output != "-" {
+				if err := os.WriteFile(output, out, 0644); err != nil {
+					return fmt.Errorf("write output: %w", err)
+				}
+				return nil
+			}
+			_, err = cmd.OutOrStdout().Write(out)
+			return err
+		},
+	}
+
+	cmd.Flags().StringVarP(&templatePath, "template", "t", "", "template the workbook was generated from")
+	cmd.Flags().StringVarP(&format, "format", "f", "", "output format: json or yaml (default: from the output file extension, else json)")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "write the data to this file instead of stdout")
+	_ = cmd.MarkFlagRequired("template")
+	return cmd
+}
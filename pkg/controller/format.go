@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 
 	"github.com/ryo-arima/goxcel/pkg/config"
 	"github.com/ryo-arima/goxcel/pkg/usecase"
@@ -27,7 +28,16 @@ func InitFormatCmd() *cobra.Command {
 			}
 			path := args[0]
 
+			logOpts, err := logOptions(cmd)
+			if err != nil {
+				return err
+			}
+			project, err := loadProjectConfig(cmd, filepath.Dir(path))
+			if err != nil {
+				return err
+			}
 			conf := config.NewBaseConfigWithFile(path)
+			conf.Logger = logOpts.newLogger("cli", "INFO", project.Log)
 			u := usecase.NewFormatUsecase(conf)
 			formatted, err := u.Format(path)
 			if err != nil {
@@ -46,7 +56,7 @@ func InitFormatCmd() *cobra.Command {
 				}
 				return nil
 			}
-			_, err = os.Stdout.Write(formatted)
+			_, err = cmd.OutOrStdout().Write(formatted)
 			return err
 		},
 	}
@@ -1,42 +1,19 @@
 package controller
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
+	"text/tabwriter"
 
+	"github.com/ryo-arima/goxcel/pkg/config"
+	gxlrepo "github.com/ryo-arima/goxcel/pkg/repository"
 	"github.com/spf13/cobra"
 )
 
-// getTemplatesDir returns the path to the templates directory
-func getTemplatesDir() (string, error) {
-	// Get executable directory
-	exe, err := os.Executable()
-	if err != nil {
-		return "", err
-	}
-	exeDir := filepath.Dir(exe)
-	
-	// Try relative to executable first (for installed binary)
-	templatesDir := filepath.Join(exeDir, ".etc", "templates")
-	if _, err := os.Stat(templatesDir); err == nil {
-		return templatesDir, nil
-	}
-	
-	// Try relative to current working directory (for development)
-	wd, err := os.Getwd()
-	if err != nil {
-		return "", err
-	}
-	templatesDir = filepath.Join(wd, ".etc", "templates")
-	if _, err := os.Stat(templatesDir); err == nil {
-		return templatesDir, nil
-	}
-	
-	return "", fmt.Errorf("templates directory not found")
-}
-
 // InitGetCmd initializes the 'get' command
 func InitGetCmd() *cobra.Command {
 	var getCmd = &cobra.Command{
@@ -51,109 +28,142 @@ func InitGetCmd() *cobra.Command {
 
 // initGetTemplatesCmd initializes the 'get templates' subcommand
 func initGetTemplatesCmd() *cobra.Command {
-	var outputDir string
+	var (
+		outputDir  string
+		jsonOutput bool
+	)
 
 	var getTemplatesCmd = &cobra.Command{
 		Use:   "templates [template-name]",
 		Short: "Get template files",
-		Long:  "Copy template files (base.gxl and base.yaml) to the current or specified directory",
+		Long:  "List the built-in and user templates, or copy every file of a template (as listed in its template.yaml) to the current or specified directory",
 		Args:  cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runGetTemplates(args, outputDir)
+			conf, err := newTemplateConfig(cmd)
+			if err != nil {
+				return err
+			}
+			return runGetTemplates(cmd.OutOrStdout(), conf, args, outputDir, jsonOutput)
 		},
 	}
 
 	getTemplatesCmd.Flags().StringVarP(&outputDir, "output", "o", ".", "Output directory for template files")
+	getTemplatesCmd.Flags().BoolVar(&jsonOutput, "json", false, "print the template list as JSON")
 
 	return getTemplatesCmd
 }
 
 // runGetTemplates executes the get templates command
-func runGetTemplates(args []string, outputDir string) error {
-	var templateName string
-	if len(args) > 0 {
-		templateName = args[0]
-	} else {
+func runGetTemplates(w io.Writer, conf config.BaseConfig, args []string, outputDir string, jsonOutput bool) error {
+	repo := gxlrepo.NewTemplateRepository(conf)
+	if len(args) == 0 {
 		// List available templates
-		return listTemplates()
+		if jsonOutput {
+			return listTemplatesJSON(w, repo)
+		}
+		return listTemplates(w, repo)
+	}
+	if jsonOutput {
+		return fmt.Errorf("--json lists templates and cannot be combined with a template name")
 	}
 
 	// Copy template files to output directory
-	return copyTemplate(templateName, outputDir)
+	_, err := copyTemplate(w, repo, args[0], outputDir)
+	return err
 }
 
-// listTemplates lists all available templates
-func listTemplates() error {
-	templatesDir, err := getTemplatesDir()
+// newTemplateConfig returns the config used to look up templates, honoring the templates
+// directory of the project configuration found from the working directory
+func newTemplateConfig(cmd *cobra.Command) (config.BaseConfig, error) {
+	conf := config.NewBaseConfig()
+	logOpts, err := logOptions(cmd)
 	if err != nil {
-		return fmt.Errorf("failed to locate templates directory: %w", err)
+		return conf, err
 	}
+	project, err := loadProjectConfig(cmd, ".")
+	if err != nil {
+		return conf, err
+	}
+	conf.Logger = logOpts.newLogger("get", "WARN", project.Log)
+	conf.TemplatesDir = project.TemplatesDir
+	return conf, nil
+}
 
-	fmt.Println("Available templates:")
-	fmt.Println()
-
-	entries, err := os.ReadDir(templatesDir)
+// listTemplates prints the available templates as a table
+func listTemplates(w io.Writer, repo gxlrepo.TemplateRepository) error {
+	templates, err := repo.ListTemplates()
 	if err != nil {
-		return fmt.Errorf("failed to read templates directory: %w", err)
+		return fmt.Errorf("failed to list templates: %w", err)
 	}
 
-	for _, entry := range entries {
-		if entry.IsDir() {
-			fmt.Printf("  - %s\n", entry.Name())
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tSOURCE\tVERSION\tTAGS\tDESCRIPTION")
+	for _, t := range templates {
+		source := t.Source
+		if t.Overrides {
+			source += " (overrides built-in)"
+		}
+		description := t.Manifest.Description
+		if description == "" {
+			description = t.Manifest.Title
 		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", t.Name, source, t.Manifest.Version, strings.Join(t.Manifest.Tags, ","), description)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
 	}
 
-	fmt.Println()
-	fmt.Println("Usage: goxcel get templates <template-name> [-o output-dir]")
+	fmt.Fprintln(w)
+	if dir, err := gxlrepo.UserTemplatesDir(); err == nil {
+		fmt.Fprintf(w, "User templates directory: %s\n", dir)
+	}
+	fmt.Fprintln(w, "Usage: goxcel get templates <template-name> [-o output-dir]")
 	return nil
 }
 
-// copyTemplate copies a specific template to the output directory
-func copyTemplate(templateName, outputDir string) error {
-	templatesDir, err := getTemplatesDir()
+// listTemplatesJSON prints the available templates with their manifests as JSON
+func listTemplatesJSON(w io.Writer, repo gxlrepo.TemplateRepository) error {
+	templates, err := repo.ListTemplates()
 	if err != nil {
-		return fmt.Errorf("failed to locate templates directory: %w", err)
+		return fmt.Errorf("failed to list templates: %w", err)
 	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(templates)
+}
 
-	templatePath := filepath.Join(templatesDir, templateName)
-
-	// Check if template exists
-	if _, err := os.Stat(templatePath); os.IsNotExist(err) {
-		return fmt.Errorf("template '%s' not found", templateName)
+// copyTemplate copies every file of a template to the output directory, keeping subdirectories,
+// and returns the template's info
+func copyTemplate(w io.Writer, repo gxlrepo.TemplateRepository, templateName, outputDir string) (gxlrepo.TemplateInfo, error) {
+	templateFS, info, err := repo.OpenTemplate(templateName)
+	if err != nil {
+		return info, err
 	}
-
-	// Create output directory if it doesn't exist
-	if err := os.MkdirAll(outputDir, 0755); err != nil {
-		return fmt.Errorf("failed to create output directory: %w", err)
+	files, err := gxlrepo.TemplateFiles(templateFS, info.Manifest)
+	if err != nil {
+		return info, fmt.Errorf("template %q: %w", templateName, err)
 	}
 
-	// Copy files from template
-	files := []string{"base.gxl", "base.yaml"}
-	for _, filename := range files {
-		srcPath := filepath.Join(templatePath, filename)
-		dstPath := filepath.Join(outputDir, filename)
-
-		// Check if source file exists
-		if _, err := os.Stat(srcPath); os.IsNotExist(err) {
-			fmt.Printf("Warning: %s not found in template, skipping\n", filename)
-			continue
+	for _, name := range files {
+		dstPath := filepath.Join(outputDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(dstPath), 0755); err != nil {
+			return info, fmt.Errorf("failed to create output directory: %w", err)
 		}
 
-		// Read source file
-		data, err := os.ReadFile(srcPath)
+		src, err := templateFS.Open(name)
 		if err != nil {
-			return fmt.Errorf("failed to read %s: %w", filename, err)
+			return info, fmt.Errorf("failed to read %s: %w", name, err)
 		}
-
-		// Write to output directory
-		if err := os.WriteFile(dstPath, data, 0644); err != nil {
-			return fmt.Errorf("failed to write %s: %w", filename, err)
+		err = copyFile(src, dstPath)
+		src.Close()
+		if err != nil {
+			return info, fmt.Errorf("failed to write %s: %w", name, err)
 		}
 
-		fmt.Printf("Created: %s\n", dstPath)
+		fmt.Fprintf(w, "Created: %s\n", dstPath)
 	}
 
-	return nil
+	return info, nil
 }
 
 // copyFile copies a file from src to dst
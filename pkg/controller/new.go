@@ -0,0 +1,179 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/ryo-arima/goxcel/pkg/config"
+	gxlrepo "github.com/ryo-arima/goxcel/pkg/repository"
+	"github.com/spf13/cobra"
+)
+
+// defaultNewTemplate is the template a new project starts from when --from is not given
+const defaultNewTemplate = "simple"
+
+// InitNewCmd creates the 'new' subcommand which scaffolds a template project.
+func InitNewCmd() *cobra.Command {
+	var from string
+
+	cmd := &cobra.Command{
+		Use:   "new <name>",
+		Short: "Create a new template project",
+		Long:  "Create a project directory with a .gxl template, sample data, a README and a Makefile, starting from a built-in or user template.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			conf, err := newTemplateConfig(cmd)
+			if err != nil {
+				return err
+			}
+			return runNew(cmd.OutOrStdout(), conf, args[0], from)
+		},
+	}
+
+	cmd.Flags().StringVar(&from, "from", defaultNewTemplate, "template to start from (see 'goxcel get templates')")
+	return cmd
+}
+
+// runNew creates the project directory dir from the named template
+func runNew(w io.Writer, conf config.BaseConfig, dir, from string) error {
+	// Look the template up first so a typo does not leave an empty directory behind
+	repo := gxlrepo.NewTemplateRepository(conf)
+	if _, _, err := repo.OpenTemplate(from); err != nil {
+		return err
+	}
+	if err := ensureEmptyDir(dir); err != nil {
+		return err
+	}
+
+	info, err := copyTemplate(w, repo, from, dir)
+	if err != nil {
+		return err
+	}
+
+	project := newProject{
+		Name:     filepath.Base(filepath.Clean(dir)),
+		From:     info.Name,
+		Template: gxlrepo.TemplateBaseFile,
+		Data:     info.Manifest.Preview,
+		Params:   info.Manifest.Params,
+	}
+	project.Output = project.Name + ".xlsx"
+
+	// A README or Makefile shipped with the template is kept as is
+	for _, tmpl := range []*template.Template{newReadmeTemplate, newMakefileTemplate} {
+		path := filepath.Join(dir, tmpl.Name())
+		if _, err := os.Stat(path); err == nil {
+			fmt.Fprintf(w, "Kept: %s (provided by the template)\n", path)
+			continue
+		}
+		if err := project.write(path, tmpl); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "Created: %s\n", path)
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "Next: cd %s && make\n", dir)
+	return nil
+}
+
+// ensureEmptyDir creates dir, or accepts it if it already exists and is empty
+func ensureEmptyDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create project directory: %w", err)
+		}
+		return nil
+	case err != nil:
+		return fmt.Errorf("failed to read project directory: %w", err)
+	case len(entries) > 0:
+		return fmt.Errorf("directory %s already exists and is not empty", dir)
+	}
+	return nil
+}
+
+// newProject holds the values used to write the README and Makefile of a new project
+type newProject struct {
+	Name     string   // Project name (base name of the directory)
+	From     string   // Template the project was created from
+	Template string   // Template file
+	Data     string   // Sample data file (empty if the template has none)
+	Output   string   // Generated workbook
+	Params   []string // Data keys the template expects
+}
+
+// write renders tmpl into path
+func (p newProject) write(path string, tmpl *template.Template) error {
+	var b strings.Builder
+	if err := tmpl.Execute(&b, p); err != nil {
+		return fmt.Errorf("failed to render %s: %w", filepath.Base(path), err)
+	}
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
+
+var newReadmeTemplate = template.Must(template.New("README.md").Parse(`# {{ .Name }}
+
+Excel report generated with [goxcel](https://github.com/ryo-arima/goxcel), created from the
+` + "`{{ .From }}`" + ` template.
+
+## Files
+
+- ` + "`{{ .Template }}`" + ` - the template
+{{- if .Data }}
+- ` + "`{{ .Data }}`" + ` - sample data
+{{- end }}
+- ` + "`Makefile`" + ` - build targets
+
+## Usage
+
+` + "```" + `bash
+make          # generate {{ .Output }}
+make preview  # print a summary without writing a file
+make clean    # remove {{ .Output }}
+` + "```" + `
+
+Or run goxcel directly:
+
+` + "```" + `bash
+goxcel generate -t {{ .Template }}{{ if .Data }} -d {{ .Data }}{{ end }} -o {{ .Output }}
+` + "```" + `
+{{- if .Params }}
+
+## Data
+
+The template expects these keys:
+{{ range .Params }}
+- ` + "`{{ . }}`" + `
+{{- end }}
+{{- end }}
+`))
+
+var newMakefileTemplate = template.Must(template.New("Makefile").Parse(`GOXCEL ?= goxcel
+TEMPLATE := {{ .Template }}
+DATA := {{ .Data }}
+OUTPUT := {{ .Output }}
+
+.PHONY: all preview clean
+
+all: $(OUTPUT)
+
+$(OUTPUT): $(TEMPLATE) $(DATA)
+	$(GOXCEL) generate -t $(TEMPLATE) $(if $(DATA),-d $(DATA)) -o $@
+
+preview:
+	$(GOXCEL) generate -t $(TEMPLATE) $(if $(DATA),-d $(DATA)) --dry-run
+
+clean:
+	rm -f $(OUTPUT)
+`))
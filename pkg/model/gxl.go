@@ -6,22 +6,43 @@ type BookNodeType int
 const (
 	BookNodeTypeImport BookNodeType = iota
 	BookNodeTypeSheet
+	BookNodeTypeFor
+	BookNodeTypeIf
 )
 
-// BookNode represents a node at book level (Import or Sheet) with order preserved
+// BookNode represents a node at book level (Import, Sheet, For or If) with order preserved
 type BookNode struct {
 	Type   BookNodeType
 	Import *ImportTag
 	Sheet  *SheetTag
+	For    *BookForTag
+	If     *BookIfTag
+}
+
+// BookForTag represents <For each="item in items"> at book level; each iteration renders its body
+// (typically one or more <Sheet> elements) with the loop variable in scope.
+type BookForTag struct {
+	Each string
+	Body []BookNode
+}
+
+// BookIfTag represents <If cond="..."> at book level for conditionally rendering sheets.
+type BookIfTag struct {
+	Cond string
+	Then []BookNode
+	Else []BookNode
 }
 
 // GXL represents the root structure of a .gxl template file.
 type GXL struct {
-	HeaderTag HeaderTag
-	BookTag   BookTag
-	Imports   []ImportTag // Import tags at book level (deprecated - use BookNodes)
-	Sheets    []SheetTag  // (deprecated - use BookNodes)
-	BookNodes []BookNode  // Ordered book-level nodes (Import and Sheet in definition order)
+	HeaderTag  HeaderTag
+	BookTag    BookTag
+	Imports    []ImportTag    // Import tags at book level (deprecated - use BookNodes)
+	Sheets     []SheetTag     // Top-level sheets only (deprecated - use BookNodes)
+	BookNodes  []BookNode     // Ordered book-level nodes (Import, Sheet, For and If in definition order)
+	Fragments  []FragmentTag  // Reusable node lists defined with <Fragment> at book level
+	Components []ComponentTag // Parameterized node lists defined with <Component> at book level
+	Blocks     []BlockTag     // Top-level <Block> overrides of a template that extends a base
 }
 
 // HeaderTag holds global metadata for the GXL template.
@@ -30,17 +51,44 @@ type HeaderTag struct {
 	Version    string
 	Encoding   string
 	Properties map[string]string
+	Params     []ParamTag // Data parameters declared with <Params>
+	Schema     string     // Optional: JSON Schema file the data must match (relative to the template)
+}
+
+// Parameter types accepted by ParamTag.Type (empty accepts any value)
+const (
+	ParamTypeString  = "string"
+	ParamTypeNumber  = "number"
+	ParamTypeInteger = "integer"
+	ParamTypeBoolean = "boolean"
+	ParamTypeDate    = "date"
+	ParamTypeObject  = "object"
+	ParamTypeArray   = "array"
+)
+
+// ParamTag represents <Param name="invoice.number" type="string" required="true" default="..." enum="a,b" />
+// declaring a data key the template expects.
+type ParamTag struct {
+	Name        string   // Dotted data path (e.g. "invoice.number")
+	Type        string   // One of the ParamType constants, or empty for any
+	Required    bool     // The data must provide a value (ignored when Default is set)
+	Default     string   // Optional: value used when the data does not provide one
+	Enum        []string // Optional: allowed values
+	Description string   // Optional: human readable description
 }
 
 // BookTag represents the <Book> element with its attributes.
 type BookTag struct {
 	Name       string
 	Properties map[string]string
+	Extends    string // Optional: path of the base template this book extends
+	Base       string // Optional: path of an .xlsx workbook the rendered sheets are written into
 }
 
 // SheetTag represents a <Sheet> element within a workbook.
 type SheetTag struct {
 	Name  string
+	Mode  string // How the sheet is written into a base workbook: SheetModeOverlay (default) or SheetModeReplace
 	Nodes []any
 	// Optional sheet-level configuration parsed from attributes or child tags
 	Config *SheetConfigTag
@@ -55,7 +103,7 @@ type SheetConfigTag struct {
 	ShowRowColHeaders  *bool
 }
 
-// ColumnTag represents <Column> for column width settings
+// ColumnTag represents <Column index="2" width="30" /> for column width settings
 type ColumnTag struct {
 	Index int     // Column number (1-based)
 	Width float64 // Width in Excel units
@@ -89,13 +137,19 @@ type GridTag struct {
 	Rows    []GridRowTag
 	Ref     string // Optional: Starting cell reference (e.g., "A1", "B5")
 	// Optional style defaults applied to all cells in this Grid
-	FontName    string
-	FontSize    int
-	FontColor   string // RGB hex without # (e.g., "FF0000")
-	FillColor   string // RGB hex without # (e.g., "FFFF00")
-	BorderStyle string // Border line style (thin, medium, thick, dashed, dotted, double)
-	BorderColor string // RGB hex without #
-	BorderSides string // comma-separated: all, top, right, bottom, left
+	FontName     string
+	FontSize     int
+	FontColor    string // RGB hex without # (e.g., "FF0000")
+	FillColor    string // RGB hex without # (e.g., "FFFF00")
+	BorderStyle  string // Border line style (thin, medium, thick, dashed, dotted, double)
+	BorderColor  string // RGB hex without #
+	BorderSides  string // comma-separated: all, top, right, bottom, left
+	Bold         bool
+	Italic       bool
+	Underline    bool
+	HAlign       string // left, center, right, ...
+	VAlign       string // top, middle, bottom, ...
+	NumberFormat string // Excel number format code (e.g., "#,##0.00")
 }
 
 // GridRowTag represents a single row parsed from Grid content.
@@ -201,8 +255,54 @@ type IfTag struct {
 	Else []any
 }
 
+// ImportAllSheets is the ImportTag.Sheet value that imports every top-level sheet of a file.
+const ImportAllSheets = "*"
+
+// ImportAllComponents is the ImportTag.Components value that imports every component of a file.
+const ImportAllComponents = "*"
+
 // ImportTag represents <Import src="..." sheet="..." /> for importing external .gxl files.
 type ImportTag struct {
 	Src   string // Path to the external .gxl file (relative or absolute)
-	Sheet string // Name of the sheet to import (required)
+	Sheet string // Name of the sheet to import (required; "*" imports all sheets)
+	As    string // Optional: name of the rendered sheet (may contain mustache expressions)
+	Data  string // Optional: data path whose value becomes the scope of the imported sheet
+	// Optional: comma-separated component names to load from the file ("*" loads all).
+	// When set, Sheet may be empty to import components only.
+	Components string
+}
+
+// FragmentTag represents <Fragment name="..."> at book level: a named list of sheet nodes
+// that other templates splice into a sheet with <Include>.
+type FragmentTag struct {
+	Name  string
+	Nodes []any
+}
+
+// IncludeTag represents <Include src="..." fragment="..." with="..." /> inside a sheet.
+type IncludeTag struct {
+	Src      string // Path to the .gxl file defining the fragment (relative or absolute)
+	Fragment string // Name of the fragment to include (required)
+	With     string // Optional: data path whose value becomes the scope of the fragment
+}
+
+// ComponentTag represents <Component name="..." params="a,b"> at book level: a named list of
+// sheet nodes rendered with its parameters in scope wherever it is used.
+type ComponentTag struct {
+	Name   string
+	Params []string
+	Nodes  []any
+}
+
+// UseTag represents <Use component="..." param="value" /> inside a sheet.
+type UseTag struct {
+	Component string            // Name of the component to render
+	Args      map[string]string // Parameter values by name (may contain mustache expressions)
+}
+
+// BlockTag represents <Block name="...">. Inside a sheet it is a placeholder rendered with its
+// default nodes; at book level in a template that extends a base it overrides the base's block.
+type BlockTag struct {
+	Name  string
+	Nodes []any
 }
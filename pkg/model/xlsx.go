@@ -17,6 +17,11 @@ const (
 	XMLRelTypeWorksheet      = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet"
 	XMLRelTypeStyles         = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles"
 	XMLRelTypeSharedStrings  = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/sharedStrings"
+	XMLRelTypeCoreProperties = "http://schemas.openxmlformats.org/package/2006/relationships/metadata/core-properties"
+
+	// Document properties namespaces
+	XMLNsCoreProperties = "http://schemas.openxmlformats.org/package/2006/metadata/core-properties"
+	XMLNsDublinCore     = "http://purl.org/dc/elements/1.1/"
 )
 
 // CellType indicates the kind of cell value; a hint for writer.
@@ -43,10 +48,13 @@ type CellStyle struct {
 	FontColor string // RGB hex color: "FF0000" for red
 	FillColor string // Cell background RGB hex: "FFFF00" for yellow
 
-	// Alignment (future)
+	// Alignment
 	HAlign string // "left", "center", "right"
 	VAlign string // "top", "middle", "bottom"
 
+	// Number format code, e.g. "#,##0.00" or "yyyy-mm-dd" (empty means General)
+	NumberFormat string
+
 	// Border (optional)
 	Border *CellBorder
 }
@@ -139,9 +147,16 @@ type PivotTable struct {
 	Options     map[string]string
 }
 
+// Ways a rendered sheet is written into the sheet of the same name in a base workbook
+const (
+	SheetModeOverlay = "overlay" // Rendered cells replace the base cells they cover; the rest is kept
+	SheetModeReplace = "replace" // The base sheet's cells and merges are cleared first
+)
+
 // Sheet contains grid cells and drawing objects.
 type Sheet struct {
 	Name     string
+	Mode     string // SheetModeOverlay (default) or SheetModeReplace; only used with a base workbook
 	Cells    []*Cell
 	Merges   []Merge
 	Images   []Image
@@ -175,6 +190,7 @@ func (s *Sheet) AddPivot(p PivotTable) { s.Pivots = append(s.Pivots, p) }
 // Book represents a workbook containing multiple sheets.
 type Book struct {
 	Sheets []*Sheet
+	Base   string // Optional: .xlsx workbook the sheets are written into (resolved path)
 }
 
 // NewBook creates an empty workbook.
@@ -222,6 +238,15 @@ type XMLOverride struct {
 	ContentType string   `xml:"ContentType,attr"`
 }
 
+// XMLCoreProperties represents the docProps/core.xml structure
+type XMLCoreProperties struct {
+	XMLName  struct{} `xml:"cp:coreProperties"`
+	XmlnsCP  string   `xml:"xmlns:cp,attr"`
+	XmlnsDC  string   `xml:"xmlns:dc,attr"`
+	Creator  string   `xml:"dc:creator,omitempty"`
+	Language string   `xml:"dc:language,omitempty"`
+}
+
 // XMLWorkbook represents the xl/workbook.xml structure
 type XMLWorkbook struct {
 	XMLName struct{}  `xml:"workbook"`
@@ -366,6 +391,7 @@ type XMLSharedStrings struct {
 type XMLStyleSheet struct {
 	XMLName      struct{}        `xml:"styleSheet"`
 	Xmlns        string          `xml:"xmlns,attr"`
+	NumFmts      *XMLNumFmts     `xml:"numFmts,omitempty"`
 	Fonts        XMLFonts        `xml:"fonts"`
 	Fills        XMLFills        `xml:"fills"`
 	Borders      XMLBorders      `xml:"borders"`
@@ -374,6 +400,20 @@ type XMLStyleSheet struct {
 	CellStyles   XMLCellStyles   `xml:"cellStyles"`
 }
 
+// XMLNumFmts contains custom number formats
+type XMLNumFmts struct {
+	XMLName struct{}    `xml:"numFmts"`
+	Count   int         `xml:"count,attr"`
+	NumFmt  []XMLNumFmt `xml:"numFmt"`
+}
+
+// XMLNumFmt represents a custom number format (IDs from 164 up)
+type XMLNumFmt struct {
+	XMLName    struct{} `xml:"numFmt"`
+	NumFmtID   int      `xml:"numFmtId,attr"`
+	FormatCode string   `xml:"formatCode,attr"`
+}
+
 // XMLFonts contains font definitions
 type XMLFonts struct {
 	XMLName struct{}  `xml:"fonts"`
@@ -519,6 +559,17 @@ type XMLXf struct {
 	ApplyFont   bool     `xml:"applyFont,attr,omitempty"`
 	ApplyFill   bool     `xml:"applyFill,attr,omitempty"`
 	ApplyBorder bool     `xml:"applyBorder,attr,omitempty"`
+
+	ApplyNumberFormat bool `xml:"applyNumberFormat,attr,omitempty"`
+	ApplyAlignment    bool `xml:"applyAlignment,attr,omitempty"`
+
+	Alignment *XMLAlignment `xml:"alignment,omitempty"`
+}
+
+// XMLAlignment represents the <alignment> of a cell format
+type XMLAlignment struct {
+	Horizontal string `xml:"horizontal,attr,omitempty"`
+	Vertical   string `xml:"vertical,attr,omitempty"`
 }
 
 // XMLCellStyleXfs represents base (named) styles
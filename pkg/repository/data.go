@@ -0,0 +1,238 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/transform"
+	"gopkg.in/yaml.v3"
+)
+
+// Data formats accepted by ReadData
+const (
+	DataFormatAuto = ""     // Detect from the file extension; unknown extensions try JSON, then YAML
+	DataFormatJSON = "json" // JSON object
+	DataFormatYAML = "yaml" // YAML mapping
+	DataFormatCSV  = "csv"  // Comma-separated values with a header row
+	DataFormatTSV  = "tsv"  // Tab-separated values with a header row
+)
+
+// Text encodings accepted by DataOptions.Encoding
+const (
+	DataEncodingUTF8     = "utf-8"
+	DataEncodingShiftJIS = "shift_jis"
+)
+
+// DefaultCSVKey is the data key under which CSV and TSV rows are exposed
+const DefaultCSVKey = "rows"
+
+// DataOptions controls how tabular data files are decoded
+type DataOptions struct {
+	Format    string // One of the DataFormat constants (empty detects from the file extension)
+	Delimiter rune   // Field delimiter for CSV (0 means ',' for CSV and '\t' for TSV)
+	Encoding  string // Text encoding of CSV and TSV files (empty means UTF-8; a UTF-8 BOM is always removed)
+	Key       string // Data key for the rows of CSV and TSV files (empty means DefaultCSVKey)
+	NoInfer   bool   // Keep every CSV and TSV field as a string instead of inferring numbers, booleans and dates
+}
+
+// ReadDataFromFile reads a JSON, YAML, CSV or TSV data file into a map.
+func ReadDataFromFile(path string, opts DataOptions) (map[string]any, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("read data: %w", err)
+	}
+	defer f.Close()
+
+	if opts.Format == DataFormatAuto {
+		opts.Format = DataFormatFromPath(path)
+	}
+	return ReadData(f, opts)
+}
+
+// DataFormatFromPath returns the data format implied by a file extension (DataFormatAuto if unknown).
+func DataFormatFromPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return DataFormatJSON
+	case ".yaml", ".yml":
+		return DataFormatYAML
+	case ".csv":
+		return DataFormatCSV
+	case ".tsv", ".tab":
+		return DataFormatTSV
+	default:
+		return DataFormatAuto
+	}
+}
+
+// ReadData decodes data in the given format from r.
+func ReadData(r io.Reader, opts DataOptions) (map[string]any, error) {
+	switch opts.Format {
+	case DataFormatCSV, DataFormatTSV:
+		return readTabularData(r, opts)
+	}
+
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("read data: %w", err)
+	}
+	var m map[string]any
+	switch opts.Format {
+	case DataFormatJSON:
+		if err := json.Unmarshal(b, &m); err != nil {
+			return nil, fmt.Errorf("parse data json: %w", err)
+		}
+	case DataFormatYAML:
+		if err := yaml.Unmarshal(b, &m); err != nil {
+			return nil, fmt.Errorf("parse data yaml: %w", err)
+		}
+	case DataFormatAuto:
+		// Try JSON first, then YAML
+		if err := json.Unmarshal(b, &m); err != nil {
+			if err := yaml.Unmarshal(b, &m); err != nil {
+				return nil, fmt.Errorf("parse data (tried JSON and YAML): %w", err)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown data format %q", opts.Format)
+	}
+	return m, nil
+}
+
+// readTabularData decodes CSV or TSV data with a header row into {key: [{header: value}, ...]}
+func readTabularData(r io.Reader, opts DataOptions) (map[string]any, error) {
+	decoded, err := decodeText(r, opts.Encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := csv.NewReader(decoded)
+	reader.Comma = opts.Delimiter
+	if reader.Comma == 0 {
+		reader.Comma = ','
+		if opts.Format == DataFormatTSV {
+			reader.Comma = '\t'
+		}
+	}
+	if opts.Format == DataFormatTSV {
+		// TSV exports rarely quote fields; treat quotes as ordinary characters
+		reader.LazyQuotes = true
+	}
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("parse data %s: missing header row", opts.Format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse data %s: %w", opts.Format, err)
+	}
+	seen := make(map[string]int, len(header))
+	for i, name := range header {
+		header[i] = strings.TrimSpace(name)
+		if header[i] == "" {
+			return nil, fmt.Errorf("parse data %s: column %d has an empty header", opts.Format, i+1)
+		}
+		if first, ok := seen[header[i]]; ok {
+			return nil, fmt.Errorf("parse data %s: columns %d and %d have the same header %q", opts.Format, first, i+1, header[i])
+		}
+		seen[header[i]] = i + 1
+	}
+
+	rows := []any{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parse data %s: %w", opts.Format, err)
+		}
+		if len(record) > len(header) {
+			line, _ := reader.FieldPos(0)
+			return nil, fmt.Errorf("parse data %s: line %d has %d fields, header has %d", opts.Format, line, len(record), len(header))
+		}
+		row := make(map[string]any, len(header))
+		for i, name := range header {
+			value := ""
+			if i < len(record) {
+				value = record[i]
+			}
+			if opts.NoInfer {
+				row[name] = value
+			} else {
+				row[name] = inferFieldValue(value)
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	key := opts.Key
+	if key == "" {
+		key = DefaultCSVKey
+	}
+	return map[string]any{key: rows}, nil
+}
+
+// decodeText converts r to UTF-8 and removes a leading UTF-8 byte order mark
+func decodeText(r io.Reader, encoding string) (io.Reader, error) {
+	switch strings.ToLower(strings.ReplaceAll(encoding, "-", "_")) {
+	case "", "utf_8", "utf8":
+	case "shift_jis", "sjis", "cp932", "windows_31j":
+		r = transform.NewReader(r, japanese.ShiftJIS.NewDecoder())
+	default:
+		return nil, fmt.Errorf("unsupported data encoding %q (expected %s or %s)", encoding, DataEncodingUTF8, DataEncodingShiftJIS)
+	}
+
+	br := bufio.NewReader(r)
+	if bom, err := br.Peek(3); err == nil && bytes.Equal(bom, []byte{0xEF, 0xBB, 0xBF}) {
+		_, _ = br.Discard(3)
+	}
+	return br, nil
+}
+
+// csvDateLayouts are the date formats recognized in CSV fields and normalized to YYYY-MM-DD
+var csvDateLayouts = []string{"2006-01-02", "2006/01/02", "2006/1/2", "2006.01.02"}
+
+// inferFieldValue converts a CSV field to an int64, float64, bool or ISO date string when it
+// unambiguously looks like one; anything else stays a string
+func inferFieldValue(s string) any {
+	v := strings.TrimSpace(s)
+	if v == "" || !utf8.ValidString(v) {
+		return s
+	}
+
+	// Keep identifiers with leading zeros (zip codes, product codes) as strings
+	if len(v) > 1 && v[0] == '0' && v[1] != '.' {
+		return s
+	}
+	if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return i
+	}
+	if f, err := strconv.ParseFloat(v, 64); err == nil && !strings.ContainsAny(v, "xXnN") {
+		return f
+	}
+	switch strings.ToLower(v) {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	for _, layout := range csvDateLayouts {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t.Format("2006-01-02")
+		}
+	}
+	return s
+}
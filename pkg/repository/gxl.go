@@ -8,6 +8,7 @@ import (
 	"encoding/xml"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"strconv"
 	"strings"
@@ -41,7 +42,15 @@ func (rcv *gxlRepository) ReadGxl() (model.GXL, error) {
 		return model.GXL{}, fmt.Errorf("file path is not set in config")
 	}
 	rcv.logger.DEBUG(util.RP1, "Reading GXL file", map[string]interface{}{"file": rcv.Conf.FilePath})
-	gxl, err := ReadGxlFromFile(rcv.Conf.FilePath, rcv.logger)
+	var (
+		gxl model.GXL
+		err error
+	)
+	if rcv.Conf.FS != nil {
+		gxl, err = ReadGxlFromFS(rcv.Conf.FS, rcv.Conf.FilePath, rcv.logger)
+	} else {
+		gxl, err = ReadGxlFromFile(rcv.Conf.FilePath, rcv.logger)
+	}
 	if err != nil {
 		rcv.logger.ERROR(util.RP2, "Failed to read GXL file")
 		return model.GXL{}, err
@@ -85,8 +94,25 @@ func ReadGxlFromFile(filePath string, logger util.Logger) (model.GXL, error) {
 		return model.GXL{}, fmt.Errorf("failed to open file: %w", err)
 	}
 	defer file.Close()
+	return ReadGxlFromReader(file, logger)
+}
+
+// ReadGxlFromFS reads and parses the .gxl file name (a slash-separated path) from fsys.
+func ReadGxlFromFS(fsys fs.FS, name string, logger util.Logger) (model.GXL, error) {
+	logger.DEBUG(util.FSO1, "Opening GXL file", map[string]interface{}{"file": name})
+	file, err := fsys.Open(name)
+	if err != nil {
+		logger.ERROR(util.FSR2, "Failed to open file")
+		return model.GXL{}, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+	return ReadGxlFromReader(file, logger)
+}
+
+// ReadGxlFromReader parses a .gxl template from r (for example, standard input).
+func ReadGxlFromReader(r io.Reader, logger util.Logger) (model.GXL, error) {
 	logger.DEBUG(util.XMLU1, "Parsing GXL XML content", nil)
-	gxl, err := parseGXL(file)
+	gxl, err := parseGXL(r)
 	if err != nil {
 		logger.ERROR(util.XMLU2, "Failed to parse GXL XML")
 		return model.GXL{}, err
@@ -115,40 +141,349 @@ func parseGXL(r io.Reader) (model.GXL, error) {
 				if name := getAttr(se, "name"); name != "" {
 					gxl.BookTag.Name = name
 				}
-			case "Import":
-				// Parse Import tag at book level
-				src := getAttr(se, "src")
-				sheet := getAttr(se, "sheet")
-				if src == "" || sheet == "" {
-					return model.GXL{}, fmt.Errorf("Import tag requires both 'src' and 'sheet' attributes")
+				gxl.BookTag.Extends = getAttr(se, "extends")
+				gxl.BookTag.Base = getAttr(se, "base")
+			case "Fragment":
+				fragment, err := parseFragmentTag(decoder, se)
+				if err != nil {
+					return model.GXL{}, err
 				}
-				importTag := model.ImportTag{
-					Src:   src,
-					Sheet: sheet,
+				gxl.Fragments = append(gxl.Fragments, fragment)
+			case "Header":
+				gxl.HeaderTag.Title = getAttr(se, "title")
+				gxl.HeaderTag.Version = getAttr(se, "version")
+				gxl.HeaderTag.Encoding = getAttr(se, "encoding")
+				gxl.HeaderTag.Schema = getAttr(se, "schema")
+			case "Params":
+				params, err := parseParamsTag(decoder)
+				if err != nil {
+					return model.GXL{}, err
 				}
-				gxl.Imports = append(gxl.Imports, importTag)
-				// Add to BookNodes to preserve order
-				gxl.BookNodes = append(gxl.BookNodes, model.BookNode{
-					Type:   model.BookNodeTypeImport,
-					Import: &importTag,
-				})
-			case "Sheet":
-				sheet, err := parseSheetTag(decoder, se)
+				gxl.HeaderTag.Params = append(gxl.HeaderTag.Params, params...)
+			case "Block":
+				block, err := parseBlockTag(decoder, se)
 				if err != nil {
 					return model.GXL{}, err
 				}
-				gxl.Sheets = append(gxl.Sheets, sheet)
+				gxl.Blocks = append(gxl.Blocks, block)
+			case "Component":
+				component, err := parseComponentTag(decoder, se)
+				if err != nil {
+					return model.GXL{}, err
+				}
+				gxl.Components = append(gxl.Components, component)
+			case "Import", "Sheet", "For", "If":
+				node, err := parseBookNode(decoder, se)
+				if err != nil {
+					return model.GXL{}, err
+				}
+				// Keep the legacy flat lists in sync for top-level nodes
+				switch node.Type {
+				case model.BookNodeTypeImport:
+					gxl.Imports = append(gxl.Imports, *node.Import)
+				case model.BookNodeTypeSheet:
+					gxl.Sheets = append(gxl.Sheets, *node.Sheet)
+				}
 				// Add to BookNodes to preserve order
-				gxl.BookNodes = append(gxl.BookNodes, model.BookNode{
-					Type:  model.BookNodeTypeSheet,
-					Sheet: &sheet,
-				})
+				gxl.BookNodes = append(gxl.BookNodes, *node)
 			}
 		}
 	}
 	return gxl, nil
 }
 
+// parseFragmentTag parses a <Fragment> definition whose children are sheet nodes.
+func parseFragmentTag(decoder *xml.Decoder, start xml.StartElement) (model.FragmentTag, error) {
+	fragment := model.FragmentTag{
+		Name: getAttr(start, "name"),
+	}
+	if fragment.Name == "" {
+		return fragment, fmt.Errorf("Fragment tag requires a 'name' attribute")
+	}
+
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return fragment, err
+		}
+		switch se := token.(type) {
+		case xml.StartElement:
+			node, err := parseNodeTag(decoder, se)
+			if err != nil {
+				return fragment, err
+			}
+			if node != nil {
+				fragment.Nodes = append(fragment.Nodes, node)
+			}
+		case xml.EndElement:
+			if se.Name.Local == "Fragment" {
+				return fragment, nil
+			}
+		}
+	}
+}
+
+// parseComponentTag parses a <Component> definition whose children are sheet nodes.
+func parseComponentTag(decoder *xml.Decoder, start xml.StartElement) (model.ComponentTag, error) {
+	component := model.ComponentTag{
+		Name: getAttr(start, "name"),
+	}
+	if component.Name == "" {
+		return component, fmt.Errorf("Component tag requires a 'name' attribute")
+	}
+	for _, param := range strings.Split(getAttr(start, "params"), ",") {
+		if param = strings.TrimSpace(param); param != "" {
+			component.Params = append(component.Params, param)
+		}
+	}
+
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return component, err
+		}
+		switch se := token.(type) {
+		case xml.StartElement:
+			node, err := parseNodeTag(decoder, se)
+			if err != nil {
+				return component, err
+			}
+			if node != nil {
+				component.Nodes = append(component.Nodes, node)
+			}
+		case xml.EndElement:
+			if se.Name.Local == "Component" {
+				return component, nil
+			}
+		}
+	}
+}
+
+// parseParamsTag parses the <Param> declarations of a <Params> section.
+func parseParamsTag(decoder *xml.Decoder) ([]model.ParamTag, error) {
+	var params []model.ParamTag
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch se := token.(type) {
+		case xml.StartElement:
+			if se.Name.Local != "Param" {
+				return nil, fmt.Errorf("unexpected <%s> in <Params>: only <Param> is allowed", se.Name.Local)
+			}
+			param, err := parseParamTag(se)
+			if err != nil {
+				return nil, err
+			}
+			if err := skipToEnd(decoder, "Param"); err != nil {
+				return nil, err
+			}
+			params = append(params, param)
+		case xml.EndElement:
+			if se.Name.Local == "Params" {
+				return params, nil
+			}
+		}
+	}
+}
+
+// parseParamTag parses the attributes of a single <Param>.
+func parseParamTag(start xml.StartElement) (model.ParamTag, error) {
+	param := model.ParamTag{
+		Name:        getAttr(start, "name"),
+		Type:        strings.ToLower(strings.TrimSpace(getAttr(start, "type"))),
+		Default:     getAttr(start, "default"),
+		Description: getAttr(start, "description"),
+	}
+	if param.Name == "" {
+		return param, fmt.Errorf("Param tag requires a 'name' attribute")
+	}
+	switch param.Type {
+	case "", model.ParamTypeString, model.ParamTypeNumber, model.ParamTypeInteger, model.ParamTypeBoolean,
+		model.ParamTypeDate, model.ParamTypeObject, model.ParamTypeArray:
+	default:
+		return param, fmt.Errorf("Param %q has unknown type %q", param.Name, param.Type)
+	}
+	if required := getAttr(start, "required"); required != "" {
+		v, err := strconv.ParseBool(required)
+		if err != nil {
+			return param, fmt.Errorf("Param %q has invalid 'required' value %q", param.Name, required)
+		}
+		param.Required = v
+	}
+	for _, value := range strings.Split(getAttr(start, "enum"), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			param.Enum = append(param.Enum, value)
+		}
+	}
+	return param, nil
+}
+
+// parseBlockTag parses a <Block> placeholder or override whose children are sheet nodes.
+func parseBlockTag(decoder *xml.Decoder, start xml.StartElement) (model.BlockTag, error) {
+	block := model.BlockTag{
+		Name: getAttr(start, "name"),
+	}
+	if block.Name == "" {
+		return block, fmt.Errorf("Block tag requires a 'name' attribute")
+	}
+
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return block, err
+		}
+		switch se := token.(type) {
+		case xml.StartElement:
+			node, err := parseNodeTag(decoder, se)
+			if err != nil {
+				return block, err
+			}
+			if node != nil {
+				block.Nodes = append(block.Nodes, node)
+			}
+		case xml.EndElement:
+			if se.Name.Local == "Block" {
+				return block, nil
+			}
+		}
+	}
+}
+
+// parseBookNode parses a book-level element (Import, Sheet, For or If).
+func parseBookNode(decoder *xml.Decoder, start xml.StartElement) (*model.BookNode, error) {
+	switch start.Name.Local {
+	case "Import":
+		src := getAttr(start, "src")
+		sheet := getAttr(start, "sheet")
+		components := getAttr(start, "components")
+		if src == "" || (sheet == "" && components == "") {
+			return nil, fmt.Errorf("Import tag requires 'src' and either 'sheet' or 'components' attributes")
+		}
+		importTag := model.ImportTag{
+			Src:        src,
+			Sheet:      sheet,
+			As:         getAttr(start, "as"),
+			Data:       getAttr(start, "data"),
+			Components: components,
+		}
+		if importTag.Sheet == model.ImportAllSheets && importTag.As != "" {
+			return nil, fmt.Errorf("Import tag 'as' cannot be combined with sheet=\"*\"")
+		}
+		if err := skipToEnd(decoder, "Import"); err != nil {
+			return nil, err
+		}
+		return &model.BookNode{Type: model.BookNodeTypeImport, Import: &importTag}, nil
+
+	case "Sheet":
+		sheet, err := parseSheetTag(decoder, start)
+		if err != nil {
+			return nil, err
+		}
+		return &model.BookNode{Type: model.BookNodeTypeSheet, Sheet: &sheet}, nil
+
+	case "For":
+		forTag, err := parseBookForTag(decoder, start)
+		if err != nil {
+			return nil, err
+		}
+		return &model.BookNode{Type: model.BookNodeTypeFor, For: &forTag}, nil
+
+	case "If":
+		ifTag, err := parseBookIfTag(decoder, start)
+		if err != nil {
+			return nil, err
+		}
+		return &model.BookNode{Type: model.BookNodeTypeIf, If: &ifTag}, nil
+
+	default:
+		if err := skipToEnd(decoder, start.Name.Local); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}
+}
+
+// parseBookForTag parses a <For> loop at book level whose body contains book-level nodes.
+func parseBookForTag(decoder *xml.Decoder, start xml.StartElement) (model.BookForTag, error) {
+	forTag := model.BookForTag{
+		Each: getAttr(start, "each"),
+	}
+
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return forTag, err
+		}
+
+		switch se := token.(type) {
+		case xml.StartElement:
+			node, err := parseBookNode(decoder, se)
+			if err != nil {
+				return forTag, err
+			}
+			if node != nil {
+				forTag.Body = append(forTag.Body, *node)
+			}
+		case xml.EndElement:
+			if se.Name.Local == "For" {
+				return forTag, nil
+			}
+		}
+	}
+}
+
+// parseBookIfTag parses an <If> conditional at book level whose branches contain book-level nodes.
+func parseBookIfTag(decoder *xml.Decoder, start xml.StartElement) (model.BookIfTag, error) {
+	ifTag := model.BookIfTag{
+		Cond: getAttr(start, "cond"),
+	}
+
+	inElse, elseClosed := false, false
+
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return ifTag, err
+		}
+
+		switch se := token.(type) {
+		case xml.StartElement:
+			// <Else> closes the If: nothing may follow it
+			if elseClosed {
+				return ifTag, fmt.Errorf("<%s> after </Else> in <If cond=%q>; <Else> must be the last element of <If>", se.Name.Local, ifTag.Cond)
+			}
+			if se.Name.Local == "Else" {
+				if inElse {
+					return ifTag, fmt.Errorf("nested <Else> in <If cond=%q>", ifTag.Cond)
+				}
+				inElse = true
+				continue
+			}
+
+			node, err := parseBookNode(decoder, se)
+			if err != nil {
+				return ifTag, err
+			}
+			if node != nil {
+				if inElse {
+					ifTag.Else = append(ifTag.Else, *node)
+				} else {
+					ifTag.Then = append(ifTag.Then, *node)
+				}
+			}
+		case xml.EndElement:
+			switch se.Name.Local {
+			case "Else":
+				inElse, elseClosed = false, true
+			case "If":
+				return ifTag, nil
+			}
+		}
+	}
+}
+
 // writeAlignedGrid formats pipe-delimited rows so that '|' columns align.
 // It indents each produced line by indentLevel (2 spaces per level).
 func writeAlignedGrid(buf *bytes.Buffer, content string, indentLevel int) {
@@ -278,6 +613,12 @@ func runeLen(s string) int {
 func parseSheetTag(decoder *xml.Decoder, start xml.StartElement) (model.SheetTag, error) {
 	sheet := model.SheetTag{
 		Name: getAttr(start, "name"),
+		Mode: getAttr(start, "mode"),
+	}
+	switch sheet.Mode {
+	case "", model.SheetModeOverlay, model.SheetModeReplace:
+	default:
+		return sheet, fmt.Errorf("sheet %q: mode must be %s or %s, got %q", sheet.Name, model.SheetModeOverlay, model.SheetModeReplace, sheet.Mode)
 	}
 
 	// Optional defaults
@@ -343,6 +684,49 @@ func parseNodeTag(decoder *xml.Decoder, start xml.StartElement) (any, error) {
 		// Import cannot be a child of Sheet - must be at book level
 		return nil, fmt.Errorf("invalid nesting: <Import> tag must appear at book level (under <Book>), not inside <Sheet> tag")
 
+	case "Fragment":
+		// Fragment definitions belong at book level
+		return nil, fmt.Errorf("invalid nesting: <Fragment> tag must appear at book level (under <Book>), not inside <Sheet> tag")
+
+	case "Component":
+		// Component definitions belong at book level
+		return nil, fmt.Errorf("invalid nesting: <Component> tag must appear at book level (under <Book>), not inside <Sheet> tag")
+
+	case "Block":
+		return parseBlockTag(decoder, start)
+
+	case "Use":
+		node := model.UseTag{
+			Component: getAttr(start, "component"),
+			Args:      make(map[string]string),
+		}
+		if node.Component == "" {
+			return nil, fmt.Errorf("Use tag requires a 'component' attribute")
+		}
+		for _, attr := range start.Attr {
+			if attr.Name.Local != "component" {
+				node.Args[attr.Name.Local] = attr.Value
+			}
+		}
+		if err := skipToEnd(decoder, "Use"); err != nil {
+			return nil, err
+		}
+		return node, nil
+
+	case "Include":
+		node := model.IncludeTag{
+			Src:      getAttr(start, "src"),
+			Fragment: getAttr(start, "fragment"),
+			With:     getAttr(start, "with"),
+		}
+		if node.Src == "" || node.Fragment == "" {
+			return nil, fmt.Errorf("Include tag requires both 'src' and 'fragment' attributes")
+		}
+		if err := skipToEnd(decoder, "Include"); err != nil {
+			return nil, err
+		}
+		return node, nil
+
 	case "Anchor":
 		node := model.AnchorTag{Ref: getAttr(start, "ref")}
 		if err := skipToEnd(decoder, "Anchor"); err != nil {
@@ -360,6 +744,20 @@ func parseNodeTag(decoder *xml.Decoder, start xml.StartElement) (any, error) {
 	case "Grid":
 		return parseGridTag(decoder, start)
 
+	case "Column":
+		index, err := strconv.Atoi(getAttr(start, "index"))
+		if err != nil || index < 1 {
+			return nil, fmt.Errorf("<Column>: index must be a column number from 1, got %q", getAttr(start, "index"))
+		}
+		width, _ := util.ParseColWidth(getAttr(start, "width"))
+		if width <= 0 {
+			return nil, fmt.Errorf("<Column index=\"%d\">: invalid width %q", index, getAttr(start, "width"))
+		}
+		if err := skipToEnd(decoder, "Column"); err != nil {
+			return nil, err
+		}
+		return model.ColumnTag{Index: index, Width: width}, nil
+
 	case "Image":
 		node := model.ImageTag{
 			Ref: getAttr(start, "ref"),
@@ -469,6 +867,8 @@ func parseGridTag(decoder *xml.Decoder, start xml.StartElement) (model.GridTag,
 	var borderStyle string
 	var borderColor string
 	var borderSides string
+	var bold, italic, underline bool
+	var hAlign, vAlign, numberFormat string
 	for _, attr := range start.Attr {
 		if attr.Name.Local == "ref" {
 			ref = attr.Value
@@ -488,6 +888,18 @@ func parseGridTag(decoder *xml.Decoder, start xml.StartElement) (model.GridTag,
 			borderColor = sanitizeColor(attr.Value)
 		} else if attr.Name.Local == "border_sides" || attr.Name.Local == "borderSides" {
 			borderSides = strings.ToLower(strings.TrimSpace(attr.Value))
+		} else if attr.Name.Local == "bold" {
+			bold = attr.Value == "true"
+		} else if attr.Name.Local == "italic" {
+			italic = attr.Value == "true"
+		} else if attr.Name.Local == "underline" {
+			underline = attr.Value == "true"
+		} else if attr.Name.Local == "h_align" || attr.Name.Local == "hAlign" {
+			hAlign = strings.ToLower(strings.TrimSpace(attr.Value))
+		} else if attr.Name.Local == "v_align" || attr.Name.Local == "vAlign" {
+			vAlign = strings.ToLower(strings.TrimSpace(attr.Value))
+		} else if attr.Name.Local == "number_format" || attr.Name.Local == "numberFormat" {
+			numberFormat = attr.Value
 		}
 	}
 
@@ -515,6 +927,13 @@ func parseGridTag(decoder *xml.Decoder, start xml.StartElement) (model.GridTag,
 					BorderStyle: borderStyle,
 					BorderColor: borderColor,
 					BorderSides: borderSides,
+
+					Bold:         bold,
+					Italic:       italic,
+					Underline:    underline,
+					HAlign:       hAlign,
+					VAlign:       vAlign,
+					NumberFormat: numberFormat,
 				}, nil
 			}
 		}
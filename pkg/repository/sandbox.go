@@ -0,0 +1,57 @@
+package parser
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// SandboxError reports a file reference that the sandbox blocked
+type SandboxError struct {
+	Path   string // The path as written in the template
+	Reason string
+}
+
+func (e *SandboxError) Error() string {
+	return fmt.Sprintf("sandbox: access to %q blocked: %s", e.Path, e.Reason)
+}
+
+// SandboxPath resolves src against dir (unless it is absolute) and checks that the result
+// stays inside root, also after following symlinks. It returns the cleaned absolute path.
+func SandboxPath(root, dir, src string) (string, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return "", fmt.Errorf("sandbox root: %w", err)
+	}
+	path := src
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, src)
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	if !withinDir(absRoot, absPath) {
+		return "", &SandboxError{Path: src, Reason: "outside the sandbox root " + absRoot}
+	}
+
+	// A symlink inside the root may still point outside it. Paths that do not exist are left
+	// to fail when they are read.
+	realRoot, err := filepath.EvalSymlinks(absRoot)
+	if err != nil {
+		return "", fmt.Errorf("sandbox root: %w", err)
+	}
+	if realPath, err := filepath.EvalSymlinks(absPath); err == nil && !withinDir(realRoot, realPath) {
+		return "", &SandboxError{Path: src, Reason: "symlink leads outside the sandbox root " + absRoot}
+	}
+	return absPath, nil
+}
+
+// withinDir reports whether path is dir or lies below it; both must be clean and absolute
+func withinDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,255 @@
+package parser
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ryo-arima/goxcel/pkg/config"
+	"github.com/ryo-arima/goxcel/pkg/util"
+	"gopkg.in/yaml.v3"
+)
+
+// builtinTemplates holds the templates shipped with goxcel, one directory per template
+//
+//go:embed templates
+var builtinTemplates embed.FS
+
+// Template sources reported by TemplateRepository
+const (
+	TemplateSourceBuiltin = "built-in" // Embedded in the binary
+	TemplateSourceUser    = "user"     // Read from the user templates directory
+)
+
+// Files of a template directory
+const (
+	TemplateManifestFile = "template.yaml" // Optional metadata
+	TemplateBaseFile     = "base.gxl"      // The template itself
+	TemplatePreviewFile  = "base.yaml"     // Default preview data
+)
+
+// TemplateManifest is the metadata in a template's template.yaml
+type TemplateManifest struct {
+	Title       string   `json:"title,omitempty" yaml:"title"`
+	Description string   `json:"description,omitempty" yaml:"description"`
+	Version     string   `json:"version,omitempty" yaml:"version"`
+	Tags        []string `json:"tags,omitempty" yaml:"tags"`
+	Params      []string `json:"params,omitempty" yaml:"params"`   // Data keys the template requires
+	Preview     string   `json:"preview,omitempty" yaml:"preview"` // Sample data file (default base.yaml)
+	Files       []string `json:"files,omitempty" yaml:"files"`     // Extra files, directories or glob patterns (images, partials)
+}
+
+// TemplateInfo describes an available template
+type TemplateInfo struct {
+	Name      string           `json:"name"`          // Directory name, used with --template-name
+	Source    string           `json:"source"`        // TemplateSourceBuiltin or TemplateSourceUser
+	Overrides bool             `json:"overrides"`     // A user template that replaces the built-in template of the same name
+	Dir       string           `json:"dir,omitempty"` // Directory on disk (empty for built-in templates)
+	Manifest  TemplateManifest `json:"manifest"`
+}
+
+// TemplateRepository locates built-in and user templates
+type TemplateRepository interface {
+	// ListTemplates returns every available template sorted by name. A user template hides the
+	// built-in template with the same name.
+	ListTemplates() ([]TemplateInfo, error)
+	// OpenTemplate returns the files of a template rooted at its directory.
+	OpenTemplate(name string) (fs.FS, TemplateInfo, error)
+}
+
+// ReadTemplateManifest reads template.yaml from a template directory. A template without a
+// manifest gets an empty one; the preview defaults to base.yaml when that file exists.
+func ReadTemplateManifest(fsys fs.FS) (TemplateManifest, error) {
+	var manifest TemplateManifest
+	b, err := fs.ReadFile(fsys, TemplateManifestFile)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return manifest, err
+	default:
+		if err := yaml.Unmarshal(b, &manifest); err != nil {
+			return manifest, fmt.Errorf("parse %s: %w", TemplateManifestFile, err)
+		}
+	}
+
+	if manifest.Preview == "" {
+		if _, err := fs.Stat(fsys, TemplatePreviewFile); err == nil {
+			manifest.Preview = TemplatePreviewFile
+		}
+	}
+	for _, name := range append([]string{manifest.Preview}, manifest.Files...) {
+		if name != "" && !fs.ValidPath(name) {
+			return manifest, fmt.Errorf("%s: invalid file %q (paths must be relative to the template directory)", TemplateManifestFile, name)
+		}
+	}
+	return manifest, nil
+}
+
+// TemplateFiles lists the files that make up a template: the manifest, base.gxl, the preview
+// data and every file matched by the manifest's files entries. Directories are walked.
+func TemplateFiles(fsys fs.FS, manifest TemplateManifest) ([]string, error) {
+	seen := map[string]bool{}
+	var files []string
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			files = append(files, name)
+		}
+	}
+
+	for _, name := range []string{TemplateManifestFile, TemplateBaseFile, manifest.Preview} {
+		if name == "" {
+			continue
+		}
+		if _, err := fs.Stat(fsys, name); err == nil {
+			add(name)
+		}
+	}
+
+	for _, pattern := range manifest.Files {
+		matches, err := fs.Glob(fsys, pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid pattern %q: %w", TemplateManifestFile, pattern, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("%s: file %q not found", TemplateManifestFile, pattern)
+		}
+		for _, match := range matches {
+			err := fs.WalkDir(fsys, match, func(name string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if !d.IsDir() {
+					add(name)
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return files, nil
+}
+
+type templateRepository struct {
+	Conf    config.BaseConfig
+	logger  util.Logger
+	userDir string
+}
+
+// NewTemplateRepository creates a template repository reading user templates from
+// conf.TemplatesDir, or from the default user templates directory when it is empty.
+func NewTemplateRepository(conf config.BaseConfig) TemplateRepository {
+	dir := conf.TemplatesDir
+	if dir == "" {
+		var err error
+		if dir, err = UserTemplatesDir(); err != nil {
+			conf.Logger.DEBUG(util.FSR1, "User templates directory unavailable", map[string]interface{}{"error": err.Error()})
+		}
+	}
+	return &templateRepository{Conf: conf, logger: conf.Logger, userDir: dir}
+}
+
+// UserTemplatesDir returns the directory for user templates:
+// $XDG_CONFIG_HOME/goxcel/templates, or goxcel/templates under the OS user config directory.
+func UserTemplatesDir() (string, error) {
+	base := os.Getenv("XDG_CONFIG_HOME")
+	if base == "" {
+		var err error
+		if base, err = os.UserConfigDir(); err != nil {
+			return "", err
+		}
+	}
+	return filepath.Join(base, "goxcel", "templates"), nil
+}
+
+// ListTemplates returns the built-in and user templates
+func (rcv *templateRepository) ListTemplates() ([]TemplateInfo, error) {
+	byName := map[string]TemplateInfo{}
+
+	entries, err := fs.ReadDir(builtinTemplates, "templates")
+	if err != nil {
+		return nil, fmt.Errorf("read built-in templates: %w", err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			byName[entry.Name()] = TemplateInfo{Name: entry.Name(), Source: TemplateSourceBuiltin}
+		}
+	}
+
+	if rcv.userDir != "" {
+		entries, err := os.ReadDir(rcv.userDir)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("read user templates: %w", err)
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				continue
+			}
+			_, builtin := byName[entry.Name()]
+			byName[entry.Name()] = TemplateInfo{
+				Name:      entry.Name(),
+				Source:    TemplateSourceUser,
+				Overrides: builtin,
+				Dir:       filepath.Join(rcv.userDir, entry.Name()),
+			}
+		}
+	}
+
+	templates := make([]TemplateInfo, 0, len(byName))
+	for _, info := range byName {
+		fsys, err := rcv.templateFS(info)
+		if err != nil {
+			return nil, err
+		}
+		if info.Manifest, err = ReadTemplateManifest(fsys); err != nil {
+			return nil, fmt.Errorf("template %q: %w", info.Name, err)
+		}
+		templates = append(templates, info)
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+	return templates, nil
+}
+
+// OpenTemplate returns the files of the named template, preferring the user templates directory
+func (rcv *templateRepository) OpenTemplate(name string) (fs.FS, TemplateInfo, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		return nil, TemplateInfo{}, fmt.Errorf("invalid template name %q", name)
+	}
+
+	info := TemplateInfo{Name: name, Source: TemplateSourceBuiltin}
+	_, builtinErr := fs.Stat(builtinTemplates, "templates/"+name)
+	if rcv.userDir != "" {
+		dir := filepath.Join(rcv.userDir, name)
+		if st, err := os.Stat(dir); err == nil && st.IsDir() {
+			info = TemplateInfo{Name: name, Source: TemplateSourceUser, Overrides: builtinErr == nil, Dir: dir}
+		}
+	}
+	if info.Source == TemplateSourceBuiltin && builtinErr != nil {
+		return nil, TemplateInfo{}, fmt.Errorf("template %q not found", name)
+	}
+	rcv.logger.DEBUG(util.FSR1, "Using template", map[string]interface{}{"template": name, "source": info.Source})
+
+	fsys, err := rcv.templateFS(info)
+	if err != nil {
+		return nil, TemplateInfo{}, err
+	}
+	if info.Manifest, err = ReadTemplateManifest(fsys); err != nil {
+		return nil, TemplateInfo{}, fmt.Errorf("template %q: %w", name, err)
+	}
+	return fsys, info, nil
+}
+
+// templateFS returns the files of a template found by ListTemplates or OpenTemplate
+func (rcv *templateRepository) templateFS(info TemplateInfo) (fs.FS, error) {
+	if info.Source == TemplateSourceUser {
+		return os.DirFS(info.Dir), nil
+	}
+	return fs.Sub(builtinTemplates, "templates/"+info.Name)
+}
@@ -2,13 +2,16 @@ package parser
 
 import (
 	"archive/zip"
+	"compress/flate"
 	"encoding/xml"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 
 	"github.com/ryo-arima/goxcel/pkg/config"
 	"github.com/ryo-arima/goxcel/pkg/model"
+	"github.com/ryo-arima/goxcel/pkg/util"
 )
 
 // This file is intentionally left minimal. The renderer has moved to pkg/usecase.
@@ -16,6 +19,7 @@ import (
 
 // XlsxRepository manages Excel workbook operations.
 type XlsxRepository interface {
+	ReadBook(filePath string) (*model.Book, error)
 	CreateBook() model.Book
 	CreateSheet(book model.Book, name string) model.Sheet
 	CreateCell(sheet model.Sheet, cell model.Cell) model.Cell
@@ -38,6 +42,20 @@ func NewXlsxRepository(conf config.BaseConfig) XlsxRepository {
 	}
 }
 
+// ReadBook reads an existing .xlsx file.
+func (r *xlsxRepository) ReadBook(filePath string) (*model.Book, error) {
+	book, err := ReadBookFromFile(filePath)
+	if r.Conf.Logger == nil {
+		return book, err
+	}
+	if err != nil {
+		r.Conf.Logger.ERROR(util.XLSXR2, "Failed to read workbook", map[string]interface{}{"file": filePath, "error": err.Error()})
+		return nil, err
+	}
+	r.Conf.Logger.DEBUG(util.XLSXR1, "Workbook read", map[string]interface{}{"file": filePath, "sheets": len(book.Sheets)})
+	return book, nil
+}
+
 // CreateBook creates a new empty workbook.
 func (r *xlsxRepository) CreateBook() model.Book {
 	return *model.NewBook()
@@ -159,14 +177,124 @@ func (r *xlsxRepository) ClearCell(book model.Book, sheet model.Sheet, cell mode
 
 // WriteBookToFile writes a Book to an XLSX file.
 func WriteBookToFile(book *model.Book, filePath string) error {
+	return WriteBookToFileWithConfig(book, filePath, config.BaseConfig{})
+}
+
+// WriteBookToFileWithConfig writes a Book to an XLSX file using the writer options and locale of conf.
+func WriteBookToFileWithConfig(book *model.Book, filePath string, conf config.BaseConfig) error {
+	// The base workbook is read first, so that it may also be the output file
+	base, err := bookBase(book, conf)
+	if err != nil {
+		return err
+	}
 	file, err := os.Create(filePath)
 	if err != nil {
 		return fmt.Errorf("failed to create file: %w", err)
 	}
-	defer file.Close()
+	if err := writeBook(book, file, conf, base); err != nil {
+		// Do not leave a truncated package behind
+		file.Close()
+		os.Remove(filePath)
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to close file: %w", err)
+	}
+	return nil
+}
+
+// WriteBook writes a Book as an XLSX package to w (for example, standard output).
+// The writer only needs to support sequential writes.
+func WriteBook(book *model.Book, w io.Writer) error {
+	return WriteBookWithConfig(book, w, config.BaseConfig{})
+}
+
+// WriteBookWithConfig writes a Book as an XLSX package to w using the writer options, locale
+// and output size limit of conf. Document properties are only written when a creator or
+// locale is set. When the book has a base workbook, it is read from conf.FS (or the OS
+// filesystem) and the sheets are written into a copy of it.
+func WriteBookWithConfig(book *model.Book, w io.Writer, conf config.BaseConfig) error {
+	base, err := bookBase(book, conf)
+	if err != nil {
+		return err
+	}
+	return writeBook(book, w, conf, base)
+}
+
+// bookBase reads the base workbook of book (nil when it has none)
+func bookBase(book *model.Book, conf config.BaseConfig) ([]byte, error) {
+	if book.Base == "" {
+		return nil, nil
+	}
+	return readBase(conf.FS, book.Base)
+}
+
+// writeBook writes book to w, into a copy of base when it is not nil
+func writeBook(book *model.Book, w io.Writer, conf config.BaseConfig, base []byte) error {
+	level, err := conf.Writer.CompressionLevel()
+	if err != nil {
+		return err
+	}
+	if max := conf.Limits.MaxOutputBytes; max > 0 {
+		w = &limitedWriter{w: w, remaining: max, max: max}
+	}
+	zipWriter := zip.NewWriter(w)
+	if level != flate.DefaultCompression {
+		zipWriter.RegisterCompressor(zip.Deflate, func(out io.Writer) (io.WriteCloser, error) {
+			return flate.NewWriter(out, level)
+		})
+	}
+
+	// A book with a base workbook is written into a copy of it, keeping its document properties
+	if base != nil {
+		if err := writeBookOverBase(zipWriter, book, base); err != nil {
+			zipWriter.Close()
+			return err
+		}
+		if err := zipWriter.Close(); err != nil {
+			return fmt.Errorf("failed to finish xlsx package: %w", err)
+		}
+		return nil
+	}
+
+	var props *model.XMLCoreProperties
+	if conf.Writer.Creator != "" || conf.Locale != "" {
+		props = &model.XMLCoreProperties{
+			XmlnsCP:  model.XMLNsCoreProperties,
+			XmlnsDC:  model.XMLNsDublinCore,
+			Creator:  conf.Writer.Creator,
+			Language: conf.Locale,
+		}
+	}
+
+	if err := writeBookParts(zipWriter, book, props); err != nil {
+		zipWriter.Close()
+		return err
+	}
+	if err := zipWriter.Close(); err != nil {
+		return fmt.Errorf("failed to finish xlsx package: %w", err)
+	}
+	return nil
+}
+
+// limitedWriter fails with a LimitError once more than max bytes have been written
+type limitedWriter struct {
+	w         io.Writer
+	remaining int64
+	max       int64
+}
 
-	zipWriter := zip.NewWriter(file)
-	defer zipWriter.Close()
+func (lw *limitedWriter) Write(p []byte) (int, error) {
+	if int64(len(p)) > lw.remaining {
+		return 0, &config.LimitError{Limit: config.LimitOutputBytes, Max: lw.max}
+	}
+	n, err := lw.w.Write(p)
+	lw.remaining -= int64(n)
+	return n, err
+}
+
+// writeBookParts writes every part of the XLSX package into zipWriter
+func writeBookParts(zipWriter *zip.Writer, book *model.Book, props *model.XMLCoreProperties) error {
 
 	// Collect all unique styles from cells
 	styleCollector := newStyleCollector()
@@ -179,15 +307,22 @@ func WriteBookToFile(book *model.Book, filePath string) error {
 	}
 
 	// Write _rels/.rels
-	if err := writeRels(zipWriter); err != nil {
+	if err := writeRels(zipWriter, props != nil); err != nil {
 		return err
 	}
 
 	// Write [Content_Types].xml
-	if err := writeContentTypes(zipWriter, len(book.Sheets)); err != nil {
+	if err := writeContentTypes(zipWriter, len(book.Sheets), props != nil); err != nil {
 		return err
 	}
 
+	// Write docProps/core.xml
+	if props != nil {
+		if err := writeCoreProperties(zipWriter, props); err != nil {
+			return err
+		}
+	}
+
 	// Write xl/_rels/workbook.xml.rels
 	if err := writeWorkbookRels(zipWriter, len(book.Sheets)); err != nil {
 		return err
@@ -231,7 +366,7 @@ func WriteBookToFile(book *model.Book, filePath string) error {
 	return nil
 }
 
-func writeRels(zw *zip.Writer) error {
+func writeRels(zw *zip.Writer, coreProps bool) error {
 	w, err := zw.Create("_rels/.rels")
 	if err != nil {
 		return err
@@ -247,6 +382,13 @@ func writeRels(zw *zip.Writer) error {
 			},
 		},
 	}
+	if coreProps {
+		rels.Relationships = append(rels.Relationships, model.XMLRelationship{
+			ID:     "rId2",
+			Type:   model.XMLRelTypeCoreProperties,
+			Target: "docProps/core.xml",
+		})
+	}
 
 	data, err := xml.MarshalIndent(rels, "", "  ")
 	if err != nil {
@@ -261,7 +403,7 @@ func writeRels(zw *zip.Writer) error {
 	return err
 }
 
-func writeContentTypes(zw *zip.Writer, numSheets int) error {
+func writeContentTypes(zw *zip.Writer, numSheets int, coreProps bool) error {
 	w, err := zw.Create("[Content_Types].xml")
 	if err != nil {
 		return err
@@ -280,6 +422,13 @@ func writeContentTypes(zw *zip.Writer, numSheets int) error {
 		},
 	}
 
+	if coreProps {
+		types.Overrides = append(types.Overrides, model.XMLOverride{
+			PartName:    "/docProps/core.xml",
+			ContentType: "application/vnd.openxmlformats-package.core-properties+xml",
+		})
+	}
+
 	for i := 1; i <= numSheets; i++ {
 		types.Overrides = append(types.Overrides, model.XMLOverride{
 			PartName:    fmt.Sprintf("/xl/worksheets/sheet%d.xml", i),
@@ -512,7 +661,16 @@ func writeSheetWithStyles(zw *zip.Writer, sheet *model.Sheet, sheetNum int, styl
 	if err != nil {
 		return err
 	}
+	data, err := worksheetXML(sheet, styleCollector)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
 
+// worksheetXML renders a sheet as a worksheet part, including the XML declaration
+func worksheetXML(sheet *model.Sheet, styleCollector *styleCollector) ([]byte, error) {
 	worksheet := model.XMLWorksheet{
 		Xmlns: model.XMLNsSpreadsheetML,
 		SheetData: model.XMLSheetData{
@@ -608,6 +766,19 @@ func writeSheetWithStyles(zw *zip.Writer, sheet *model.Sheet, sheetNum int, styl
 	}
 
 	data, err := xml.MarshalIndent(worksheet, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+func writeCoreProperties(zw *zip.Writer, props *model.XMLCoreProperties) error {
+	w, err := zw.Create("docProps/core.xml")
+	if err != nil {
+		return err
+	}
+
+	data, err := xml.MarshalIndent(props, "", "  ")
 	if err != nil {
 		return err
 	}
@@ -663,17 +834,90 @@ func writeStylesWithCollector(zw *zip.Writer, sc *styleCollector) error {
 	}
 
 	// Build fonts, fills, and borders dynamically from collected styles
-	fonts := []model.XMLFont{}
-	fills := []model.XMLFill{{PatternFill: model.XMLPatternFill{PatternType: "none"}}}
-	borders := []model.XMLBorder{{
+	entries := sc.entries(0, styleBase{fills: 1, borders: 1, nextNumFmt: firstCustomNumFmtID})
+	fonts := entries.fonts
+	fills := append([]model.XMLFill{{PatternFill: model.XMLPatternFill{PatternType: "none"}}}, entries.fills...)
+	borders := append([]model.XMLBorder{{
 		Left:   model.XMLBorderSide{},
 		Right:  model.XMLBorderSide{},
 		Top:    model.XMLBorderSide{},
 		Bottom: model.XMLBorderSide{},
-	}}
-	xfs := []model.XMLXf{}
+	}}, entries.borders...)
+	xfs := entries.xfs
+	numFmts := entries.numFmts
+
+	styleSheet := model.XMLStyleSheet{
+		Xmlns: model.XMLNsSpreadsheetML,
+		Fonts: model.XMLFonts{
+			Count: len(fonts),
+			Font:  fonts,
+		},
+		Fills: model.XMLFills{
+			Count: len(fills),
+			Fill:  fills,
+		},
+		Borders: model.XMLBorders{
+			Count:  len(borders),
+			Border: borders,
+		},
+		CellStyleXfs: model.XMLCellStyleXfs{
+			Count: 1,
+			Xf: []model.XMLXf{
+				{NumFmtID: 0, FontID: 0, FillID: 0, BorderID: 0}, // base Normal
+			},
+		},
+		CellXfs: model.XMLCellXfs{
+			Count: len(xfs),
+			Xf:    xfs,
+		},
+		CellStyles: model.XMLCellStyles{
+			Count: 1,
+			Cell: []model.XMLCellStyle{
+				{Name: "Normal", XfID: 0, BuiltinID: 0},
+			},
+		},
+	}
+
+	if len(numFmts) > 0 {
+		styleSheet.NumFmts = &model.XMLNumFmts{Count: len(numFmts), NumFmt: numFmts}
+	}
 
-	for i, style := range sc.styles {
+	data, err := xml.MarshalIndent(styleSheet, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write([]byte(xml.Header))
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// styleBase holds the number of stylesheet entries that precede the generated ones
+type styleBase struct {
+	fonts      int
+	fills      int
+	borders    int
+	nextNumFmt int // First free custom number format ID
+}
+
+// styleEntries holds the stylesheet entries generated for collected styles
+type styleEntries struct {
+	numFmts []model.XMLNumFmt
+	fonts   []model.XMLFont
+	fills   []model.XMLFill
+	borders []model.XMLBorder
+	xfs     []model.XMLXf // One per style, in style ID order
+}
+
+// entries builds the fonts, fills, borders, number formats and cell formats of the styles
+// from index first on, numbering them after the entries counted in base
+func (sc *styleCollector) entries(first int, base styleBase) styleEntries {
+	var e styleEntries
+	numFmtIDs := map[string]int{}
+	for _, style := range sc.styles[first:] {
 		fontName := "Calibri"
 		fontSize := "11"
 
@@ -712,7 +956,8 @@ func writeStylesWithCollector(zw *zip.Writer, sc *styleCollector) error {
 			font.Charset = &model.XMLFontCharset{Val: 0}
 		}
 
-		fonts = append(fonts, font)
+		fontID := base.fonts + len(e.fonts)
+		e.fonts = append(e.fonts, font)
 
 		// Create fill for background color
 		fillID := 0
@@ -724,8 +969,8 @@ func writeStylesWithCollector(zw *zip.Writer, sc *styleCollector) error {
 					BgColor:     &model.XMLBgColor{Indexed: 64},
 				},
 			}
-			fills = append(fills, fill)
-			fillID = len(fills) - 1
+			fillID = base.fills + len(e.fills)
+			e.fills = append(e.fills, fill)
 		}
 
 		// Border
@@ -747,65 +992,70 @@ func writeStylesWithCollector(zw *zip.Writer, sc *styleCollector) error {
 				Top:    mkSide(style.Border.Top),
 				Bottom: mkSide(style.Border.Bottom),
 			}
-			borders = append(borders, b)
-			borderID = len(borders) - 1
+			borderID = base.borders + len(e.borders)
+			e.borders = append(e.borders, b)
+		}
+
+		// Number format: a built-in ID where one matches, otherwise a custom format
+		numFmtID := 0
+		if style != nil && style.NumberFormat != "" {
+			id, ok := builtinNumFmtID(style.NumberFormat)
+			if !ok {
+				if id, ok = numFmtIDs[style.NumberFormat]; !ok {
+					id = base.nextNumFmt + len(e.numFmts)
+					numFmtIDs[style.NumberFormat] = id
+					e.numFmts = append(e.numFmts, model.XMLNumFmt{NumFmtID: id, FormatCode: style.NumberFormat})
+				}
+			}
+			numFmtID = id
 		}
 
 		xf := model.XMLXf{
-			NumFmtID:    0,
-			FontID:      i,
-			FillID:      fillID,
-			BorderID:    borderID,
-			ApplyFont:   true,
-			ApplyFill:   fillID != 0,
-			ApplyBorder: borderID != 0,
+			NumFmtID:          numFmtID,
+			FontID:            fontID,
+			FillID:            fillID,
+			BorderID:          borderID,
+			ApplyFont:         true,
+			ApplyFill:         fillID != 0,
+			ApplyBorder:       borderID != 0,
+			ApplyNumberFormat: numFmtID != 0,
+		}
+		if style != nil && (style.HAlign != "" || style.VAlign != "") {
+			// SpreadsheetML calls the middle vertical alignment "center"
+			vertical := style.VAlign
+			if vertical == "middle" {
+				vertical = "center"
+			}
+			xf.Alignment = &model.XMLAlignment{Horizontal: style.HAlign, Vertical: vertical}
+			xf.ApplyAlignment = true
 		}
-		xfs = append(xfs, xf)
+		e.xfs = append(e.xfs, xf)
 	}
 
-	styleSheet := model.XMLStyleSheet{
-		Xmlns: model.XMLNsSpreadsheetML,
-		Fonts: model.XMLFonts{
-			Count: len(fonts),
-			Font:  fonts,
-		},
-		Fills: model.XMLFills{
-			Count: len(fills),
-			Fill:  fills,
-		},
-		Borders: model.XMLBorders{
-			Count:  len(borders),
-			Border: borders,
-		},
-		CellStyleXfs: model.XMLCellStyleXfs{
-			Count: 1,
-			Xf: []model.XMLXf{
-				{NumFmtID: 0, FontID: 0, FillID: 0, BorderID: 0}, // base Normal
-			},
-		},
-		CellXfs: model.XMLCellXfs{
-			Count: len(xfs),
-			Xf:    xfs,
-		},
-		CellStyles: model.XMLCellStyles{
-			Count: 1,
-			Cell: []model.XMLCellStyle{
-				{Name: "Normal", XfID: 0, BuiltinID: 0},
-			},
-		},
-	}
+	return e
+}
 
-	data, err := xml.MarshalIndent(styleSheet, "", "  ")
-	if err != nil {
-		return err
-	}
+// firstCustomNumFmtID is the first number format ID available for custom formats
+const firstCustomNumFmtID = 164
+
+// builtinNumFmts maps the built-in number format IDs to their format codes
+var builtinNumFmts = map[int]string{
+	0: "General", 1: "0", 2: "0.00", 3: "#,##0", 4: "#,##0.00",
+	9: "0%", 10: "0.00%", 11: "0.00E+00", 12: "# ?/?", 13: "# ??/??",
+	14: "mm-dd-yy", 15: "d-mmm-yy", 16: "d-mmm", 17: "mmm-yy",
+	18: "h:mm AM/PM", 19: "h:mm:ss AM/PM", 20: "h:mm", 21: "h:mm:ss", 22: "m/d/yy h:mm",
+	37: "#,##0 ;(#,##0)", 38: "#,##0 ;[Red](#,##0)", 39: "#,##0.00;(#,##0.00)", 40: "#,##0.00;[Red](#,##0.00)",
+	45: "mm:ss", 46: "[h]:mm:ss", 47: "mmss.0", 48: "##0.0E+0", 49: "@",
+}
 
-	_, err = w.Write([]byte(xml.Header))
-	if err != nil {
-		return err
+// builtinNumFmtID returns the built-in ID of a number format code
+func builtinNumFmtID(code string) (int, bool) {
+	for id, c := range builtinNumFmts {
+		if c == code {
+			return id, true
+		}
 	}
-	_, err = w.Write(data)
-	return err
+	return 0, false
 }
 
 // classifyFontFamily maps a font name to OOXML family code
@@ -862,6 +1112,7 @@ func parseA1Ref(ref string) (int, int, error) {
 type styleCollector struct {
 	styles   []*model.CellStyle
 	styleMap map[string]int // style signature -> style ID
+	xfOffset int            // Added to non-zero style IDs when the styles follow existing cell formats
 }
 
 func newStyleCollector() *styleCollector {
@@ -891,7 +1142,7 @@ func (sc *styleCollector) GetStyleID(style *model.CellStyle) int {
 
 	sig := sc.styleSignature(style)
 	if id, exists := sc.styleMap[sig]; exists {
-		return id
+		return id + sc.xfOffset
 	}
 	return 0
 }
@@ -904,9 +1155,9 @@ func (sc *styleCollector) styleSignature(style *model.CellStyle) string {
 			style.Border.Style, style.Border.Color,
 			style.Border.Top, style.Border.Right, style.Border.Bottom, style.Border.Left)
 	}
-	return fmt.Sprintf("%v|%v|%v|%s|%d|%s|%s%s",
+	return fmt.Sprintf("%v|%v|%v|%s|%d|%s|%s%s|n:%s|a:%s/%s",
 		style.Bold, style.Italic, style.Underline,
 		style.FontName, style.FontSize,
 		style.FontColor, style.FillColor,
-		bSig)
+		bSig, style.NumberFormat, style.HAlign, style.VAlign)
 }
@@ -0,0 +1,874 @@
+package parser
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ryo-arima/goxcel/pkg/model"
+)
+
+// Relationship and content types used when sheets are added to a base workbook
+const (
+	xmlRelTypeCalcChain     = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/calcChain"
+	xmlContentTypeWorksheet = "application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"
+)
+
+// readBase reads the base workbook of a book from fsys, or from the OS filesystem when fsys is nil
+func readBase(fsys fs.FS, name string) ([]byte, error) {
+	var (
+		data []byte
+		err  error
+	)
+	if fsys != nil {
+		data, err = fs.ReadFile(fsys, name)
+	} else {
+		data, err = os.ReadFile(name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read base workbook: %w", err)
+	}
+	return data, nil
+}
+
+// writeBookOverBase writes the sheets of book into a copy of the base workbook. Sheets whose
+// name matches a base sheet are written into it (see model.SheetModeOverlay and
+// model.SheetModeReplace); the others are added after the base sheets. Every other part of
+// the package (styles, theme, drawings, print settings, untouched sheets) is copied as is.
+func writeBookOverBase(zw *zip.Writer, book *model.Book, base []byte) error {
+	zr, err := zip.NewReader(bytes.NewReader(base), int64(len(base)))
+	if err != nil {
+		return fmt.Errorf("base workbook %s is not an xlsx package: %w", book.Base, err)
+	}
+	pkg := xlsxPackage{files: map[string]*zip.File{}}
+	for _, f := range zr.File {
+		pkg.files[f.Name] = f
+	}
+	ov := &baseOverlay{pkg: pkg, parts: map[string][]byte{}, removed: map[string]bool{}}
+	if err := ov.apply(book); err != nil {
+		return fmt.Errorf("base workbook %s: %w", book.Base, err)
+	}
+
+	// Copy the package in its original order, then add the new parts
+	for _, f := range zr.File {
+		if ov.removed[f.Name] {
+			continue
+		}
+		if data, ok := ov.parts[f.Name]; ok {
+			w, err := zw.Create(f.Name)
+			if err != nil {
+				return err
+			}
+			if _, err := w.Write(data); err != nil {
+				return err
+			}
+			delete(ov.parts, f.Name)
+			continue
+		}
+		if err := zw.Copy(f); err != nil {
+			return err
+		}
+	}
+	for _, name := range ov.added {
+		w, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(ov.parts[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// baseOverlay collects the parts of a base workbook that change
+type baseOverlay struct {
+	pkg     xlsxPackage
+	parts   map[string][]byte // New content by part name
+	added   []string          // Parts that are not in the base, in creation order
+	removed map[string]bool
+}
+
+// read returns the current content of a part
+func (ov *baseOverlay) read(name string) ([]byte, error) {
+	if data, ok := ov.parts[name]; ok {
+		return data, nil
+	}
+	f, ok := ov.pkg.files[name]
+	if !ok {
+		return nil, fmt.Errorf("missing part %s", name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+func (ov *baseOverlay) apply(book *model.Book) error {
+	// Locate the workbook, its relationships and the stylesheet
+	workbookPath := "xl/workbook.xml"
+	var rootRels xlsxRelationships
+	if err := ov.pkg.decode("_rels/.rels", &rootRels); err == nil {
+		if target := rootRels.target("", model.XMLRelTypeOfficeDocument); target != "" {
+			workbookPath = target
+		}
+	}
+	wbDir := path.Dir(workbookPath)
+	relsPath := path.Join(wbDir, "_rels", path.Base(workbookPath)+".rels")
+	var wb xlsxWorkbook
+	if err := ov.pkg.decode(workbookPath, &wb); err != nil {
+		return err
+	}
+	var wbRels xlsxRelationships
+	if err := ov.pkg.decode(relsPath, &wbRels); err != nil {
+		return err
+	}
+	stylesPath := wbRels.target(wbDir, model.XMLRelTypeStyles)
+	if stylesPath == "" || !ov.pkg.has(stylesPath) {
+		return fmt.Errorf("no styles part")
+	}
+
+	// Rendered styles are appended to the base stylesheet
+	sc := newStyleCollector()
+	for _, sheet := range book.Sheets {
+		for _, cell := range sheet.Cells {
+			sc.AddStyle(cell.Style)
+		}
+	}
+	if len(sc.styles) > 1 {
+		if err := ov.appendStyles(stylesPath, sc); err != nil {
+			return fmt.Errorf("styles: %w", err)
+		}
+	}
+
+	var newSheets []*model.Sheet
+	for _, sheet := range book.Sheets {
+		var ref *xlsxSheetRef
+		for i := range wb.Sheets {
+			if strings.EqualFold(wb.Sheets[i].Name, sheet.Name) {
+				ref = &wb.Sheets[i]
+				break
+			}
+		}
+		if ref == nil {
+			newSheets = append(newSheets, sheet)
+			continue
+		}
+		if wbRels.byID(ref.RID).Type != model.XMLRelTypeWorksheet {
+			return fmt.Errorf("sheet %q is not a worksheet", ref.Name)
+		}
+		target := wbRels.targetByID(wbDir, ref.RID)
+		data, err := ov.read(target)
+		if err != nil {
+			return fmt.Errorf("sheet %q: %w", ref.Name, err)
+		}
+		if data, err = overlaySheet(data, sheet, sc); err != nil {
+			return fmt.Errorf("sheet %q: %w", ref.Name, err)
+		}
+		ov.parts[target] = data
+	}
+	if len(newSheets) > 0 {
+		if err := ov.addSheets(workbookPath, relsPath, wbRels, newSheets, sc); err != nil {
+			return err
+		}
+	}
+
+	// The calculation chain lists formula cells; Excel rebuilds it when it is missing
+	if calcChain := wbRels.target(wbDir, xmlRelTypeCalcChain); calcChain != "" {
+		if err := ov.removePart(relsPath, calcChain, xmlRelTypeCalcChain); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// appendStyles adds the cell formats of sc after those of the base stylesheet and makes sc
+// number its styles accordingly
+func (ov *baseOverlay) appendStyles(stylesPath string, sc *styleCollector) error {
+	data, err := ov.read(stylesPath)
+	if err != nil {
+		return err
+	}
+	var ss xlsxStyleSheet
+	if err := xml.Unmarshal(data, &ss); err != nil {
+		return err
+	}
+	nextNumFmt := firstCustomNumFmtID
+	for _, nf := range ss.NumFmts {
+		if nf.ID >= nextNumFmt {
+			nextNumFmt = nf.ID + 1
+		}
+	}
+	entries := sc.entries(1, styleBase{
+		fonts:      len(ss.Fonts),
+		fills:      len(ss.Fills),
+		borders:    len(ss.Borders),
+		nextNumFmt: nextNumFmt,
+	})
+	sc.xfOffset = len(ss.CellXfs) - 1
+
+	doc, err := scanPart(data)
+	if err != nil {
+		return err
+	}
+	if doc.root.name.Space != "" {
+		return fmt.Errorf("stylesheets with a prefixed namespace (%s:styleSheet) are not supported", doc.root.name.Space)
+	}
+	var edits []partEdit
+	appendTo := func(name string, existing int, items any, n int) error {
+		if n == 0 {
+			return nil
+		}
+		var inner bytes.Buffer
+		if err := xml.NewEncoder(&inner).Encode(items); err != nil {
+			return err
+		}
+		if child := doc.child(name); child != nil {
+			edits = append(edits, child.appendContent(data, inner.Bytes(), existing+n))
+			return nil
+		}
+		// Only numFmts may be missing; it comes first in the stylesheet
+		if name != "numFmts" {
+			return fmt.Errorf("no <%s> element", name)
+		}
+		element := fmt.Sprintf(`<numFmts count="%d">%s</numFmts>`, n, inner.Bytes())
+		edits = append(edits, partEdit{start: doc.root.startEnd, end: doc.root.startEnd, text: []byte(element)})
+		return nil
+	}
+	if err := appendTo("numFmts", len(ss.NumFmts), entries.numFmts, len(entries.numFmts)); err != nil {
+		return err
+	}
+	if err := appendTo("fonts", len(ss.Fonts), entries.fonts, len(entries.fonts)); err != nil {
+		return err
+	}
+	if err := appendTo("fills", len(ss.Fills), entries.fills, len(entries.fills)); err != nil {
+		return err
+	}
+	if err := appendTo("borders", len(ss.Borders), entries.borders, len(entries.borders)); err != nil {
+		return err
+	}
+	if err := appendTo("cellXfs", len(ss.CellXfs), entries.xfs, len(entries.xfs)); err != nil {
+		return err
+	}
+	ov.parts[stylesPath] = applyEdits(data, edits)
+	return nil
+}
+
+// addSheets adds new worksheets after the base sheets
+func (ov *baseOverlay) addSheets(workbookPath, relsPath string, wbRels xlsxRelationships, sheets []*model.Sheet, sc *styleCollector) error {
+	wbData, err := ov.read(workbookPath)
+	if err != nil {
+		return err
+	}
+	wbDoc, err := scanPart(wbData)
+	if err != nil {
+		return err
+	}
+	sheetsEl := wbDoc.child("sheets")
+	if sheetsEl == nil {
+		return fmt.Errorf("workbook has no <sheets> element")
+	}
+	var ids struct {
+		Sheets []struct {
+			SheetID int `xml:"sheetId,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := xml.Unmarshal(wbData, &ids); err != nil {
+		return err
+	}
+	nextSheetID := 1
+	for _, s := range ids.Sheets {
+		if s.SheetID >= nextSheetID {
+			nextSheetID = s.SheetID + 1
+		}
+	}
+
+	// The r:id attribute needs a prefix bound to the relationships namespace
+	relPrefix := ""
+	for _, attr := range wbDoc.root.attrs {
+		if attr.Name.Space == "xmlns" && attr.Value == model.XMLNsOfficeDocRelationships {
+			relPrefix = attr.Name.Local
+		}
+	}
+	nsDecl := ""
+	if relPrefix == "" {
+		relPrefix = "r"
+		nsDecl = fmt.Sprintf(` xmlns:r="%s"`, model.XMLNsOfficeDocRelationships)
+	}
+
+	usedIDs := map[string]bool{}
+	for _, rel := range wbRels.Relationships {
+		usedIDs[rel.ID] = true
+	}
+	var sheetRefs, rels, overrides bytes.Buffer
+	for _, sheet := range sheets {
+		partName := ov.freePartName("xl/worksheets/sheet%d.xml")
+		relID := freeName("rId%d", usedIDs)
+		usedIDs[relID] = true
+
+		data, err := worksheetXML(sheet, sc)
+		if err != nil {
+			return err
+		}
+		ov.parts[partName] = data
+		ov.added = append(ov.added, partName)
+
+		fmt.Fprintf(&sheetRefs, `<sheet name="%s" sheetId="%d"%s %s:id="%s"/>`, escapeAttr(sheet.Name), nextSheetID, nsDecl, relPrefix, relID)
+		fmt.Fprintf(&rels, `<Relationship Id="%s" Type="%s" Target="/%s"/>`, relID, model.XMLRelTypeWorksheet, partName)
+		fmt.Fprintf(&overrides, `<Override PartName="/%s" ContentType="%s"/>`, partName, xmlContentTypeWorksheet)
+		nextSheetID++
+	}
+
+	ov.parts[workbookPath] = applyEdits(wbData, []partEdit{{start: sheetsEl.closeStart, end: sheetsEl.closeStart, text: sheetRefs.Bytes()}})
+	if err := ov.appendToRoot(relsPath, rels.Bytes()); err != nil {
+		return err
+	}
+	return ov.appendToRoot("[Content_Types].xml", overrides.Bytes())
+}
+
+// appendToRoot inserts text before the closing tag of the root element of a part
+func (ov *baseOverlay) appendToRoot(name string, text []byte) error {
+	data, err := ov.read(name)
+	if err != nil {
+		return err
+	}
+	doc, err := scanPart(data)
+	if err != nil {
+		return err
+	}
+	ov.parts[name] = applyEdits(data, []partEdit{{start: doc.root.closeStart, end: doc.root.closeStart, text: text}})
+	return nil
+}
+
+// removePart drops a part together with its relationship and content type override
+func (ov *baseOverlay) removePart(relsPath, partName, relType string) error {
+	ov.removed[partName] = true
+	drop := func(name string, match func(el *xmlElement) bool) error {
+		data, err := ov.read(name)
+		if err != nil {
+			return err
+		}
+		doc, err := scanPart(data)
+		if err != nil {
+			return err
+		}
+		var edits []partEdit
+		for _, el := range doc.children {
+			if match(el) {
+				edits = append(edits, partEdit{start: el.start, end: el.end})
+			}
+		}
+		ov.parts[name] = applyEdits(data, edits)
+		return nil
+	}
+	if err := drop(relsPath, func(el *xmlElement) bool { return el.attr("Type") == relType }); err != nil {
+		return err
+	}
+	return drop("[Content_Types].xml", func(el *xmlElement) bool { return el.attr("PartName") == "/"+partName })
+}
+
+// freePartName returns the first part name of the pattern (numbered from 1) that is not in use
+func (ov *baseOverlay) freePartName(pattern string) string {
+	used := map[string]bool{}
+	for name := range ov.pkg.files {
+		used[name] = true
+	}
+	for _, name := range ov.added {
+		used[name] = true
+	}
+	return freeName(pattern, used)
+}
+
+// freeName returns the first name of the pattern (numbered from 1) that is not in used
+func freeName(pattern string, used map[string]bool) string {
+	for i := 1; ; i++ {
+		if name := fmt.Sprintf(pattern, i); !used[name] {
+			return name
+		}
+	}
+}
+
+// overlaySheet writes the cells and merges of sheet into a base worksheet part. Everything
+// outside <sheetData>, <mergeCells> and <dimension> is kept byte for byte.
+func overlaySheet(data []byte, sheet *model.Sheet, sc *styleCollector) ([]byte, error) {
+	doc, err := scanPart(data)
+	if err != nil {
+		return nil, err
+	}
+	if doc.root.name.Space != "" {
+		return nil, fmt.Errorf("worksheets with a prefixed namespace (%s:worksheet) are not supported", doc.root.name.Space)
+	}
+	sheetData := doc.child("sheetData")
+	if sheetData == nil {
+		return nil, fmt.Errorf("no <sheetData> element")
+	}
+	replace := sheet.Mode == model.SheetModeReplace
+
+	// Rows of the base sheet, with their cells
+	rows := map[int]*baseRow{}
+	if !replace {
+		baseRows, err := scanRows(data, sheetData)
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range baseRows {
+			rows[row.num] = row
+		}
+	}
+
+	// Rendered cells replace base cells at the same position; unstyled ones keep the base style
+	for _, cell := range sheet.Cells {
+		r, c, err := parseA1Ref(cell.Ref)
+		if err != nil {
+			return nil, fmt.Errorf("cell %q: %w", cell.Ref, err)
+		}
+		row := rows[r]
+		if row == nil {
+			row = &baseRow{num: r, cells: map[int]baseCell{}}
+			rows[r] = row
+		}
+		xmlCell := createXMLCellWithStyle(cell, sc)
+		if cell.Style == nil {
+			if old, ok := row.cells[c]; ok && old.style != "" {
+				if s, err := strconv.Atoi(old.style); err == nil {
+					xmlCell.S = &s
+				}
+			}
+		}
+		raw, err := xml.Marshal(xmlCell)
+		if err != nil {
+			return nil, err
+		}
+		row.cells[c] = baseCell{raw: raw}
+		row.changed = true
+	}
+	if sheet.Config != nil {
+		for _, rh := range sheet.Config.RowHeights {
+			if row := rows[rh.Row]; row != nil {
+				row.height = rh.Height
+				row.changed = true
+			}
+		}
+	}
+
+	var body bytes.Buffer
+	body.WriteString("<sheetData>")
+	nums := make([]int, 0, len(rows))
+	for num := range rows {
+		nums = append(nums, num)
+	}
+	sort.Ints(nums)
+	minRow, maxRow, minCol, maxCol := 0, 0, 0, 0
+	for _, num := range nums {
+		row := rows[num]
+		if !row.changed && row.raw != nil {
+			body.Write(row.raw)
+		} else {
+			body.WriteString(row.startTag())
+		}
+		cols := make([]int, 0, len(row.cells))
+		for col := range row.cells {
+			cols = append(cols, col)
+		}
+		sort.Ints(cols)
+		if row.changed || row.raw == nil {
+			for _, col := range cols {
+				body.Write(row.cells[col].raw)
+			}
+			body.WriteString("</row>")
+		}
+
+		if len(cols) > 0 {
+			if minRow == 0 || num < minRow {
+				minRow = num
+			}
+			if num > maxRow {
+				maxRow = num
+			}
+			if minCol == 0 || cols[0] < minCol {
+				minCol = cols[0]
+			}
+			if last := cols[len(cols)-1]; last > maxCol {
+				maxCol = last
+			}
+		}
+	}
+	body.WriteString("</sheetData>")
+	edits := []partEdit{{start: sheetData.start, end: sheetData.end, text: body.Bytes()}}
+
+	if dim := doc.child("dimension"); dim != nil {
+		ref := "A1"
+		if maxRow > 0 {
+			ref = columnName(minCol) + strconv.Itoa(minRow)
+			if maxRow != minRow || maxCol != minCol {
+				ref += ":" + columnName(maxCol) + strconv.Itoa(maxRow)
+			}
+		}
+		edits = append(edits, partEdit{start: dim.start, end: dim.end, text: []byte(fmt.Sprintf(`<dimension ref="%s"/>`, ref))})
+	}
+
+	// Base merges that overlap a rendered merge are dropped; overlapping merges corrupt the file
+	var merges []string
+	mergeEl := doc.child("mergeCells")
+	if mergeEl != nil && !replace {
+		var mc struct {
+			Merge []struct {
+				Ref string `xml:"ref,attr"`
+			} `xml:"mergeCell"`
+		}
+		if err := xml.Unmarshal(data[mergeEl.start:mergeEl.end], &mc); err != nil {
+			return nil, err
+		}
+		for _, m := range mc.Merge {
+			overlaps := false
+			for _, rendered := range sheet.Merges {
+				if rangesOverlap(m.Ref, rendered.Range) {
+					overlaps = true
+					break
+				}
+			}
+			if !overlaps {
+				merges = append(merges, m.Ref)
+			}
+		}
+	}
+	for _, m := range sheet.Merges {
+		merges = append(merges, m.Range)
+	}
+	var mergeXML []byte
+	if len(merges) > 0 {
+		var b bytes.Buffer
+		fmt.Fprintf(&b, `<mergeCells count="%d">`, len(merges))
+		for _, ref := range merges {
+			fmt.Fprintf(&b, `<mergeCell ref="%s"/>`, escapeAttr(ref))
+		}
+		b.WriteString("</mergeCells>")
+		mergeXML = b.Bytes()
+	}
+	switch {
+	case mergeEl != nil:
+		edits = append(edits, partEdit{start: mergeEl.start, end: mergeEl.end, text: mergeXML})
+	case mergeXML != nil:
+		// <mergeCells> follows these elements in the worksheet schema
+		pos := sheetData.end
+		for _, el := range doc.children {
+			switch el.name.Local {
+			case "sheetCalcPr", "sheetProtection", "protectedRanges", "scenarios", "autoFilter", "sortState", "dataConsolidate", "customSheetViews":
+				if el.end > pos {
+					pos = el.end
+				}
+			}
+		}
+		edits = append(edits, partEdit{start: pos, end: pos, text: mergeXML})
+	}
+	return applyEdits(data, edits), nil
+}
+
+// rangesOverlap reports whether two A1 ranges ("A1:C2" or "B3") share a cell
+func rangesOverlap(a, b string) bool {
+	r1, c1, r2, c2, ok := parseA1Range(a)
+	s1, d1, s2, d2, ok2 := parseA1Range(b)
+	if !ok || !ok2 {
+		return false
+	}
+	return r1 <= s2 && s1 <= r2 && c1 <= d2 && d1 <= c2
+}
+
+// parseA1Range returns the first and last row and column of an A1 range
+func parseA1Range(ref string) (int, int, int, int, bool) {
+	from, to, found := strings.Cut(ref, ":")
+	if !found {
+		to = from
+	}
+	r1, c1, err := parseA1Ref(strings.ReplaceAll(from, "$", ""))
+	if err != nil {
+		return 0, 0, 0, 0, false
+	}
+	r2, c2, err := parseA1Ref(strings.ReplaceAll(to, "$", ""))
+	if err != nil {
+		return 0, 0, 0, 0, false
+	}
+	if r2 < r1 {
+		r1, r2 = r2, r1
+	}
+	if c2 < c1 {
+		c1, c2 = c2, c1
+	}
+	return r1, c1, r2, c2, true
+}
+
+// baseRow is a row of a base worksheet
+type baseRow struct {
+	num     int
+	attrs   []xml.Attr
+	raw     []byte // The row as written in the base (nil for new rows)
+	cells   map[int]baseCell
+	height  float64 // Height set by the rendered sheet (0 keeps the base height)
+	changed bool
+}
+
+// baseCell is a cell of a base worksheet, or a rendered cell
+type baseCell struct {
+	style string // s attribute of a base cell
+	raw   []byte
+}
+
+// startTag returns the opening tag of the row. The spans hint is dropped because the cells
+// may have changed.
+func (row *baseRow) startTag() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `<row r="%d"`, row.num)
+	for _, attr := range row.attrs {
+		name := attr.Name.Local
+		if attr.Name.Space != "" {
+			name = attr.Name.Space + ":" + name
+		}
+		switch name {
+		case "r", "spans":
+			continue
+		case "ht", "customHeight":
+			if row.height > 0 {
+				continue
+			}
+		}
+		fmt.Fprintf(&b, ` %s="%s"`, name, escapeAttr(attr.Value))
+	}
+	if row.height > 0 {
+		fmt.Fprintf(&b, ` ht="%s" customHeight="1"`, strconv.FormatFloat(row.height, 'f', -1, 64))
+	}
+	b.WriteString(">")
+	return b.String()
+}
+
+// scanRows reads the rows and cells inside <sheetData>
+func scanRows(data []byte, sheetData *xmlElement) ([]*baseRow, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data[sheetData.startEnd:sheetData.closeStart]))
+	offset := sheetData.startEnd
+	var (
+		rows    []*baseRow
+		row     *baseRow
+		rowFrom int64
+		cellAt  int64
+		cell    baseCell
+		cellCol int
+		hasRef  bool
+		lastCol int
+		depth   int
+	)
+	for {
+		at := dec.InputOffset()
+		tok, err := dec.RawToken()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			depth++
+			switch {
+			case depth == 1 && t.Name.Local == "row":
+				num := len(rows) + 1
+				if len(rows) > 0 {
+					num = rows[len(rows)-1].num + 1
+				}
+				if v := rawAttr(t.Attr, "r"); v != "" {
+					if num, err = strconv.Atoi(v); err != nil {
+						return nil, fmt.Errorf("invalid row number %q", v)
+					}
+				}
+				row = &baseRow{num: num, attrs: t.Attr, cells: map[int]baseCell{}}
+				rowFrom = at
+				lastCol = 0
+			case depth == 2 && row != nil && t.Name.Local == "c":
+				cellAt = at
+				cellCol = lastCol + 1
+				ref := rawAttr(t.Attr, "r")
+				hasRef = ref != ""
+				if hasRef {
+					if _, cellCol, err = parseA1Ref(ref); err != nil {
+						return nil, err
+					}
+				}
+				cell = baseCell{style: rawAttr(t.Attr, "s")}
+			}
+		case xml.EndElement:
+			switch {
+			case depth == 2 && row != nil && t.Name.Local == "c":
+				end := dec.InputOffset()
+				cell.raw = data[offset+cellAt : offset+end]
+				if !hasRef {
+					// Cells without a reference are given one so that their position survives reordering
+					cell.raw = append([]byte(fmt.Sprintf(`<c r="%s%d"`, columnName(cellCol), row.num)), cell.raw[2:]...)
+				}
+				row.cells[cellCol] = cell
+				lastCol = cellCol
+			case depth == 1 && row != nil && t.Name.Local == "row":
+				row.raw = data[offset+rowFrom : offset+dec.InputOffset()]
+				rows = append(rows, row)
+				row = nil
+			}
+			depth--
+		}
+	}
+}
+
+// rawAttr returns the value of an unprefixed attribute
+func rawAttr(attrs []xml.Attr, name string) string {
+	for _, attr := range attrs {
+		if attr.Name.Space == "" && attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// xmlElement locates an element of a part by byte offsets
+type xmlElement struct {
+	name       xml.Name // As written, with the prefix in Space
+	attrs      []xml.Attr
+	start      int64 // Offset of "<"
+	startEnd   int64 // Offset after the start tag
+	closeStart int64 // Offset of the end tag (startEnd for an empty element)
+	end        int64 // Offset after the element
+}
+
+func (el *xmlElement) attr(name string) string {
+	return rawAttr(el.attrs, name)
+}
+
+// selfClosing reports whether the element was written as <name/>
+func (el *xmlElement) selfClosing() bool {
+	return el.end == el.startEnd
+}
+
+// appendContent returns an edit that adds inner content at the end of the element and
+// sets its count attribute
+func (el *xmlElement) appendContent(data, inner []byte, count int) partEdit {
+	name := el.name.Local
+	if el.name.Space != "" {
+		name = el.name.Space + ":" + name
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "<%s", name)
+	hasCount := false
+	for _, attr := range el.attrs {
+		attrName := attr.Name.Local
+		if attr.Name.Space != "" {
+			attrName = attr.Name.Space + ":" + attrName
+		}
+		value := attr.Value
+		if attrName == "count" {
+			value, hasCount = strconv.Itoa(count), true
+		}
+		fmt.Fprintf(&b, ` %s="%s"`, attrName, escapeAttr(value))
+	}
+	if !hasCount {
+		fmt.Fprintf(&b, ` count="%d"`, count)
+	}
+	b.WriteString(">")
+	if !el.selfClosing() {
+		b.Write(data[el.startEnd:el.closeStart])
+	}
+	b.Write(inner)
+	fmt.Fprintf(&b, "</%s>", name)
+	return partEdit{start: el.start, end: el.end, text: b.Bytes()}
+}
+
+// xmlPart holds the root element of a part and its direct children
+type xmlPart struct {
+	root     *xmlElement
+	children []*xmlElement
+}
+
+// child returns the first child element with the given local name
+func (p *xmlPart) child(local string) *xmlElement {
+	for _, el := range p.children {
+		if el.name.Local == local {
+			return el
+		}
+	}
+	return nil
+}
+
+// scanPart locates the root element of an XML part and its children without interpreting
+// namespaces, so that the part can be edited in place
+func scanPart(data []byte) (*xmlPart, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	part := &xmlPart{}
+	var stack []*xmlElement
+	for {
+		at := dec.InputOffset()
+		tok, err := dec.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			el := &xmlElement{name: t.Name, attrs: t.Attr, start: at, startEnd: dec.InputOffset()}
+			switch len(stack) {
+			case 0:
+				part.root = el
+			case 1:
+				part.children = append(part.children, el)
+			}
+			stack = append(stack, el)
+		case xml.EndElement:
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("unexpected </%s>", t.Name.Local)
+			}
+			el := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			el.end = dec.InputOffset()
+			el.closeStart = at
+			if el.end == el.startEnd {
+				el.closeStart = el.startEnd
+			}
+		}
+	}
+	if part.root == nil {
+		return nil, fmt.Errorf("empty XML part")
+	}
+	return part, nil
+}
+
+// partEdit replaces the bytes between start and end of a part with text
+type partEdit struct {
+	start, end int64
+	text       []byte
+}
+
+// applyEdits applies non-overlapping edits to data
+func applyEdits(data []byte, edits []partEdit) []byte {
+	sort.SliceStable(edits, func(i, j int) bool { return edits[i].start < edits[j].start })
+	var out bytes.Buffer
+	var pos int64
+	for _, e := range edits {
+		out.Write(data[pos:e.start])
+		out.Write(e.text)
+		pos = e.end
+	}
+	out.Write(data[pos:])
+	return out.Bytes()
+}
+
+// escapeAttr escapes a value for use in a double-quoted attribute
+func escapeAttr(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,624 @@
+package parser
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ryo-arima/goxcel/pkg/model"
+)
+
+// maxReadColumns bounds how far a <col> range is expanded into per-column widths. Ranges
+// reaching further usually cover the rest of the sheet.
+const maxReadColumns = 1024
+
+// ReadBookFromFile reads an existing .xlsx file into a Book.
+func ReadBookFromFile(filePath string) (*model.Book, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	return ReadBook(f, st.Size())
+}
+
+// ReadBook reads an .xlsx package of the given size into a Book. Cell values, types and
+// formulas, shared and inline strings, styles (fonts, fills, borders, alignment and number
+// formats), merges, column widths, row heights and sheet views are read; drawings, charts and
+// other parts are not. Theme and indexed colors are not resolved.
+func ReadBook(r io.ReaderAt, size int64) (*model.Book, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("not an xlsx package: %w", err)
+	}
+	pkg := xlsxPackage{files: map[string]*zip.File{}}
+	for _, f := range zr.File {
+		pkg.files[f.Name] = f
+	}
+
+	// Locate the workbook through the package relationships
+	workbookPath := "xl/workbook.xml"
+	var rootRels xlsxRelationships
+	if err := pkg.decode("_rels/.rels", &rootRels); err == nil {
+		if target := rootRels.target("", model.XMLRelTypeOfficeDocument); target != "" {
+			workbookPath = target
+		}
+	}
+	var wb xlsxWorkbook
+	if err := pkg.decode(workbookPath, &wb); err != nil {
+		return nil, fmt.Errorf("read workbook: %w", err)
+	}
+	var wbRels xlsxRelationships
+	relsPath := path.Join(path.Dir(workbookPath), "_rels", path.Base(workbookPath)+".rels")
+	if err := pkg.decode(relsPath, &wbRels); err != nil {
+		return nil, fmt.Errorf("read workbook relationships: %w", err)
+	}
+	wbDir := path.Dir(workbookPath)
+
+	rd := &xlsxBookReader{epoch: time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)}
+	if wb.WorkbookPr.Date1904 {
+		rd.epoch = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	if target := wbRels.target(wbDir, model.XMLRelTypeSharedStrings); target != "" && pkg.has(target) {
+		var sst xlsxSharedStrings
+		if err := pkg.decode(target, &sst); err != nil {
+			return nil, fmt.Errorf("read shared strings: %w", err)
+		}
+		for _, si := range sst.Items {
+			rd.sharedStrings = append(rd.sharedStrings, si.text())
+		}
+	}
+	if target := wbRels.target(wbDir, model.XMLRelTypeStyles); target != "" && pkg.has(target) {
+		var ss xlsxStyleSheet
+		if err := pkg.decode(target, &ss); err != nil {
+			return nil, fmt.Errorf("read styles: %w", err)
+		}
+		rd.loadStyles(ss)
+	}
+
+	book := model.NewBook()
+	for _, ref := range wb.Sheets {
+		target := wbRels.targetByID(wbDir, ref.RID)
+		if target == "" {
+			return nil, fmt.Errorf("sheet %q: no worksheet part for %s", ref.Name, ref.RID)
+		}
+		if rel := wbRels.byID(ref.RID); rel.Type != model.XMLRelTypeWorksheet {
+			// Chart sheets and dialog sheets have no cells
+			continue
+		}
+		var ws xlsxWorksheet
+		if err := pkg.decode(target, &ws); err != nil {
+			return nil, fmt.Errorf("sheet %q: %w", ref.Name, err)
+		}
+		sheet, err := rd.sheet(ref.Name, ws)
+		if err != nil {
+			return nil, fmt.Errorf("sheet %q: %w", ref.Name, err)
+		}
+		book.AddSheet(sheet)
+	}
+	return book, nil
+}
+
+// xlsxPackage gives access to the parts of a zip package by name
+type xlsxPackage struct {
+	files map[string]*zip.File
+}
+
+func (p xlsxPackage) has(name string) bool {
+	_, ok := p.files[name]
+	return ok
+}
+
+// decode unmarshals the XML part name into v
+func (p xlsxPackage) decode(name string, v any) error {
+	f, ok := p.files[name]
+	if !ok {
+		return fmt.Errorf("missing part %s", name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	if err := xml.NewDecoder(rc).Decode(v); err != nil {
+		return fmt.Errorf("parse %s: %w", name, err)
+	}
+	return nil
+}
+
+// xlsxBookReader converts worksheet XML into model sheets using the workbook's shared tables
+type xlsxBookReader struct {
+	epoch         time.Time // Day zero of date serial numbers
+	sharedStrings []string
+	styles        []*model.CellStyle // Cell style per cellXfs index (nil means unformatted)
+	dateStyles    []bool             // Whether the cellXfs index has a date or time format
+}
+
+// loadStyles resolves each cell format of the stylesheet into a model style
+func (rd *xlsxBookReader) loadStyles(ss xlsxStyleSheet) {
+	numFmts := map[int]string{}
+	for id, code := range builtinNumFmts {
+		numFmts[id] = code
+	}
+	for _, nf := range ss.NumFmts {
+		numFmts[nf.ID] = nf.Code
+	}
+	var defaultFont xlsxFont
+	if len(ss.Fonts) > 0 {
+		defaultFont = ss.Fonts[0]
+	}
+
+	for _, xf := range ss.CellXfs {
+		style := &model.CellStyle{}
+		if xf.FontID >= 0 && xf.FontID < len(ss.Fonts) {
+			font := ss.Fonts[xf.FontID]
+			style.Bold = font.B != nil && font.B.on()
+			style.Italic = font.I != nil && font.I.on()
+			style.Underline = font.U != nil && font.U.Val != "none"
+			if font.Name.Val != defaultFont.Name.Val {
+				style.FontName = font.Name.Val
+			}
+			if font.Sz.Val != defaultFont.Sz.Val {
+				if sz, err := strconv.ParseFloat(font.Sz.Val, 64); err == nil {
+					style.FontSize = int(math.Round(sz))
+				}
+			}
+			style.FontColor = rgbColor(font.Color)
+		}
+		if xf.FillID >= 0 && xf.FillID < len(ss.Fills) {
+			if pf := ss.Fills[xf.FillID].PatternFill; pf.PatternType == "solid" {
+				style.FillColor = rgbColor(pf.FgColor)
+			}
+		}
+		if xf.BorderID >= 0 && xf.BorderID < len(ss.Borders) {
+			style.Border = ss.Borders[xf.BorderID].cellBorder()
+		}
+		if xf.Alignment != nil {
+			style.HAlign = xf.Alignment.Horizontal
+			style.VAlign = xf.Alignment.Vertical
+			if style.VAlign == "center" {
+				style.VAlign = "middle"
+			}
+		}
+		code := numFmts[xf.NumFmtID]
+		if code != "General" {
+			style.NumberFormat = code
+		}
+		rd.dateStyles = append(rd.dateStyles, isDateFormat(code))
+
+		if *style == (model.CellStyle{}) {
+			style = nil
+		}
+		rd.styles = append(rd.styles, style)
+	}
+}
+
+// sheet converts a worksheet into a model sheet
+func (rd *xlsxBookReader) sheet(name string, ws xlsxWorksheet) (*model.Sheet, error) {
+	sheet := model.NewSheet(name)
+	conf := sheet.Config
+	if ws.SheetFormatPr != nil {
+		if ws.SheetFormatPr.DefaultRowHeight > 0 {
+			conf.DefaultRowHeight = ws.SheetFormatPr.DefaultRowHeight
+		}
+		if ws.SheetFormatPr.DefaultColWidth > 0 {
+			conf.DefaultColumnWidth = ws.SheetFormatPr.DefaultColWidth
+		}
+	}
+	if len(ws.SheetViews) > 0 {
+		view := ws.SheetViews[0]
+		conf.ShowGridLines = view.ShowGridLines == nil || *view.ShowGridLines
+		conf.ShowRowColHeaders = view.ShowRowColHeaders == nil || *view.ShowRowColHeaders
+		if view.Pane != nil && strings.HasPrefix(view.Pane.State, "frozen") {
+			conf.FreezePane = view.Pane.TopLeftCell
+		}
+	}
+	for _, col := range ws.Cols {
+		if col.Width <= 0 {
+			continue
+		}
+		for c := col.Min; c <= col.Max && c <= maxReadColumns; c++ {
+			conf.ColumnWidths = append(conf.ColumnWidths, model.ColumnWidth{Column: c, Width: col.Width})
+		}
+	}
+
+	lastRow := 0
+	for _, row := range ws.Rows {
+		if row.R == 0 {
+			row.R = lastRow + 1
+		}
+		lastRow = row.R
+		if row.Height > 0 && (row.CustomHeight || row.Height != conf.DefaultRowHeight) {
+			conf.RowHeights = append(conf.RowHeights, model.RowHeight{Row: row.R, Height: row.Height})
+		}
+		lastCol := 0
+		for _, c := range row.Cells {
+			if c.R == "" {
+				c.R = columnName(lastCol+1) + strconv.Itoa(row.R)
+			}
+			_, col, err := parseA1Ref(c.R)
+			if err != nil {
+				return nil, err
+			}
+			lastCol = col
+			cell, err := rd.cell(c)
+			if err != nil {
+				return nil, fmt.Errorf("cell %s: %w", c.R, err)
+			}
+			if cell != nil {
+				sheet.AddCell(cell)
+			}
+		}
+	}
+
+	for _, m := range ws.MergeCells {
+		sheet.AddMerge(model.Merge{Range: m.Ref})
+	}
+	return sheet, nil
+}
+
+// cell converts a worksheet cell. Cells with neither a value nor a style are skipped.
+func (rd *xlsxBookReader) cell(c xlsxCell) (*model.Cell, error) {
+	cell := &model.Cell{Ref: c.R}
+	isDate := false
+	if c.S >= 0 && c.S < len(rd.styles) {
+		cell.Style = rd.styles[c.S]
+		isDate = rd.dateStyles[c.S]
+	}
+
+	// A formula is kept with its leading "=". Cells sharing a formula only carry it in the
+	// first cell; the others keep their cached value.
+	if c.F != nil && c.F.Text != "" {
+		cell.Type = model.CellTypeFormula
+		cell.Value = "=" + c.F.Text
+		return cell, nil
+	}
+
+	switch c.T {
+	case "s":
+		idx, err := strconv.Atoi(strings.TrimSpace(c.V))
+		if err != nil || idx < 0 || idx >= len(rd.sharedStrings) {
+			return nil, fmt.Errorf("invalid shared string index %q", c.V)
+		}
+		cell.Type, cell.Value = model.CellTypeString, rd.sharedStrings[idx]
+	case "inlineStr":
+		cell.Type, cell.Value = model.CellTypeString, c.IS.text()
+	case "str", "e":
+		cell.Type, cell.Value = model.CellTypeString, c.V
+	case "b":
+		cell.Type, cell.Value = model.CellTypeBoolean, strconv.FormatBool(strings.TrimSpace(c.V) == "1")
+	case "d":
+		cell.Type, cell.Value = model.CellTypeDate, c.V
+	default:
+		if c.V == "" {
+			if cell.Style == nil {
+				return nil, nil
+			}
+			cell.Type = model.CellTypeString
+			return cell, nil
+		}
+		cell.Type, cell.Value = model.CellTypeNumber, c.V
+		if isDate {
+			serial, err := strconv.ParseFloat(c.V, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid date serial %q", c.V)
+			}
+			cell.Type, cell.Value = model.CellTypeDate, rd.dateValue(serial)
+		}
+	}
+	return cell, nil
+}
+
+// dateValue formats a date serial number: a whole number as a date, a fraction below one as
+// a time of day, anything else as date and time.
+func (rd *xlsxBookReader) dateValue(serial float64) string {
+	// Round to the second to undo floating point error
+	seconds := int64(math.Round(serial * 86400))
+	t := rd.epoch.Add(time.Duration(seconds) * time.Second)
+	switch {
+	case seconds%86400 == 0:
+		return t.Format("2006-01-02")
+	case serial < 1:
+		return t.Format("15:04:05")
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
+
+// isDateFormat reports whether a number format code shows a date or time. Quoted text,
+// escaped characters and bracketed sections such as colors are ignored; elapsed time
+// sections like [h] count as time.
+func isDateFormat(code string) bool {
+	if code == "" || code == "General" {
+		return false
+	}
+	// Only the first section (positive numbers) decides
+	inQuote := false
+	for i := 0; i < len(code); i++ {
+		ch := code[i]
+		switch {
+		case inQuote:
+			if ch == '"' {
+				inQuote = false
+			}
+		case ch == '"':
+			inQuote = true
+		case ch == '\\' || ch == '_' || ch == '*':
+			i++
+		case ch == ';':
+			return false
+		case ch == '[':
+			end := strings.IndexByte(code[i:], ']')
+			if end < 0 {
+				return false
+			}
+			switch strings.ToLower(code[i+1 : i+end]) {
+			case "h", "hh", "m", "mm", "s", "ss":
+				return true
+			}
+			i += end
+		default:
+			switch ch | 0x20 {
+			case 'y', 'm', 'd', 'h', 's':
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// rgbColor returns the RGB hex of a color without its alpha channel ("" for theme or
+// indexed colors)
+func rgbColor(c *xlsxColor) string {
+	if c == nil || c.RGB == "" {
+		return ""
+	}
+	rgb := strings.ToUpper(c.RGB)
+	if len(rgb) == 8 {
+		rgb = rgb[2:]
+	}
+	return rgb
+}
+
+// columnName converts a 1-based column number to its letters (1 -> A, 27 -> AA)
+func columnName(col int) string {
+	name := ""
+	for col > 0 {
+		col--
+		name = string(rune('A'+col%26)) + name
+		col /= 26
+	}
+	return name
+}
+
+// XML structures for reading. They accept the markup written by Excel and other producers,
+// which the writer structures in pkg/model do not cover.
+
+type xlsxRelationships struct {
+	Relationships []xlsxRelationship `xml:"Relationship"`
+}
+
+type xlsxRelationship struct {
+	ID         string `xml:"Id,attr"`
+	Type       string `xml:"Type,attr"`
+	Target     string `xml:"Target,attr"`
+	TargetMode string `xml:"TargetMode,attr"`
+}
+
+func (rels xlsxRelationships) byID(id string) xlsxRelationship {
+	for _, rel := range rels.Relationships {
+		if rel.ID == id {
+			return rel
+		}
+	}
+	return xlsxRelationship{}
+}
+
+// target returns the part name of the first relationship of the given type
+func (rels xlsxRelationships) target(dir, relType string) string {
+	for _, rel := range rels.Relationships {
+		if rel.Type == relType {
+			return resolvePartName(dir, rel.Target)
+		}
+	}
+	return ""
+}
+
+// targetByID returns the part name of the relationship with the given ID
+func (rels xlsxRelationships) targetByID(dir, id string) string {
+	rel := rels.byID(id)
+	if rel.Target == "" || rel.TargetMode == "External" {
+		return ""
+	}
+	return resolvePartName(dir, rel.Target)
+}
+
+// resolvePartName resolves a relationship target against the directory of its source part
+func resolvePartName(dir, target string) string {
+	if strings.HasPrefix(target, "/") {
+		return strings.TrimPrefix(path.Clean(target), "/")
+	}
+	return strings.TrimPrefix(path.Join(dir, target), "/")
+}
+
+type xlsxWorkbook struct {
+	WorkbookPr struct {
+		Date1904 bool `xml:"date1904,attr"`
+	} `xml:"workbookPr"`
+	Sheets []xlsxSheetRef `xml:"sheets>sheet"`
+}
+
+type xlsxSheetRef struct {
+	Name string `xml:"name,attr"`
+	RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxRichText `xml:"si"`
+}
+
+// xlsxRichText is a string item: plain text in <t> or runs of rich text in <r><t>
+type xlsxRichText struct {
+	T    *string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (rt *xlsxRichText) text() string {
+	if rt == nil {
+		return ""
+	}
+	if rt.T != nil {
+		return *rt.T
+	}
+	var b strings.Builder
+	for _, r := range rt.Runs {
+		b.WriteString(r.T)
+	}
+	return b.String()
+}
+
+type xlsxStyleSheet struct {
+	NumFmts []struct {
+		ID   int    `xml:"numFmtId,attr"`
+		Code string `xml:"formatCode,attr"`
+	} `xml:"numFmts>numFmt"`
+	Fonts   []xlsxFont   `xml:"fonts>font"`
+	Fills   []xlsxFill   `xml:"fills>fill"`
+	Borders []xlsxBorder `xml:"borders>border"`
+	CellXfs []xlsxXf     `xml:"cellXfs>xf"`
+}
+
+type xlsxFont struct {
+	B *xlsxBoolVal `xml:"b"`
+	I *xlsxBoolVal `xml:"i"`
+	U *struct {
+		Val string `xml:"val,attr"`
+	} `xml:"u"`
+	Sz struct {
+		Val string `xml:"val,attr"`
+	} `xml:"sz"`
+	Name struct {
+		Val string `xml:"val,attr"`
+	} `xml:"name"`
+	Color *xlsxColor `xml:"color"`
+}
+
+// xlsxBoolVal is a flag element such as <b/>, which may carry val="0"
+type xlsxBoolVal struct {
+	Val string `xml:"val,attr"`
+}
+
+func (b xlsxBoolVal) on() bool {
+	return b.Val != "0" && b.Val != "false"
+}
+
+type xlsxColor struct {
+	RGB string `xml:"rgb,attr"`
+}
+
+type xlsxFill struct {
+	PatternFill struct {
+		PatternType string     `xml:"patternType,attr"`
+		FgColor     *xlsxColor `xml:"fgColor"`
+	} `xml:"patternFill"`
+}
+
+type xlsxBorder struct {
+	Left   xlsxBorderSide `xml:"left"`
+	Right  xlsxBorderSide `xml:"right"`
+	Top    xlsxBorderSide `xml:"top"`
+	Bottom xlsxBorderSide `xml:"bottom"`
+}
+
+type xlsxBorderSide struct {
+	Style string     `xml:"style,attr"`
+	Color *xlsxColor `xml:"color"`
+}
+
+// cellBorder converts a border to the model, which has one style and color for all sides.
+// The first side with a style (top, right, bottom, left) supplies them.
+func (b xlsxBorder) cellBorder() *model.CellBorder {
+	sides := []xlsxBorderSide{b.Top, b.Right, b.Bottom, b.Left}
+	border := &model.CellBorder{}
+	for _, side := range sides {
+		if side.Style != "" && side.Style != "none" && border.Style == "" {
+			border.Style = side.Style
+			border.Color = rgbColor(side.Color)
+		}
+	}
+	if border.Style == "" {
+		return nil
+	}
+	on := func(side xlsxBorderSide) bool { return side.Style != "" && side.Style != "none" }
+	border.Top, border.Right, border.Bottom, border.Left = on(b.Top), on(b.Right), on(b.Bottom), on(b.Left)
+	return border
+}
+
+type xlsxXf struct {
+	NumFmtID  int `xml:"numFmtId,attr"`
+	FontID    int `xml:"fontId,attr"`
+	FillID    int `xml:"fillId,attr"`
+	BorderID  int `xml:"borderId,attr"`
+	Alignment *struct {
+		Horizontal string `xml:"horizontal,attr"`
+		Vertical   string `xml:"vertical,attr"`
+	} `xml:"alignment"`
+}
+
+type xlsxWorksheet struct {
+	SheetViews    []xlsxSheetView `xml:"sheetViews>sheetView"`
+	SheetFormatPr *struct {
+		DefaultRowHeight float64 `xml:"defaultRowHeight,attr"`
+		DefaultColWidth  float64 `xml:"defaultColWidth,attr"`
+	} `xml:"sheetFormatPr"`
+	Cols []struct {
+		Min   int     `xml:"min,attr"`
+		Max   int     `xml:"max,attr"`
+		Width float64 `xml:"width,attr"`
+	} `xml:"cols>col"`
+	Rows       []xlsxRow `xml:"sheetData>row"`
+	MergeCells []struct {
+		Ref string `xml:"ref,attr"`
+	} `xml:"mergeCells>mergeCell"`
+}
+
+type xlsxSheetView struct {
+	ShowGridLines     *bool `xml:"showGridLines,attr"`
+	ShowRowColHeaders *bool `xml:"showRowColHeaders,attr"`
+	Pane              *struct {
+		TopLeftCell string `xml:"topLeftCell,attr"`
+		State       string `xml:"state,attr"`
+	} `xml:"pane"`
+}
+
+type xlsxRow struct {
+	R            int        `xml:"r,attr"`
+	Height       float64    `xml:"ht,attr"`
+	CustomHeight bool       `xml:"customHeight,attr"`
+	Cells        []xlsxCell `xml:"c"`
+}
+
+type xlsxCell struct {
+	R  string        `xml:"r,attr"`
+	S  int           `xml:"s,attr"`
+	T  string        `xml:"t,attr"`
+	V  string        `xml:"v"`
+	F  *xlsxFormula  `xml:"f"`
+	IS *xlsxRichText `xml:"is"`
+}
+
+type xlsxFormula struct {
+	Text string `xml:",chardata"`
+}
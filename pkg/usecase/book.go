@@ -3,6 +3,7 @@ package usecase
 import (
 	"context"
 	"errors"
+	"fmt"
 
 	"github.com/ryo-arima/goxcel/pkg/config"
 	"github.com/ryo-arima/goxcel/pkg/model"
@@ -12,17 +13,29 @@ import (
 // BookUsecase handles book-level rendering operations
 type BookUsecase interface {
 	Render(ctx context.Context, gxl *model.GXL, data any) (*model.Book, error)
+	// ResolveExtends merges a template with its base template(s); a template that extends
+	// nothing is returned as is
+	ResolveExtends(gxl *model.GXL) (*model.GXL, error)
 }
 
 // bookUsecase is the default (unexported) implementation of BookUsecase
 type bookUsecase struct {
 	conf   config.BaseConfig
 	logger util.Logger
+	cell   *cellHelper
 }
 
 // NewBookUsecase creates a new BookUsecase with config
 func NewBookUsecase(conf config.BaseConfig) BookUsecase {
-	return &bookUsecase{conf: conf, logger: conf.Logger}
+	return &bookUsecase{conf: conf, logger: conf.Logger, cell: newCellHelper(conf)}
+}
+
+// ResolveExtends merges a template declaring <Book extends> with its base template(s)
+func (rcv *bookUsecase) ResolveExtends(gxl *model.GXL) (*model.GXL, error) {
+	if gxl == nil {
+		return nil, errors.New("book usecase: gxl template is nil")
+	}
+	return rcv.resolveExtends(gxl, newImportContext(rcv.conf))
 }
 
 // Render renders the GXL template into a Book
@@ -31,71 +44,230 @@ func (rcv *bookUsecase) Render(ctx context.Context, gxl *model.GXL, data any) (*
 		return nil, errors.New("book usecase: gxl template is nil")
 	}
 
+	// The sheet name policy is checked up front rather than when the first sheet is added
+	names, err := newSheetNamer(rcv.conf.SheetNamePolicy)
+	if err != nil {
+		return nil, err
+	}
+
 	book := model.NewBook()
 
+	// Cancellation, the timeout and the size limits are checked as the template is rendered
+	budget, cancel := newRenderBudget(ctx, rcv.conf.Limits)
+	defer cancel()
+	ctx = budget.ctx
+	if err := budget.check(); err != nil {
+		return nil, err
+	}
+
 	// Normalize data to map[string]any for consistent access
 	normalizedData := rcv.normalizeData(data)
 
 	// Initialize import context for circular detection
-	importCtx := &importContext{
-		visitedFiles: make(map[string]bool),
-		importDepth:  0,
-		baseDir:      rcv.conf.BaseDir,
+	importCtx := newImportContext(rcv.conf)
+
+	// Merge the template with its base template(s) when it extends one
+	gxl, err = rcv.resolveExtends(gxl, importCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	// The base workbook resolves like an import, so the sandbox applies to it
+	if gxl.BookTag.Base != "" {
+		if book.Base, err = importCtx.resolve(gxl.BookTag.Base); err != nil {
+			return nil, fmt.Errorf("base workbook: %w", err)
+		}
+	}
+
+	// Check the data against the declared parameters and fill in defaults
+	if len(gxl.HeaderTag.Params) > 0 {
+		if normalizedData, err = applyParams(gxl.HeaderTag.Params, normalizedData); err != nil {
+			return nil, err
+		}
+	}
+
+	// Sheet names are validated (and optionally de-duplicated) as sheets are added
+	state := &bookState{
+		book:       book,
+		names:      names,
+		importCtx:  importCtx,
+		components: newComponentRegistry(),
+		budget:     budget,
+	}
+	if rcv.conf.Strict {
+		state.unresolved = newUnresolvedPaths()
+	}
+	ctxStack := []map[string]any{normalizedData}
+
+	// Register components before rendering so that any sheet can use them
+	if err := loadComponents(state.components, importCtx, gxl, "template", rcv.logger); err != nil {
+		return nil, err
 	}
 
 	// Process book nodes in definition order
 	if len(gxl.BookNodes) > 0 {
 		// Use BookNodes if available (preserves definition order)
-		for _, node := range gxl.BookNodes {
-			switch node.Type {
-			case model.BookNodeTypeImport:
-				if node.Import != nil {
-					importedSheets, err := rcv.resolveAndRenderImports(ctx, *node.Import, normalizedData, importCtx)
-					if err != nil {
-						return nil, err
-					}
-					for _, importedSheet := range importedSheets {
-						book.AddSheet(importedSheet)
-					}
-				}
-			case model.BookNodeTypeSheet:
-				if node.Sheet != nil {
-					renderer := newSheetRenderer(rcv.conf)
-					sheet, err := renderer.RenderSheet(ctx, node.Sheet, normalizedData)
-					if err != nil {
-						return nil, err
-					}
-					book.AddSheet(sheet)
-				}
-			}
+		if err := rcv.renderBookNodes(ctx, state, ctxStack, gxl.BookNodes); err != nil {
+			return nil, err
 		}
 	} else {
 		// Fallback to old behavior (imports first, then sheets)
 		// Process imports at book level (creates new sheets)
 		for _, importTag := range gxl.Imports {
-			importedSheets, err := rcv.resolveAndRenderImports(ctx, importTag, normalizedData, importCtx)
+			importedSheets, err := rcv.resolveAndRenderImports(ctx, importTag, ctxStack, state)
 			if err != nil {
 				return nil, err
 			}
 			for _, importedSheet := range importedSheets {
-				book.AddSheet(importedSheet)
+				if err := rcv.addSheet(state, importedSheet); err != nil {
+					return nil, err
+				}
 			}
 		}
 
 		// Render each sheet defined in the main file
 		for _, sheetTag := range gxl.Sheets {
-			renderer := newSheetRenderer(rcv.conf)
+			if err := budget.check(); err != nil {
+				return nil, err
+			}
+			renderer := rcv.sheetRendererFor(state)
 			sheet, err := renderer.RenderSheet(ctx, &sheetTag, normalizedData)
 			if err != nil {
 				return nil, err
 			}
-			book.AddSheet(sheet)
+			if err := rcv.addSheet(state, sheet); err != nil {
+				return nil, err
+			}
 		}
 	}
 
+	if state.unresolved != nil && len(state.unresolved.paths) > 0 {
+		return nil, &UnresolvedError{Paths: state.unresolved.paths}
+	}
 	return book, nil
 }
 
+// bookState holds the workbook being built while book-level nodes are rendered
+type bookState struct {
+	book       *model.Book
+	names      *sheetNamer
+	importCtx  *importContext
+	components *componentRegistry
+	unresolved *unresolvedPaths // Expressions that did not resolve (nil unless rendering strictly)
+	budget     *renderBudget    // Cancellation and resource limits
+}
+
+// sheetRendererFor creates a sheet renderer sharing the book's import context and components
+func (rcv *bookUsecase) sheetRendererFor(state *bookState) *sheetRenderer {
+	renderer := newSheetRenderer(rcv.conf)
+	renderer.importCtx = state.importCtx
+	renderer.components = state.components
+	renderer.cell.unresolved = state.unresolved
+	renderer.budget = state.budget
+	return renderer
+}
+
+// renderBookNodes renders book-level nodes (Import, Sheet, For, If) in definition order
+func (rcv *bookUsecase) renderBookNodes(ctx context.Context, state *bookState, ctxStack []map[string]any, nodes []model.BookNode) error {
+	for _, node := range nodes {
+		if err := state.budget.check(); err != nil {
+			return err
+		}
+		switch node.Type {
+		case model.BookNodeTypeImport:
+			if node.Import != nil {
+				importedSheets, err := rcv.resolveAndRenderImports(ctx, *node.Import, ctxStack, state)
+				if err != nil {
+					return err
+				}
+				for _, importedSheet := range importedSheets {
+					if err := rcv.addSheet(state, importedSheet); err != nil {
+						return err
+					}
+				}
+			}
+		case model.BookNodeTypeSheet:
+			if node.Sheet != nil {
+				renderer := rcv.sheetRendererFor(state)
+				sheet, err := renderer.RenderSheetWithStack(ctx, node.Sheet, ctxStack)
+				if err != nil {
+					return err
+				}
+				if err := rcv.addSheet(state, sheet); err != nil {
+					return err
+				}
+			}
+		case model.BookNodeTypeFor:
+			if node.For != nil {
+				if err := rcv.renderBookFor(ctx, state, ctxStack, *node.For); err != nil {
+					return err
+				}
+			}
+		case model.BookNodeTypeIf:
+			if node.If != nil {
+				branch := node.If.Else
+				if rcv.cell.EvaluateCondition(ctxStack, node.If.Cond) {
+					branch = node.If.Then
+				}
+				if err := rcv.renderBookNodes(ctx, state, ctxStack, branch); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// renderBookFor renders the body of a book-level <For> once per item with the loop variable in scope
+func (rcv *bookUsecase) renderBookFor(ctx context.Context, state *bookState, ctxStack []map[string]any, tag model.BookForTag) error {
+	rcv.logger.DEBUG(util.UBR1, fmt.Sprintf("Processing book-level for loop: %s", tag.Each), nil)
+
+	renderer := newSheetRenderer(rcv.conf)
+	varName, dataPath, err := renderer.parseForSyntax(tag.Each)
+	if err != nil {
+		return err
+	}
+
+	var items []any
+	switch arr := rcv.cell.ResolvePath(ctxStack, dataPath).(type) {
+	case []any:
+		items = arr
+	case []map[string]any:
+		for _, item := range arr {
+			items = append(items, item)
+		}
+	default:
+		// Not an iterable type, skip
+		return nil
+	}
+
+	for i, item := range items {
+		if err := state.budget.iterate(); err != nil {
+			return err
+		}
+		scope := renderer.createLoopScope(varName, item, i)
+		newStack := append(ctxStack, scope)
+		if err := rcv.renderBookNodes(ctx, state, newStack, tag.Body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addSheet assigns a valid, unique name to the sheet and appends it to the book
+func (rcv *bookUsecase) addSheet(state *bookState, sheet *model.Sheet) error {
+	name, err := state.names.Assign(sheet.Name)
+	if err != nil {
+		return fmt.Errorf("sheet %d: %w", len(state.book.Sheets)+1, err)
+	}
+	if name != sheet.Name {
+		rcv.logger.WARN(util.UBW1, fmt.Sprintf("Renamed sheet %q to %q", sheet.Name, name))
+		sheet.Name = name
+	}
+	state.book.AddSheet(sheet)
+	return nil
+}
+
 // normalizeData converts any data type to map[string]any
 func (rcv *bookUsecase) normalizeData(data any) map[string]any {
 	if m, ok := data.(map[string]any); ok {
@@ -104,37 +276,19 @@ func (rcv *bookUsecase) normalizeData(data any) map[string]any {
 	return map[string]any{"data": data}
 }
 
-// resolveAndRenderImports loads an external .gxl file and renders the specified sheet
-func (rcv *bookUsecase) resolveAndRenderImports(ctx context.Context, importTag model.ImportTag, data map[string]any, importCtx *importContext) ([]*model.Sheet, error) {
-	// Check import depth limit
-	if importCtx.importDepth >= maxImportDepth {
-		return nil, errors.New("import depth limit exceeded (max 10)")
+// resolveAndRenderImports loads an external .gxl file and renders the specified sheet (or all sheets for "*")
+func (rcv *bookUsecase) resolveAndRenderImports(ctx context.Context, importTag model.ImportTag, ctxStack []map[string]any, state *bookState) ([]*model.Sheet, error) {
+	// Component-only imports are loaded before rendering and create no sheets
+	if importTag.Sheet == "" {
+		return nil, nil
 	}
 
-	// Resolve file path
-	filePath := importTag.Src
-	if !isAbsolutePath(importTag.Src) && importCtx.baseDir != "" {
-		filePath = joinPath(importCtx.baseDir, importTag.Src)
-	}
-
-	// Normalize path for circular detection
-	normalizedPath, err := normalizePath(filePath)
+	// Resolve the path, check depth and circular imports, and mark the file as visited
+	normalizedPath, leave, err := state.importCtx.enter(importTag.Src)
 	if err != nil {
 		return nil, err
 	}
-
-	// Check for circular import
-	if importCtx.visitedFiles[normalizedPath] {
-		return nil, errors.New("circular import detected: " + normalizedPath)
-	}
-
-	// Mark as visited
-	importCtx.visitedFiles[normalizedPath] = true
-	importCtx.importDepth++
-	defer func() {
-		delete(importCtx.visitedFiles, normalizedPath)
-		importCtx.importDepth--
-	}()
+	defer leave()
 
 	// Load and parse the imported .gxl file
 	rcv.logger.DEBUG(util.UBR1, "Loading imported file for sheet creation", map[string]interface{}{
@@ -142,30 +296,75 @@ func (rcv *bookUsecase) resolveAndRenderImports(ctx context.Context, importTag m
 		"sheet": importTag.Sheet,
 	})
 
-	importedGxl, err := readGxlFile(normalizedPath, rcv.logger)
+	importedGxl, err := state.importCtx.readGxl(normalizedPath, rcv.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	// The imported sheets can use the components defined in their own file
+	components, err := scopeComponents(state.components, state.importCtx, &importedGxl, normalizedPath, rcv.logger)
 	if err != nil {
 		return nil, err
 	}
 
-	// Find the specified sheet
-	var targetSheetTag *model.SheetTag
+	// Select the sheets to render
+	var targetSheetTags []*model.SheetTag
+	importAll := importTag.Sheet == model.ImportAllSheets
 	for i := range importedGxl.Sheets {
-		if importedGxl.Sheets[i].Name == importTag.Sheet {
-			targetSheetTag = &importedGxl.Sheets[i]
+		if !importAll && importedGxl.Sheets[i].Name != importTag.Sheet {
+			continue
+		}
+		targetSheetTags = append(targetSheetTags, &importedGxl.Sheets[i])
+		if !importAll {
 			break
 		}
 	}
 
-	if targetSheetTag == nil {
+	if len(targetSheetTags) == 0 {
+		if importAll {
+			return nil, errors.New("no sheets found in " + normalizedPath)
+		}
 		return nil, errors.New("sheet \"" + importTag.Sheet + "\" not found in " + normalizedPath)
 	}
 
-	// Render the imported sheet
-	renderer := newSheetRenderer(rcv.conf)
-	sheet, err := renderer.RenderSheet(ctx, targetSheetTag, data)
-	if err != nil {
-		return nil, err
+	// With a data path the imported sheets see only that object, not the importing data
+	sheetStack := ctxStack
+	if importTag.Data != "" {
+		scope, err := rcv.resolveImportData(ctxStack, importTag.Data)
+		if err != nil {
+			return nil, err
+		}
+		sheetStack = []map[string]any{scope}
+	}
+
+	// Render the imported sheets
+	var sheets []*model.Sheet
+	for _, targetSheetTag := range targetSheetTags {
+		renderer := rcv.sheetRendererFor(state)
+		renderer.components = components
+		sheet, err := renderer.RenderSheetWithStack(ctx, targetSheetTag, sheetStack)
+		if err != nil {
+			return nil, err
+		}
+		if importTag.As != "" {
+			// The new name belongs to the importing template and uses its data
+			sheet.Name = renderer.cell.ExpandMustache(ctxStack, importTag.As)
+		}
+		sheets = append(sheets, sheet)
 	}
 
-	return []*model.Sheet{sheet}, nil
+	return sheets, nil
+}
+
+// resolveImportData resolves the data path of an <Import data="..."> into a scope map
+func (rcv *bookUsecase) resolveImportData(ctxStack []map[string]any, dataPath string) (map[string]any, error) {
+	value := rcv.cell.ResolvePath(ctxStack, dataPath)
+	switch v := value.(type) {
+	case map[string]any:
+		return v, nil
+	case nil:
+		return nil, fmt.Errorf("import data %q not found", dataPath)
+	default:
+		return nil, fmt.Errorf("import data %q must resolve to an object, got %T", dataPath, value)
+	}
 }
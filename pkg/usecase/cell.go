@@ -3,6 +3,7 @@ package usecase
 import (
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -21,6 +22,35 @@ type cellHelper struct {
 	dateRe     *regexp.Regexp
 	boldRe     *regexp.Regexp
 	italicRe   *regexp.Regexp
+	unresolved *unresolvedPaths // Records expressions that do not resolve (nil when not strict)
+}
+
+// UnresolvedError reports the {{ expressions }} that did not resolve during a strict render
+type UnresolvedError struct {
+	Paths []string // Expression paths in the order they were first seen
+}
+
+func (e *UnresolvedError) Error() string {
+	return "unresolved expressions: " + strings.Join(e.Paths, ", ")
+}
+
+// unresolvedPaths collects distinct unresolved expression paths
+type unresolvedPaths struct {
+	paths []string
+	seen  map[string]bool
+}
+
+// newUnresolvedPaths creates an empty collector
+func newUnresolvedPaths() *unresolvedPaths {
+	return &unresolvedPaths{seen: make(map[string]bool)}
+}
+
+// add records path once
+func (u *unresolvedPaths) add(path string) {
+	if !u.seen[path] {
+		u.seen[path] = true
+		u.paths = append(u.paths, path)
+	}
 }
 
 // newCellHelper creates a new internal cell helper with config
@@ -67,6 +97,9 @@ func (rcv *cellHelper) ExpandMustacheWithType(ctxStack []map[string]any, templat
 
 		// Resolve value from context
 		value := rcv.ResolvePath(ctxStack, cleanPath)
+		if value == nil && rcv.unresolved != nil {
+			rcv.unresolved.add(cleanPath)
+		}
 
 		// Convert to string
 		return rcv.valueToString(value)
@@ -282,12 +315,26 @@ func (rcv *cellHelper) valueToString(value any) string {
 			return "true"
 		}
 		return "false"
+	case time.Time:
+		return rcv.timeToString(v)
 	default:
 		// For other types, use Sprint
 		return fmt.Sprint(v)
 	}
 }
 
+// timeToString formats a decoded timestamp. Plain dates stay dates; timestamps with a time of
+// day are shown in the configured time zone.
+func (rcv *cellHelper) timeToString(t time.Time) string {
+	if t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 && t.Nanosecond() == 0 && t.Location() == time.UTC {
+		return t.Format("2006-01-02")
+	}
+	if rcv.conf.Location != nil {
+		t = t.In(rcv.conf.Location)
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
+
 // ParseMarkdownStyle parses markdown-style formatting and returns clean text with style
 // Supports: **bold**, _italic_
 func (rcv *cellHelper) ParseMarkdownStyle(text string) (string, *model.CellStyle) {
@@ -315,3 +362,207 @@ func (rcv *cellHelper) ParseMarkdownStyle(text string) (string, *model.CellStyle
 
 	return cleanText, style
 }
+
+// comparisonOperators lists supported comparison operators (two-character operators first)
+var comparisonOperators = []string{"==", "!=", ">=", "<=", ">", "<"}
+
+// EvaluateCondition evaluates an <If cond="..."> expression against the context stack.
+// Supports path truthiness, negation (!path), comparisons (==, !=, >, >=, <, <=)
+// and combining terms with && and || (&& binds tighter; no parentheses).
+func (rcv *cellHelper) EvaluateCondition(ctxStack []map[string]any, cond string) bool {
+	cond = strings.TrimSpace(cond)
+	if cond == "" {
+		return false
+	}
+	for _, orTerm := range splitOutsideQuotes(cond, "||") {
+		matched := true
+		for _, andTerm := range splitOutsideQuotes(orTerm, "&&") {
+			if !rcv.evaluateTerm(ctxStack, strings.TrimSpace(andTerm)) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// evaluateTerm evaluates a single comparison or truthiness check
+func (rcv *cellHelper) evaluateTerm(ctxStack []map[string]any, term string) bool {
+	for _, op := range comparisonOperators {
+		parts := splitOutsideQuotes(term, op)
+		if len(parts) != 2 {
+			continue
+		}
+		left := rcv.resolveOperand(ctxStack, strings.TrimSpace(parts[0]))
+		right := rcv.resolveOperand(ctxStack, strings.TrimSpace(parts[1]))
+		return compareValues(left, right, op)
+	}
+	if strings.HasPrefix(term, "!") {
+		return !rcv.evaluateTerm(ctxStack, strings.TrimSpace(term[1:]))
+	}
+	return isTruthy(rcv.resolveOperand(ctxStack, term))
+}
+
+// resolveOperand resolves a condition operand as a typed literal or a context path
+func (rcv *cellHelper) resolveOperand(ctxStack []map[string]any, operand string) any {
+	switch {
+	case rcv.isStringLiteral(operand) && len(operand) >= 2:
+		return operand[1 : len(operand)-1]
+	case operand == "true":
+		return true
+	case operand == "false":
+		return false
+	case operand == "null" || operand == "nil":
+		return nil
+	}
+	if f, err := strconv.ParseFloat(operand, 64); err == nil {
+		return f
+	}
+	return rcv.resolveFromContext(ctxStack, operand)
+}
+
+// compareValues compares two operands numerically when both are numbers, otherwise as strings
+func compareValues(left, right any, op string) bool {
+	lf, lok := toFloat(left)
+	rf, rok := toFloat(right)
+	if lok && rok {
+		switch op {
+		case "==":
+			return lf == rf
+		case "!=":
+			return lf != rf
+		case ">":
+			return lf > rf
+		case ">=":
+			return lf >= rf
+		case "<":
+			return lf < rf
+		case "<=":
+			return lf <= rf
+		}
+		return false
+	}
+	if lb, ok := left.(bool); ok {
+		if rb, ok := right.(bool); ok {
+			switch op {
+			case "==":
+				return lb == rb
+			case "!=":
+				return lb != rb
+			}
+			return false
+		}
+	}
+	if left == nil || right == nil {
+		switch op {
+		case "==":
+			return left == nil && right == nil
+		case "!=":
+			return !(left == nil && right == nil)
+		}
+		return false
+	}
+	ls, rs := fmt.Sprint(left), fmt.Sprint(right)
+	switch op {
+	case "==":
+		return ls == rs
+	case "!=":
+		return ls != rs
+	case ">":
+		return ls > rs
+	case ">=":
+		return ls >= rs
+	case "<":
+		return ls < rs
+	case "<=":
+		return ls <= rs
+	}
+	return false
+}
+
+// toFloat converts numeric values (and numeric strings) to float64
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	case string:
+		if f, err := strconv.ParseFloat(strings.TrimSpace(n), 64); err == nil {
+			return f, true
+		}
+	}
+	return 0, false
+}
+
+// isTruthy reports whether a value counts as true in a condition
+func isTruthy(v any) bool {
+	switch t := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return t
+	case string:
+		return t != ""
+	case []any:
+		return len(t) > 0
+	case []map[string]any:
+		return len(t) > 0
+	case map[string]any:
+		return t != nil
+	}
+	if f, ok := toFloat(v); ok {
+		return f != 0
+	}
+	return true
+}
+
+// splitOutsideQuotes splits s on sep, ignoring separators inside single or double quotes
+func splitOutsideQuotes(s, sep string) []string {
+	var parts []string
+	var quote byte
+	start := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case strings.HasPrefix(s[i:], sep):
+			// Do not split "!=" on "=" or ">=" on ">" etc.
+			if len(sep) == 1 && i+1 < len(s) && s[i+1] == '=' {
+				continue
+			}
+			parts = append(parts, s[start:i])
+			i += len(sep) - 1
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
@@ -0,0 +1,188 @@
+package usecase
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ryo-arima/goxcel/pkg/model"
+	"github.com/ryo-arima/goxcel/pkg/util"
+)
+
+// componentRegistry holds the <Component> definitions available to a template. The
+// components of an imported or included file are registered in a child registry, so they
+// are visible to that file only.
+type componentRegistry struct {
+	defs    map[string]model.ComponentTag
+	origins map[string]string  // where each component was defined, for duplicate errors
+	parent  *componentRegistry // Registry of the importing template (nil at the top)
+}
+
+// newComponentRegistry creates an empty component registry
+func newComponentRegistry() *componentRegistry {
+	return &componentRegistry{
+		defs:    make(map[string]model.ComponentTag),
+		origins: make(map[string]string),
+	}
+}
+
+// add registers a component definition; names must be unique within a registry
+func (rcv *componentRegistry) add(def model.ComponentTag, origin string) error {
+	if prev, ok := rcv.origins[def.Name]; ok {
+		return fmt.Errorf("component %q defined in %s is already defined in %s", def.Name, origin, prev)
+	}
+	rcv.defs[def.Name] = def
+	rcv.origins[def.Name] = origin
+	return nil
+}
+
+// get returns the component definition with the given name, looking in the importing
+// templates' registries when it is not defined here
+func (rcv *componentRegistry) get(name string) (model.ComponentTag, bool) {
+	for r := rcv; r != nil; r = r.parent {
+		if def, ok := r.defs[name]; ok {
+			return def, true
+		}
+	}
+	return model.ComponentTag{}, false
+}
+
+// scopeComponents returns the registry for the sheets or fragments of an imported or included
+// file: a child of parent holding the file's own components, or parent when it defines none
+func scopeComponents(parent *componentRegistry, importCtx *importContext, gxl *model.GXL, origin string, logger util.Logger) (*componentRegistry, error) {
+	if len(gxl.Components) == 0 && !hasComponentImports(gxl) {
+		return parent, nil
+	}
+	scoped := newComponentRegistry()
+	scoped.parent = parent
+	if err := loadComponents(scoped, importCtx, gxl, origin, logger); err != nil {
+		return nil, err
+	}
+	return scoped, nil
+}
+
+// hasComponentImports reports whether a template has any <Import components="...">
+func hasComponentImports(gxl *model.GXL) bool {
+	for _, importTag := range bookImports(gxl) {
+		if importTag.Components != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// loadComponents registers the inline components of gxl and the components loaded by its
+// <Import components="..."> tags (including those inside book-level <For> and <If>),
+// following imports recursively
+func loadComponents(registry *componentRegistry, importCtx *importContext, gxl *model.GXL, origin string, logger util.Logger) error {
+	for _, def := range gxl.Components {
+		if err := registry.add(def, origin); err != nil {
+			return err
+		}
+	}
+
+	for _, importTag := range bookImports(gxl) {
+		if importTag.Components == "" {
+			continue
+		}
+		if err := loadImportedComponents(registry, importCtx, importTag, logger); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadImportedComponents loads the components selected by an <Import components="..."> tag
+func loadImportedComponents(registry *componentRegistry, importCtx *importContext, importTag model.ImportTag, logger util.Logger) error {
+	normalizedPath, leave, err := importCtx.enter(importTag.Src)
+	if err != nil {
+		return err
+	}
+	defer leave()
+
+	logger.DEBUG(util.UBR1, "Loading imported components", map[string]interface{}{
+		"file":       normalizedPath,
+		"components": importTag.Components,
+	})
+
+	importedGxl, err := importCtx.readGxl(normalizedPath, logger)
+	if err != nil {
+		return err
+	}
+
+	// Select the requested components before registering them
+	if importTag.Components != model.ImportAllComponents {
+		available := make(map[string]model.ComponentTag)
+		for _, def := range importedGxl.Components {
+			available[def.Name] = def
+		}
+		var selected []model.ComponentTag
+		for _, name := range parseCommaSeparated(importTag.Components) {
+			def, ok := available[name]
+			if !ok {
+				return fmt.Errorf("component %q not found in %s", name, normalizedPath)
+			}
+			selected = append(selected, def)
+		}
+		importedGxl.Components = selected
+	}
+
+	// A file imported along two paths (two component files importing the same one) defines
+	// its components once
+	var fresh []model.ComponentTag
+	for _, def := range importedGxl.Components {
+		if registry.origins[def.Name] != normalizedPath {
+			fresh = append(fresh, def)
+		}
+	}
+	importedGxl.Components = fresh
+
+	return loadComponents(registry, importCtx, &importedGxl, normalizedPath, logger)
+}
+
+// handleUse renders a component at the current position with its arguments in a new scope
+func (rcv *sheetRenderer) handleUse(state *renderState, ctxStack []map[string]any, tag model.UseTag) error {
+	if rcv.components == nil {
+		return fmt.Errorf("component %q is not defined", tag.Component)
+	}
+	def, ok := rcv.components.get(tag.Component)
+	if !ok {
+		return fmt.Errorf("component %q is not defined", tag.Component)
+	}
+
+	for _, active := range rcv.activeComponents {
+		if active == def.Name {
+			return fmt.Errorf("recursive use of component %q", def.Name)
+		}
+	}
+
+	// Build the parameter scope; missing parameters are empty so outer values do not leak in
+	scope := make(map[string]any, len(def.Params))
+	for _, param := range def.Params {
+		scope[param] = ""
+	}
+	for name, raw := range tag.Args {
+		if _, ok := scope[name]; !ok {
+			return fmt.Errorf("component %q has no parameter %q (params: %s)", def.Name, name, strings.Join(def.Params, ", "))
+		}
+		scope[name] = rcv.evaluateArg(ctxStack, raw)
+	}
+
+	rcv.logger.DEBUG(util.USR1, fmt.Sprintf("Rendering component: %s", def.Name), nil)
+
+	rcv.activeComponents = append(rcv.activeComponents, def.Name)
+	defer func() { rcv.activeComponents = rcv.activeComponents[:len(rcv.activeComponents)-1] }()
+
+	return rcv.renderNodes(state, append(ctxStack, scope), def.Nodes)
+}
+
+// evaluateArg evaluates a <Use> argument. An argument that is a single {{ expr }} keeps the
+// resolved value as is (numbers, objects, arrays); anything else is expanded to a string.
+func (rcv *sheetRenderer) evaluateArg(ctxStack []map[string]any, raw string) any {
+	trimmed := strings.TrimSpace(raw)
+	if m := rcv.cell.mustacheRe.FindStringSubmatch(trimmed); m != nil && m[0] == trimmed {
+		if value := rcv.cell.ResolvePath(ctxStack, strings.TrimSpace(m[1])); value != nil {
+			return value
+		}
+	}
+	return rcv.cell.ExpandMustache(ctxStack, raw)
+}
@@ -0,0 +1,308 @@
+package usecase
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ryo-arima/goxcel/pkg/config"
+	"github.com/ryo-arima/goxcel/pkg/model"
+	gxlrepo "github.com/ryo-arima/goxcel/pkg/repository"
+	"github.com/ryo-arima/goxcel/pkg/util"
+)
+
+// ConvertUsecase turns existing .xlsx workbooks into starting .gxl templates
+type ConvertUsecase interface {
+	// Convert reads an .xlsx file and returns the .gxl template that reproduces it
+	Convert(xlsxPath string) ([]byte, error)
+	// ConvertBook returns the .gxl template that reproduces book
+	ConvertBook(book *model.Book) ([]byte, error)
+}
+
+// convertUsecase is the default implementation of ConvertUsecase
+type convertUsecase struct {
+	conf   config.BaseConfig
+	logger util.Logger
+	cell   *cellHelper
+}
+
+// NewConvertUsecase creates a new convert use case with config.
+func NewConvertUsecase(conf config.BaseConfig) ConvertUsecase {
+	return &convertUsecase{conf: conf, logger: conf.Logger, cell: newCellHelper(conf)}
+}
+
+// Convert reads the workbook and converts it
+func (rcv *convertUsecase) Convert(xlsxPath string) ([]byte, error) {
+	book, err := gxlrepo.NewXlsxRepository(rcv.conf).ReadBook(xlsxPath)
+	if err != nil {
+		return nil, err
+	}
+	return rcv.ConvertBook(book)
+}
+
+// ConvertBook writes one <Sheet> per sheet of the book. Cells become <Grid> blocks, one per
+// rectangle of cells with the same style, placed with ref so that the blocks do not depend on
+// each other; the style becomes attributes of the grid.
+func (rcv *convertUsecase) ConvertBook(book *model.Book) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	buf.WriteString("<Book>\n")
+	for i, sheet := range book.Sheets {
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+		if err := rcv.writeSheet(&buf, sheet); err != nil {
+			return nil, fmt.Errorf("sheet %q: %w", sheet.Name, err)
+		}
+	}
+	buf.WriteString("</Book>\n")
+	return buf.Bytes(), nil
+}
+
+// gridBlock is a rectangle of cells that share a style
+type gridBlock struct {
+	row, col int // Top left cell
+	width    int
+	style    *model.CellStyle
+	rows     [][]string // Cell text as written in the grid
+}
+
+func (rcv *convertUsecase) writeSheet(buf *bytes.Buffer, sheet *model.Sheet) error {
+	conf := sheet.Config
+	if conf == nil {
+		conf = model.NewSheet(sheet.Name).Config
+	}
+	buf.WriteString(`  <Sheet name="` + gxlEscaper.Replace(sheet.Name) + `"`)
+	defaults := model.NewSheet("").Config
+	if conf.DefaultColumnWidth > 0 && conf.DefaultColumnWidth != defaults.DefaultColumnWidth {
+		buf.WriteString(` col_width="` + formatFloat(conf.DefaultColumnWidth) + `"`)
+	}
+	if conf.DefaultRowHeight > 0 && conf.DefaultRowHeight != defaults.DefaultRowHeight {
+		buf.WriteString(` row_height="` + formatFloat(conf.DefaultRowHeight) + `"`)
+	}
+	buf.WriteString(">\n")
+	if len(conf.RowHeights) > 0 || conf.FreezePane != "" || !conf.ShowGridLines {
+		rcv.logger.WARN(util.UCVW1, fmt.Sprintf("sheet %q: row heights, frozen panes and hidden grid lines are not converted", sheet.Name), nil)
+	}
+
+	for _, cw := range conf.ColumnWidths {
+		fmt.Fprintf(buf, "    <Column index=\"%d\" width=\"%s\" />\n", cw.Column, formatFloat(cw.Width))
+	}
+
+	blocks, err := rcv.gridBlocks(sheet)
+	if err != nil {
+		return err
+	}
+	for _, block := range blocks {
+		writeGridBlock(buf, block)
+	}
+	for _, merge := range sheet.Merges {
+		buf.WriteString(`    <Merge range="` + gxlEscaper.Replace(merge.Range) + `" />` + "\n")
+	}
+	buf.WriteString("  </Sheet>\n")
+	return nil
+}
+
+// gridBlocks groups the cells of a sheet into blocks: runs of adjacent cells with the same
+// style in a row, stacked with the runs below that span the same columns
+func (rcv *convertUsecase) gridBlocks(sheet *model.Sheet) ([]*gridBlock, error) {
+	type position struct{ row, col int }
+	cells := map[position]*model.Cell{}
+	var positions []position
+	for _, cell := range sheet.Cells {
+		row, col, err := parseA1Ref(cell.Ref)
+		if err != nil {
+			return nil, fmt.Errorf("cell %q: %w", cell.Ref, err)
+		}
+		p := position{row, col}
+		if _, dup := cells[p]; !dup {
+			positions = append(positions, p)
+		}
+		cells[p] = cell
+	}
+	sort.Slice(positions, func(i, j int) bool {
+		if positions[i].row != positions[j].row {
+			return positions[i].row < positions[j].row
+		}
+		return positions[i].col < positions[j].col
+	})
+
+	var blocks []*gridBlock
+	open := map[string]*gridBlock{} // Blocks that may continue on the next row, by first column, width and style
+	for i := 0; i < len(positions); {
+		// Collect a run of adjacent cells with the same style
+		start := positions[i]
+		first := cells[start]
+		key := styleKey(first.Style)
+		var texts []string
+		j := i
+		for ; j < len(positions); j++ {
+			p := positions[j]
+			cell := cells[p]
+			if p.row != start.row || p.col != start.col+len(texts) || styleKey(cell.Style) != key {
+				break
+			}
+			text, err := rcv.gridText(sheet.Name, cell)
+			if err != nil {
+				return nil, err
+			}
+			texts = append(texts, text)
+		}
+		i = j
+
+		runKey := fmt.Sprintf("%d|%d|%s", start.col, len(texts), key)
+		if block, ok := open[runKey]; ok && block.row+len(block.rows) == start.row {
+			block.rows = append(block.rows, texts)
+			continue
+		}
+		block := &gridBlock{row: start.row, col: start.col, width: len(texts), style: first.Style, rows: [][]string{texts}}
+		blocks = append(blocks, block)
+		open[runKey] = block
+	}
+	return blocks, nil
+}
+
+// gridText returns the text of a cell as written in a grid. Text that no grid cell reads back
+// unchanged is an error.
+func (rcv *convertUsecase) gridText(sheetName string, cell *model.Cell) (string, error) {
+	value := cell.Value
+	if strings.ContainsAny(value, "\r\n") {
+		rcv.logger.WARN(util.UCVW1, fmt.Sprintf("sheet %q, cell %s: line breaks are replaced with spaces", sheetName, cell.Ref), nil)
+		value = strings.Join(strings.Fields(strings.NewReplacer("\r\n", "\n", "\r", "\n").Replace(value)), " ")
+	}
+	if cell.Type == model.CellTypeNumber {
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			value = strconv.FormatFloat(f, 'f', -1, 64)
+		}
+	}
+	if strings.Contains(value, "|") || rcv.cell.boldRe.MatchString(value) || rcv.cell.italicRe.MatchString(value) {
+		return "", fmt.Errorf("sheet %q, cell %s: %q contains a pipe or markdown markers, which a grid reads as syntax", sheetName, cell.Ref, value)
+	}
+
+	// Text that would be read as another type, loses surrounding spaces or looks like an
+	// expression is written as a quoted literal with a type hint. An expression cannot hold
+	// "}", so text with "{{" and "}" instead writes each "{{" as an expression of its own.
+	text := value
+	hint, ok := typeHints[cell.Type]
+	if !ok {
+		hint = typeHints[model.CellTypeString]
+	}
+	padded := value != strings.TrimSpace(value)
+	if padded || strings.Contains(value, "{{") || (ok && rcv.cell.InferCellType(value) != cell.Type) {
+		switch {
+		case !strings.Contains(value, "}"):
+			text = `{{ "` + value + `":` + hint + ` }}`
+		case !padded && strings.Contains(value, "{{") && cell.Type == model.CellTypeString:
+			text = strings.ReplaceAll(value, "{{", `{{ "{{" }}`)
+		default:
+			return "", fmt.Errorf("sheet %q, cell %s: %q cannot be written in a grid", sheetName, cell.Ref, value)
+		}
+	}
+	return gxlTextEscaper.Replace(text), nil
+}
+
+// typeHints maps cell types to the type hint that keeps them
+var typeHints = map[model.CellType]string{
+	model.CellTypeString:  "string",
+	model.CellTypeNumber:  "number",
+	model.CellTypeBoolean: "bool",
+	model.CellTypeDate:    "date",
+}
+
+// writeGridBlock writes a block as a <Grid> with aligned pipes
+func writeGridBlock(buf *bytes.Buffer, block *gridBlock) {
+	buf.WriteString(`    <Grid ref="` + toA1Ref(block.row, block.col) + `"`)
+	for _, attr := range styleAttrs(block.style) {
+		buf.WriteString(" " + attr[0] + `="` + gxlEscaper.Replace(attr[1]) + `"`)
+	}
+	buf.WriteString(">\n")
+
+	widths := make([]int, block.width)
+	for _, row := range block.rows {
+		for c, text := range row {
+			if n := len([]rune(text)); n > widths[c] {
+				widths[c] = n
+			}
+		}
+	}
+	for _, row := range block.rows {
+		buf.WriteString("      |")
+		for c, text := range row {
+			buf.WriteString(" " + text + strings.Repeat(" ", widths[c]-len([]rune(text))) + " |")
+		}
+		buf.WriteString("\n")
+	}
+	buf.WriteString("    </Grid>\n")
+}
+
+// styleAttrs returns the <Grid> attributes that reproduce a style, in a fixed order
+func styleAttrs(style *model.CellStyle) [][2]string {
+	if style == nil {
+		return nil
+	}
+	var attrs [][2]string
+	add := func(name, value string) {
+		if value != "" {
+			attrs = append(attrs, [2]string{name, value})
+		}
+	}
+	add("font", style.FontName)
+	if style.FontSize > 0 {
+		add("font_size", strconv.Itoa(style.FontSize))
+	}
+	if style.FontColor != "" {
+		add("font_color", "#"+style.FontColor)
+	}
+	if style.FillColor != "" {
+		add("fill_color", "#"+style.FillColor)
+	}
+	if style.Bold {
+		add("bold", "true")
+	}
+	if style.Italic {
+		add("italic", "true")
+	}
+	if style.Underline {
+		add("underline", "true")
+	}
+	add("h_align", style.HAlign)
+	add("v_align", style.VAlign)
+	add("number_format", style.NumberFormat)
+	if b := style.Border; b != nil && b.Style != "" {
+		add("border", b.Style)
+		if b.Color != "" {
+			add("border_color", "#"+b.Color)
+		}
+		if !(b.Top && b.Right && b.Bottom && b.Left) {
+			var sides []string
+			for _, side := range []struct {
+				on   bool
+				name string
+			}{{b.Top, "top"}, {b.Right, "right"}, {b.Bottom, "bottom"}, {b.Left, "left"}} {
+				if side.on {
+					sides = append(sides, side.name)
+				}
+			}
+			add("border_sides", strings.Join(sides, ","))
+		}
+	}
+	return attrs
+}
+
+// styleKey identifies a style by the grid attributes that reproduce it
+func styleKey(style *model.CellStyle) string {
+	return fmt.Sprint(styleAttrs(style))
+}
+
+// gxlEscaper escapes .gxl attribute values and gxlTextEscaper element content
+var (
+	gxlEscaper     = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+	gxlTextEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+)
+
+// formatFloat formats a size without trailing zeros
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
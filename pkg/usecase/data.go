@@ -0,0 +1,73 @@
+package usecase
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ryo-arima/goxcel/pkg/model"
+	"gopkg.in/yaml.v3"
+)
+
+// MergeData deep-merges src into dst and returns the result. Objects are merged key by key;
+// any other value in src (scalars and arrays) replaces the value in dst. Neither input is modified.
+func MergeData(dst, src map[string]any) map[string]any {
+	out := make(map[string]any, len(dst)+len(src))
+	for k, v := range dst {
+		out[k] = v
+	}
+	for k, v := range src {
+		srcMap, srcIsMap := v.(map[string]any)
+		dstMap, dstIsMap := out[k].(map[string]any)
+		if srcIsMap && dstIsMap {
+			out[k] = MergeData(dstMap, srcMap)
+		} else {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// SetDataValue returns a copy of data with value stored at the dotted path, creating (or
+// replacing non-object) intermediate values as objects
+func SetDataValue(data map[string]any, path string, value any) (map[string]any, error) {
+	parts := strings.Split(path, ".")
+	for _, part := range parts {
+		if part == "" {
+			return nil, fmt.Errorf("invalid data path %q", path)
+		}
+	}
+	return setParam(data, parts, value), nil
+}
+
+// ParseSetExpression parses a "path=value" override. Unless raw is set, the value is read as a
+// YAML scalar or flow collection, so "3" becomes a number, "true" a boolean and "[a, b]" a list.
+func ParseSetExpression(expr string, raw bool) (string, any, error) {
+	path, text, ok := strings.Cut(expr, "=")
+	path = strings.TrimSpace(path)
+	if !ok || path == "" {
+		return "", nil, fmt.Errorf("invalid override %q (expected path=value)", expr)
+	}
+	if raw || text == "" {
+		return path, text, nil
+	}
+
+	var value any
+	if err := yaml.Unmarshal([]byte(text), &value); err != nil {
+		return "", nil, fmt.Errorf("invalid override %q: %w", expr, err)
+	}
+	return path, value, nil
+}
+
+// ParseSetExpressionForParams parses a "path=value" override like ParseSetExpression, but keeps
+// the value as text when the template declares the path as a string or date parameter, so
+// "--set code=007" stays "007" instead of becoming the number 7
+func ParseSetExpressionForParams(expr string, params []model.ParamTag) (string, any, error) {
+	path, _, _ := strings.Cut(expr, "=")
+	path = strings.TrimSpace(path)
+	for _, param := range params {
+		if param.Name == path && (param.Type == model.ParamTypeString || param.Type == model.ParamTypeDate) {
+			return ParseSetExpression(expr, true)
+		}
+	}
+	return ParseSetExpression(expr, false)
+}
@@ -0,0 +1,157 @@
+package usecase
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/ryo-arima/goxcel/pkg/config"
+	"github.com/ryo-arima/goxcel/pkg/model"
+	gxlrepo "github.com/ryo-arima/goxcel/pkg/repository"
+)
+
+// JSONSchemaDialect is the $schema of the documents produced by DescribeUsecase.JSONSchema
+const JSONSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// DescribeUsecase reports the data contract of a .gxl template.
+type DescribeUsecase interface {
+	// Describe returns the template's declared parameters, including those of its base templates.
+	Describe(templatePath string) (*TemplateDescription, error)
+	// JSONSchema converts a description to a JSON Schema (2020-12) document.
+	JSONSchema(desc *TemplateDescription) map[string]any
+}
+
+// TemplateDescription is the parameter contract of a template
+type TemplateDescription struct {
+	Name   string
+	Params []model.ParamTag
+}
+
+// describeUsecase is the default implementation of DescribeUsecase
+type describeUsecase struct {
+	conf config.BaseConfig
+}
+
+// NewDescribeUsecase creates a new describe use case with config.
+func NewDescribeUsecase(conf config.BaseConfig) DescribeUsecase {
+	return &describeUsecase{conf: conf}
+}
+
+// Describe parses the template and collects its parameters
+func (rcv *describeUsecase) Describe(templatePath string) (*TemplateDescription, error) {
+	conf := rcv.conf
+	if templatePath != "" {
+		conf.FilePath = templatePath
+		conf.BaseDir = extractBaseDir(templatePath)
+		if conf.FS != nil {
+			conf.BaseDir = path.Dir(templatePath)
+		}
+	}
+	if conf.FilePath == "" {
+		return nil, fmt.Errorf("template path is required")
+	}
+
+	gxl, err := gxlrepo.NewGxlRepository(conf).ReadGxl()
+	if err != nil {
+		return nil, err
+	}
+
+	// Parameters of base templates are part of the contract
+	book := &bookUsecase{conf: conf, logger: conf.Logger, cell: newCellHelper(conf)}
+	merged, err := book.resolveExtends(&gxl, newImportContext(conf))
+	if err != nil {
+		return nil, err
+	}
+
+	return &TemplateDescription{Name: merged.BookTag.Name, Params: merged.HeaderTag.Params}, nil
+}
+
+// JSONSchema builds a JSON Schema object; dotted parameter names become nested objects
+func (rcv *describeUsecase) JSONSchema(desc *TemplateDescription) map[string]any {
+	root := map[string]any{
+		"$schema": JSONSchemaDialect,
+		"type":    "object",
+	}
+	if desc.Name != "" {
+		root["title"] = desc.Name
+	}
+
+	for _, param := range desc.Params {
+		parts := strings.Split(param.Name, ".")
+		parent := root
+		for _, part := range parts[:len(parts)-1] {
+			// Intermediate objects are required when anything below them is required
+			if param.Required && param.Default == "" {
+				addRequired(parent, part)
+			}
+			props := schemaProperties(parent)
+			child, ok := props[part].(map[string]any)
+			if !ok {
+				child = map[string]any{"type": "object"}
+				props[part] = child
+			}
+			parent = child
+		}
+
+		name := parts[len(parts)-1]
+		schemaProperties(parent)[name] = paramSchema(param)
+		if param.Required && param.Default == "" {
+			addRequired(parent, name)
+		}
+	}
+	return root
+}
+
+// paramSchema returns the JSON Schema of a single parameter
+func paramSchema(param model.ParamTag) map[string]any {
+	schema := map[string]any{}
+	switch param.Type {
+	case model.ParamTypeDate:
+		schema["type"] = "string"
+		schema["format"] = "date"
+	case "":
+	default:
+		schema["type"] = param.Type
+	}
+	if param.Description != "" {
+		schema["description"] = param.Description
+	}
+	if param.Default != "" {
+		if def, err := convertParamDefault(param); err == nil {
+			schema["default"] = def
+		}
+	}
+	if len(param.Enum) > 0 {
+		var enum []any
+		for _, value := range param.Enum {
+			p := param
+			p.Default = value
+			if v, err := convertParamDefault(p); err == nil {
+				enum = append(enum, v)
+			} else {
+				enum = append(enum, value)
+			}
+		}
+		schema["enum"] = enum
+	}
+	return schema
+}
+
+// schemaProperties returns the properties map of an object schema, creating it if needed
+func schemaProperties(schema map[string]any) map[string]any {
+	props, ok := schema["properties"].(map[string]any)
+	if !ok {
+		props = map[string]any{}
+		schema["properties"] = props
+	}
+	return props
+}
+
+// addRequired adds name to the required list of an object schema once
+func addRequired(schema map[string]any, name string) {
+	required, _ := schema["required"].([]string)
+	if containsString(required, name) {
+		return
+	}
+	schema["required"] = append(required, name)
+}
@@ -0,0 +1,426 @@
+package usecase
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ryo-arima/goxcel/pkg/config"
+	"github.com/ryo-arima/goxcel/pkg/model"
+	"github.com/ryo-arima/goxcel/pkg/util"
+)
+
+// DiffUsecase compares two workbooks structurally
+type DiffUsecase interface {
+	// Diff returns the differences that turn workbook a into workbook b
+	Diff(a, b *model.Book) (*BookDiff, error)
+}
+
+// Change operations
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeChanged = "changed"
+)
+
+// Change targets
+const (
+	DiffTargetSheet  = "sheet"
+	DiffTargetCell   = "cell"
+	DiffTargetMerge  = "merge"
+	DiffTargetColumn = "column"
+	DiffTargetRow    = "row"
+)
+
+// BookDiff lists the differences between two workbooks, sheet by sheet in the order of the
+// first workbook (sheets only in the second come last)
+type BookDiff struct {
+	Changes []Change `json:"changes"`
+}
+
+// Equal reports whether the workbooks have no differences
+func (rcv *BookDiff) Equal() bool {
+	return len(rcv.Changes) == 0
+}
+
+// Change is a single difference. A cell that is only in one workbook is one added or removed
+// change with its value (or formula); a cell without a value has no field.
+type Change struct {
+	Op     string `json:"op"`              // added, removed or changed
+	Sheet  string `json:"sheet"`           // Sheet name
+	Target string `json:"target"`          // sheet, cell, merge, column or row
+	Ref    string `json:"ref,omitempty"`   // Cell reference, merge range, column letter or row number
+	Field  string `json:"field,omitempty"` // What changed: value, formula, type, style.<attribute>, width, ...
+	A      string `json:"a,omitempty"`     // Value in the first workbook
+	B      string `json:"b,omitempty"`     // Value in the second workbook
+}
+
+// String formats the change as one line of the text report
+func (rcv Change) String() string {
+	sign := map[string]string{ChangeAdded: "+", ChangeRemoved: "-"}[rcv.Op]
+	if sign == "" {
+		sign = "~"
+	}
+	var where string
+	switch rcv.Target {
+	case DiffTargetSheet:
+		where = quoteSheetName(rcv.Sheet)
+		if rcv.Field == "" {
+			where = fmt.Sprintf("sheet %q", rcv.Sheet)
+		}
+	case DiffTargetCell:
+		where = quoteSheetName(rcv.Sheet) + "!" + rcv.Ref
+	default:
+		where = fmt.Sprintf("%s %s %s", quoteSheetName(rcv.Sheet), rcv.Target, rcv.Ref)
+	}
+	if rcv.Field == "" {
+		return sign + " " + where
+	}
+	switch rcv.Op {
+	case ChangeAdded:
+		return fmt.Sprintf("%s %s %s: %s", sign, where, rcv.Field, diffValue(rcv.B))
+	case ChangeRemoved:
+		return fmt.Sprintf("%s %s %s: %s", sign, where, rcv.Field, diffValue(rcv.A))
+	}
+	return fmt.Sprintf("%s %s %s: %s -> %s", sign, where, rcv.Field, diffValue(rcv.A), diffValue(rcv.B))
+}
+
+// quoteSheetName quotes a sheet name the way formulas do when it is not a plain word
+func quoteSheetName(name string) string {
+	for _, r := range name {
+		if !(r == '_' || r >= '0' && r <= '9' || r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' || r > 0x7f) {
+			return "'" + strings.ReplaceAll(name, "'", "''") + "'"
+		}
+	}
+	return name
+}
+
+// diffValue shows a value in the text report
+func diffValue(v string) string {
+	if v == "" {
+		return "(none)"
+	}
+	return strconv.Quote(v)
+}
+
+// diffUsecase is the default implementation of DiffUsecase
+type diffUsecase struct {
+	conf   config.BaseConfig
+	logger util.Logger
+}
+
+// NewDiffUsecase creates a new diff use case with config.
+func NewDiffUsecase(conf config.BaseConfig) DiffUsecase {
+	return &diffUsecase{conf: conf, logger: conf.Logger}
+}
+
+// Diff compares sheets by name, then the sheet settings, column widths, row heights, cells
+// and merges of every sheet in both workbooks. Only sheets that moved relative to the other
+// sheets in both workbooks report a position change, so adding or removing a sheet does not
+// move the sheets after it.
+func (rcv *diffUsecase) Diff(a, b *model.Book) (*BookDiff, error) {
+	if a == nil || b == nil {
+		return nil, fmt.Errorf("diff: workbook is nil")
+	}
+	diff := &BookDiff{Changes: []Change{}}
+
+	indexB := make(map[string]int, len(b.Sheets))
+	for i, sheet := range b.Sheets {
+		indexB[sheet.Name] = i
+	}
+	moved := movedSheets(a.Sheets, indexB)
+	seen := make(map[string]bool, len(a.Sheets))
+	for i, sheetA := range a.Sheets {
+		seen[sheetA.Name] = true
+		j, ok := indexB[sheetA.Name]
+		if !ok {
+			diff.Changes = append(diff.Changes, Change{Op: ChangeRemoved, Sheet: sheetA.Name, Target: DiffTargetSheet})
+			continue
+		}
+		if moved[sheetA.Name] {
+			diff.Changes = append(diff.Changes, Change{Op: ChangeChanged, Sheet: sheetA.Name, Target: DiffTargetSheet,
+				Field: "position", A: strconv.Itoa(i + 1), B: strconv.Itoa(j + 1)})
+		}
+		changes, err := rcv.diffSheet(sheetA, b.Sheets[j])
+		if err != nil {
+			return nil, fmt.Errorf("sheet %q: %w", sheetA.Name, err)
+		}
+		diff.Changes = append(diff.Changes, changes...)
+	}
+	for _, sheetB := range b.Sheets {
+		if !seen[sheetB.Name] {
+			diff.Changes = append(diff.Changes, Change{Op: ChangeAdded, Sheet: sheetB.Name, Target: DiffTargetSheet})
+		}
+	}
+	return diff, nil
+}
+
+// movedSheets returns the names of the sheets of a that changed their order in b: the shared
+// sheets outside the longest run that keeps its order in both workbooks
+func movedSheets(a []*model.Sheet, indexB map[string]int) map[string]bool {
+	// Positions in b of the shared sheets, in the order of a
+	var names []string
+	var positions []int
+	for _, sheet := range a {
+		if j, ok := indexB[sheet.Name]; ok {
+			names = append(names, sheet.Name)
+			positions = append(positions, j)
+		}
+	}
+
+	// Longest increasing subsequence of the positions
+	length := make([]int, len(positions))
+	prev := make([]int, len(positions))
+	best := -1
+	for i := range positions {
+		length[i], prev[i] = 1, -1
+		for k := 0; k < i; k++ {
+			if positions[k] < positions[i] && length[k]+1 > length[i] {
+				length[i], prev[i] = length[k]+1, k
+			}
+		}
+		if best < 0 || length[i] > length[best] {
+			best = i
+		}
+	}
+	kept := make(map[int]bool, len(positions))
+	for i := best; i >= 0; i = prev[i] {
+		kept[i] = true
+	}
+
+	moved := map[string]bool{}
+	for i, name := range names {
+		if !kept[i] {
+			moved[name] = true
+		}
+	}
+	return moved
+}
+
+// diffSheet compares two sheets with the same name
+func (rcv *diffUsecase) diffSheet(a, b *model.Sheet) ([]Change, error) {
+	var changes []Change
+	add := func(target, ref, field, va, vb string) {
+		if va == vb {
+			return
+		}
+		op := ChangeChanged
+		switch {
+		case va == "" && target != DiffTargetSheet:
+			op = ChangeAdded
+		case vb == "" && target != DiffTargetSheet:
+			op = ChangeRemoved
+		}
+		changes = append(changes, Change{Op: op, Sheet: a.Name, Target: target, Ref: ref, Field: field, A: va, B: vb})
+	}
+
+	// Sheet settings and the used range
+	confA, confB := sheetConfigOf(a), sheetConfigOf(b)
+	add(DiffTargetSheet, "", "default_column_width", formatFloat(confA.DefaultColumnWidth), formatFloat(confB.DefaultColumnWidth))
+	add(DiffTargetSheet, "", "default_row_height", formatFloat(confA.DefaultRowHeight), formatFloat(confB.DefaultRowHeight))
+	add(DiffTargetSheet, "", "freeze_pane", confA.FreezePane, confB.FreezePane)
+	add(DiffTargetSheet, "", "show_grid_lines", strconv.FormatBool(confA.ShowGridLines), strconv.FormatBool(confB.ShowGridLines))
+	add(DiffTargetSheet, "", "show_row_col_headers", strconv.FormatBool(confA.ShowRowColHeaders), strconv.FormatBool(confB.ShowRowColHeaders))
+	cellsA, rangeA, err := diffCells(a)
+	if err != nil {
+		return nil, err
+	}
+	cellsB, rangeB, err := diffCells(b)
+	if err != nil {
+		return nil, err
+	}
+	add(DiffTargetSheet, "", "used_range", rangeA, rangeB)
+
+	// Column widths and row heights
+	widthsA, widthsB := map[int]string{}, map[int]string{}
+	for _, cw := range confA.ColumnWidths {
+		widthsA[cw.Column] = formatFloat(cw.Width)
+	}
+	for _, cw := range confB.ColumnWidths {
+		widthsB[cw.Column] = formatFloat(cw.Width)
+	}
+	for _, col := range unionKeys(widthsA, widthsB) {
+		add(DiffTargetColumn, columnLetters(col), "width", widthsA[col], widthsB[col])
+	}
+	heightsA, heightsB := map[int]string{}, map[int]string{}
+	for _, rh := range confA.RowHeights {
+		heightsA[rh.Row] = formatFloat(rh.Height)
+	}
+	for _, rh := range confB.RowHeights {
+		heightsB[rh.Row] = formatFloat(rh.Height)
+	}
+	for _, row := range unionKeys(heightsA, heightsB) {
+		add(DiffTargetRow, strconv.Itoa(row), "height", heightsA[row], heightsB[row])
+	}
+
+	// Cells in row order
+	positions := make(map[[2]int]bool)
+	for p := range cellsA {
+		positions[p] = true
+	}
+	for p := range cellsB {
+		positions[p] = true
+	}
+	sorted := make([][2]int, 0, len(positions))
+	for p := range positions {
+		sorted = append(sorted, p)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i][0] < sorted[j][0] || sorted[i][0] == sorted[j][0] && sorted[i][1] < sorted[j][1]
+	})
+	for _, p := range sorted {
+		ref := toA1Ref(p[0], p[1])
+		cellA, cellB := cellsA[p], cellsB[p]
+		if cellA == nil || cellB == nil {
+			changes = append(changes, diffOneSidedCell(a.Name, ref, cellA, cellB))
+			continue
+		}
+		for _, field := range diffCellFields(cellA, cellB) {
+			add(DiffTargetCell, ref, field[0], field[1], field[2])
+		}
+	}
+
+	// Merges
+	mergesA, mergesB := map[string]bool{}, map[string]bool{}
+	for _, m := range a.Merges {
+		mergesA[strings.ToUpper(m.Range)] = true
+	}
+	for _, m := range b.Merges {
+		mergesB[strings.ToUpper(m.Range)] = true
+	}
+	for _, m := range a.Merges {
+		if r := strings.ToUpper(m.Range); !mergesB[r] {
+			changes = append(changes, Change{Op: ChangeRemoved, Sheet: a.Name, Target: DiffTargetMerge, Ref: r})
+		}
+	}
+	for _, m := range b.Merges {
+		if r := strings.ToUpper(m.Range); !mergesA[r] {
+			changes = append(changes, Change{Op: ChangeAdded, Sheet: a.Name, Target: DiffTargetMerge, Ref: r})
+		}
+	}
+	return changes, nil
+}
+
+// sheetConfigOf returns the sheet's settings, or the defaults
+func sheetConfigOf(sheet *model.Sheet) *model.SheetConfig {
+	if sheet.Config != nil {
+		return sheet.Config
+	}
+	return model.NewSheet(sheet.Name).Config
+}
+
+// diffCells indexes the cells of a sheet by position and returns the range they span
+func diffCells(sheet *model.Sheet) (map[[2]int]*model.Cell, string, error) {
+	cells := make(map[[2]int]*model.Cell, len(sheet.Cells))
+	minRow, minCol, maxRow, maxCol := 0, 0, 0, 0
+	for _, cell := range sheet.Cells {
+		row, col, err := parseA1Ref(cell.Ref)
+		if err != nil {
+			return nil, "", fmt.Errorf("cell %q: %w", cell.Ref, err)
+		}
+		cells[[2]int{row, col}] = cell
+		if minRow == 0 || row < minRow {
+			minRow = row
+		}
+		if minCol == 0 || col < minCol {
+			minCol = col
+		}
+		if row > maxRow {
+			maxRow = row
+		}
+		if col > maxCol {
+			maxCol = col
+		}
+	}
+	if len(cells) == 0 {
+		return cells, "", nil
+	}
+	return cells, toA1Ref(minRow, minCol) + ":" + toA1Ref(maxRow, maxCol), nil
+}
+
+// diffOneSidedCell returns the change for a cell that is only in one of the workbooks
+func diffOneSidedCell(sheet, ref string, a, b *model.Cell) Change {
+	change := Change{Op: ChangeAdded, Sheet: sheet, Target: DiffTargetCell, Ref: ref}
+	cell := b
+	if a != nil {
+		change.Op, cell = ChangeRemoved, a
+	}
+	if cell.Value != "" {
+		change.Field = "value"
+		if cell.Type == model.CellTypeFormula {
+			change.Field = "formula"
+		}
+	}
+	if a != nil {
+		change.A = cell.Value
+	} else {
+		change.B = cell.Value
+	}
+	return change
+}
+
+// diffCellFields returns the fields that differ between two cells as (field, a, b). Formulas
+// are compared as formulas; a formula replaced by a value shows as a type and value change.
+func diffCellFields(a, b *model.Cell) [][3]string {
+	var fields [][3]string
+	valueA, typeA, styleA := cellFields(a)
+	valueB, typeB, styleB := cellFields(b)
+	if valueA != valueB {
+		field := "value"
+		if typeA == model.CellTypeFormula && typeB == model.CellTypeFormula {
+			field = "formula"
+		}
+		fields = append(fields, [3]string{field, valueA, valueB})
+	}
+	if typeA != typeB {
+		fields = append(fields, [3]string{"type", string(typeA), string(typeB)})
+	}
+	for _, name := range unionKeys(styleA, styleB) {
+		if styleA[name] != styleB[name] {
+			fields = append(fields, [3]string{"style." + name, styleA[name], styleB[name]})
+		}
+	}
+	return fields
+}
+
+// cellFields returns the value, type and style attributes of a cell. A missing or empty cell
+// has no type, so that adding a value reports its type as well.
+func cellFields(cell *model.Cell) (string, model.CellType, map[string]string) {
+	style := map[string]string{}
+	if cell == nil {
+		return "", "", style
+	}
+	for _, attr := range styleAttrs(cell.Style) {
+		style[attr[0]] = attr[1]
+	}
+	typ := cell.Type
+	if cell.Value == "" || typ == model.CellTypeAuto {
+		typ = ""
+		if cell.Value != "" {
+			typ = model.CellTypeString
+		}
+	}
+	return cell.Value, typ, style
+}
+
+// unionKeys returns the keys of both maps in ascending order
+func unionKeys[K int | string](a, b map[K]string) []K {
+	keys := make([]K, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
+
+// columnLetters returns the letters of a column number
+func columnLetters(col int) string {
+	ref := toA1Ref(1, col)
+	return ref[:len(ref)-1]
+}
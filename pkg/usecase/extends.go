@@ -0,0 +1,220 @@
+package usecase
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ryo-arima/goxcel/pkg/model"
+	"github.com/ryo-arima/goxcel/pkg/util"
+)
+
+// resolveExtends merges a template declaring <Book extends="..."> with its base template.
+// Blocks defined at the top level of the child replace the same-named <Block> placeholders
+// of the base, and the child's own sheets follow the base's. The child's parameters and
+// schema take precedence over the base's. Bases may extend other bases;
+// path resolution and cycle detection are shared with <Import>.
+func (rcv *bookUsecase) resolveExtends(gxl *model.GXL, importCtx *importContext) (*model.GXL, error) {
+	if gxl.BookTag.Extends == "" {
+		return gxl, nil
+	}
+
+	normalizedPath, leave, err := importCtx.enter(gxl.BookTag.Extends)
+	if err != nil {
+		return nil, err
+	}
+	defer leave()
+
+	rcv.logger.DEBUG(util.UBR1, "Loading base template", map[string]interface{}{
+		"file": normalizedPath,
+	})
+
+	baseGxl, err := importCtx.readGxl(normalizedPath, rcv.logger)
+	if err != nil {
+		return nil, err
+	}
+	base, err := rcv.resolveExtends(&baseGxl, importCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	overrides := make(map[string]model.BlockTag, len(gxl.Blocks))
+	for _, block := range gxl.Blocks {
+		if _, ok := overrides[block.Name]; ok {
+			return nil, fmt.Errorf("block %q is overridden more than once", block.Name)
+		}
+		overrides[block.Name] = block
+	}
+
+	// Rewrite the base so that its relative paths keep resolving from its own directory
+	m := &blockMerger{
+		importCtx: importCtx,
+		baseDir:   importCtx.dir(normalizedPath),
+		overrides: overrides,
+		used:      make(map[string]bool),
+	}
+	merged := &model.GXL{
+		HeaderTag: gxl.HeaderTag,
+		BookTag:   base.BookTag,
+		Imports:   append(m.imports(base.Imports), gxl.Imports...),
+		Sheets:    append(m.sheets(base.Sheets), gxl.Sheets...),
+		Fragments: append(append([]model.FragmentTag(nil), base.Fragments...), gxl.Fragments...),
+	}
+	for _, component := range base.Components {
+		component.Nodes = m.nodes(component.Nodes)
+		merged.Components = append(merged.Components, component)
+	}
+	merged.Components = append(merged.Components, gxl.Components...)
+	if len(base.BookNodes) > 0 || len(gxl.BookNodes) > 0 {
+		merged.BookNodes = append(m.bookNodes(bookNodesOf(base)), bookNodesOf(gxl)...)
+	}
+	if gxl.BookTag.Name != "" {
+		merged.BookTag.Name = gxl.BookTag.Name
+	}
+	merged.BookTag.Base = m.rebase(base.BookTag.Base)
+	if gxl.BookTag.Base != "" {
+		merged.BookTag.Base = gxl.BookTag.Base
+	}
+	if merged.HeaderTag.Schema == "" {
+		merged.HeaderTag.Schema = m.rebase(base.HeaderTag.Schema)
+	}
+	merged.HeaderTag.Params = mergeParams(base.HeaderTag.Params, gxl.HeaderTag.Params)
+	merged.BookTag.Extends = ""
+
+	// Every override must replace a placeholder of the base
+	var unknown []string
+	for name := range overrides {
+		if !m.used[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("block %s not defined in base template %s", strings.Join(unknown, ", "), normalizedPath)
+	}
+
+	return merged, nil
+}
+
+// mergeParams combines the parameters of a base and a child template; the child's
+// declaration wins when both declare the same name
+func mergeParams(base, child []model.ParamTag) []model.ParamTag {
+	var merged []model.ParamTag
+	for _, param := range base {
+		overridden := false
+		for _, c := range child {
+			if c.Name == param.Name {
+				overridden = true
+				break
+			}
+		}
+		if !overridden {
+			merged = append(merged, param)
+		}
+	}
+	return append(merged, child...)
+}
+
+// bookNodesOf returns the ordered book-level nodes of gxl, building them from the legacy
+// lists (imports first, then sheets) when the template was not produced by the parser
+func bookNodesOf(gxl *model.GXL) []model.BookNode {
+	if len(gxl.BookNodes) > 0 {
+		return gxl.BookNodes
+	}
+	var nodes []model.BookNode
+	for i := range gxl.Imports {
+		nodes = append(nodes, model.BookNode{Type: model.BookNodeTypeImport, Import: &gxl.Imports[i]})
+	}
+	for i := range gxl.Sheets {
+		nodes = append(nodes, model.BookNode{Type: model.BookNodeTypeSheet, Sheet: &gxl.Sheets[i]})
+	}
+	return nodes
+}
+
+// blockMerger copies base template nodes, substituting overridden blocks and rebasing relative paths
+type blockMerger struct {
+	importCtx *importContext
+	baseDir   string
+	overrides map[string]model.BlockTag
+	used      map[string]bool
+}
+
+// rebase resolves a relative path of the base template against the base's directory
+func (rcv *blockMerger) rebase(src string) string {
+	return rcv.importCtx.rebase(rcv.baseDir, src)
+}
+
+func (rcv *blockMerger) imports(tags []model.ImportTag) []model.ImportTag {
+	var out []model.ImportTag
+	for _, tag := range tags {
+		tag.Src = rcv.rebase(tag.Src)
+		out = append(out, tag)
+	}
+	return out
+}
+
+func (rcv *blockMerger) sheets(tags []model.SheetTag) []model.SheetTag {
+	var out []model.SheetTag
+	for _, tag := range tags {
+		tag.Nodes = rcv.nodes(tag.Nodes)
+		out = append(out, tag)
+	}
+	return out
+}
+
+func (rcv *blockMerger) bookNodes(nodes []model.BookNode) []model.BookNode {
+	var out []model.BookNode
+	for _, node := range nodes {
+		switch {
+		case node.Import != nil:
+			tag := rcv.imports([]model.ImportTag{*node.Import})[0]
+			node.Import = &tag
+		case node.Sheet != nil:
+			tag := rcv.sheets([]model.SheetTag{*node.Sheet})[0]
+			node.Sheet = &tag
+		case node.For != nil:
+			tag := *node.For
+			tag.Body = rcv.bookNodes(tag.Body)
+			node.For = &tag
+		case node.If != nil:
+			tag := *node.If
+			tag.Then = rcv.bookNodes(tag.Then)
+			tag.Else = rcv.bookNodes(tag.Else)
+			node.If = &tag
+		}
+		out = append(out, node)
+	}
+	return out
+}
+
+func (rcv *blockMerger) nodes(nodes []any) []any {
+	var out []any
+	for _, node := range nodes {
+		switch v := node.(type) {
+		case model.BlockTag:
+			if override, ok := rcv.overrides[v.Name]; ok {
+				rcv.used[v.Name] = true
+				// Keep the placeholder so that templates extending this one can override it again
+				v.Nodes = override.Nodes
+			} else {
+				v.Nodes = rcv.nodes(v.Nodes)
+			}
+			node = v
+		case model.ForTag:
+			v.Body = rcv.nodes(v.Body)
+			node = v
+		case model.IfTag:
+			v.Then = rcv.nodes(v.Then)
+			v.Else = rcv.nodes(v.Else)
+			node = v
+		case model.IncludeTag:
+			v.Src = rcv.rebase(v.Src)
+			node = v
+		case model.ImageTag:
+			v.Src = rcv.rebase(v.Src)
+			node = v
+		}
+		out = append(out, node)
+	}
+	return out
+}
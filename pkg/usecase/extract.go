@@ -0,0 +1,876 @@
+package usecase
+
+import (
+	"fmt"
+	"path"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ryo-arima/goxcel/pkg/config"
+	"github.com/ryo-arima/goxcel/pkg/model"
+	gxlrepo "github.com/ryo-arima/goxcel/pkg/repository"
+	"github.com/ryo-arima/goxcel/pkg/util"
+)
+
+// ExtractUsecase reads the data back out of a workbook generated from a .gxl template, for
+// example a form that was filled in by hand
+type ExtractUsecase interface {
+	// Extract reads the template and the .xlsx file and returns the data found in the workbook
+	Extract(templatePath, xlsxPath string) (map[string]any, error)
+	// ExtractBook returns the data that the template's {{ expressions }} place in book
+	ExtractBook(gxl *model.GXL, book *model.Book) (map[string]any, error)
+}
+
+// extractUsecase is the default implementation of ExtractUsecase
+type extractUsecase struct {
+	conf   config.BaseConfig
+	logger util.Logger
+	cell   *cellHelper
+}
+
+// NewExtractUsecase creates a new extract use case with config.
+func NewExtractUsecase(conf config.BaseConfig) ExtractUsecase {
+	return &extractUsecase{conf: conf, logger: conf.Logger, cell: newCellHelper(conf)}
+}
+
+// Extract parses the template and reads the workbook
+func (rcv *extractUsecase) Extract(templatePath, xlsxPath string) (map[string]any, error) {
+	conf := rcv.conf
+	if templatePath != "" {
+		conf.FilePath = templatePath
+		conf.BaseDir = extractBaseDir(templatePath)
+		if conf.FS != nil {
+			conf.BaseDir = path.Dir(templatePath)
+		}
+	}
+	if conf.FilePath == "" {
+		return nil, fmt.Errorf("template path is required")
+	}
+
+	gxl, err := gxlrepo.NewGxlRepository(conf).ReadGxl()
+	if err != nil {
+		return nil, err
+	}
+	book, err := gxlrepo.NewXlsxRepository(rcv.conf).ReadBook(xlsxPath)
+	if err != nil {
+		return nil, err
+	}
+	return NewExtractUsecase(conf).ExtractBook(&gxl, book)
+}
+
+// ExtractBook walks the template the way the renderer does, keeping track of the cell each
+// expression was written to, and reads the values of those cells. A loop is repeated while
+// its iterations find values, so a <For> over rows reads rows until the first empty one.
+func (rcv *extractUsecase) ExtractBook(gxl *model.GXL, book *model.Book) (map[string]any, error) {
+	if gxl == nil {
+		return nil, fmt.Errorf("extract: gxl template is nil")
+	}
+
+	importCtx := newImportContext(rcv.conf)
+	books := &bookUsecase{conf: rcv.conf, logger: rcv.logger, cell: rcv.cell}
+	gxl, err := books.resolveExtends(gxl, importCtx)
+	if err != nil {
+		return nil, err
+	}
+	components := newComponentRegistry()
+	if err := loadComponents(components, importCtx, gxl, "template", rcv.logger); err != nil {
+		return nil, err
+	}
+
+	x := &extractor{
+		conf:        rcv.conf,
+		logger:      rcv.logger,
+		cell:        rcv.cell,
+		book:        book,
+		importCtx:   importCtx,
+		components:  components,
+		patterns:    make(map[string]*regexp.Regexp),
+		staticNames: make(map[string]bool),
+	}
+	nodes := bookNodesOf(gxl)
+	x.collectStaticNames(nodes)
+	if err := x.bookNodes(nodes, &extractScope{}); err != nil {
+		return nil, err
+	}
+	for _, warning := range x.warnings {
+		rcv.logger.WARN(util.UEXW1, warning, nil)
+	}
+	return x.data(), nil
+}
+
+// dataPath is a path into the data: object keys (string) and array indexes (int)
+type dataPath []any
+
+// String formats the path as items[0].name
+func (p dataPath) String() string {
+	var sb strings.Builder
+	for _, part := range p {
+		switch v := part.(type) {
+		case int:
+			fmt.Fprintf(&sb, "[%d]", v)
+		default:
+			if sb.Len() > 0 {
+				sb.WriteString(".")
+			}
+			sb.WriteString(fmt.Sprint(v))
+		}
+	}
+	return sb.String()
+}
+
+// join returns a new path with parts appended
+func (p dataPath) join(parts ...any) dataPath {
+	out := make(dataPath, 0, len(p)+len(parts))
+	return append(append(out, p...), parts...)
+}
+
+// extractScope tells what data the names used in expressions stand for, mirroring the
+// context stack of the renderer
+type extractScope struct {
+	names  map[string]dataPath // Loop variables and component parameters; nil paths are not data
+	prefix dataPath            // Data path of an <Include with>/<Import data> scope
+	index  int                 // Iteration of a loop scope (names holds "loop")
+	parent *extractScope
+}
+
+// loopValue returns the value of loop.index or loop.number in the innermost loop
+func (rcv *extractScope) loopValue(expr string) (float64, bool) {
+	for s := rcv; s != nil; s = s.parent {
+		if _, ok := s.names["loop"]; !ok {
+			continue
+		}
+		switch strings.TrimPrefix(expr, ".") {
+		case "loop.index":
+			return float64(s.index), true
+		case "loop.number":
+			return float64(s.index + 1), true
+		}
+		return 0, false
+	}
+	return 0, false
+}
+
+// resolve returns the data path of an expression path, or false for literals and values
+// that do not come from the data (such as loop.index)
+func (rcv *extractScope) resolve(cell *cellHelper, expr string) (dataPath, bool) {
+	if cell.tryResolveLiteral(expr) != nil {
+		return nil, false
+	}
+	var parts dataPath
+	for _, part := range strings.Split(strings.TrimPrefix(expr, "."), ".") {
+		if part == "" {
+			return nil, false
+		}
+		parts = append(parts, part)
+	}
+	for s := rcv; s != nil; s = s.parent {
+		if p, ok := s.names[parts[0].(string)]; ok {
+			if p == nil {
+				return nil, false
+			}
+			return p.join(parts[1:]...), true
+		}
+		if s.prefix != nil {
+			return s.prefix.join(parts...), true
+		}
+	}
+	return parts, true
+}
+
+// extractedValue is a value read from the workbook
+type extractedValue struct {
+	path  dataPath
+	value any
+	where string // Sheet and cell, for warnings
+}
+
+// extractor walks the template over a workbook and collects the values it finds
+type extractor struct {
+	conf             config.BaseConfig
+	logger           util.Logger
+	cell             *cellHelper
+	book             *model.Book
+	importCtx        *importContext
+	components       *componentRegistry
+	activeComponents []string
+	patterns         map[string]*regexp.Regexp // Compiled cell templates
+	staticNames      map[string]bool           // Sheet names without expressions
+	values           []extractedValue
+	used             []*model.Sheet // Workbook sheets matched so far
+	warnings         []string       // Logged once the walk is done, so discarded walks do not warn
+	misses           int            // Cells that do not match their template
+	literals         *literalCheck  // Counts the literal cells found while trying the nodes after a loop
+}
+
+// literalCheck counts the cells without expressions that a walk compared with the workbook
+type literalCheck struct {
+	checked, mismatched int
+}
+
+// extractMark is the progress of the walk at some point
+type extractMark struct {
+	values, used, warnings, misses int
+}
+
+// mark returns the current progress, to roll back a branch or an iteration that does not fit
+func (rcv *extractor) mark() extractMark {
+	return extractMark{len(rcv.values), len(rcv.used), len(rcv.warnings), rcv.misses}
+}
+
+// rollback discards what was found since m
+func (rcv *extractor) rollback(m extractMark) {
+	rcv.values = rcv.values[:m.values]
+	rcv.used = rcv.used[:m.used]
+	rcv.warnings = rcv.warnings[:m.warnings]
+	rcv.misses = m.misses
+}
+
+// fits reports whether something was found since m and every cell matched its template
+func (rcv *extractor) fits(m extractMark) bool {
+	return rcv.misses == m.misses && (len(rcv.values) > m.values || len(rcv.used) > m.used)
+}
+
+// warn records a warning
+func (rcv *extractor) warn(format string, args ...any) {
+	rcv.warnings = append(rcv.warnings, fmt.Sprintf(format, args...))
+}
+
+// collectStaticNames records the sheet names without expressions, so that the sheets of a
+// book-level loop do not take them
+func (rcv *extractor) collectStaticNames(nodes []model.BookNode) {
+	for _, node := range nodes {
+		switch {
+		case node.Sheet != nil && !rcv.cell.mustacheRe.MatchString(node.Sheet.Name):
+			rcv.staticNames[node.Sheet.Name] = true
+		case node.For != nil:
+			rcv.collectStaticNames(node.For.Body)
+		case node.If != nil:
+			rcv.collectStaticNames(node.If.Then)
+			rcv.collectStaticNames(node.If.Else)
+		}
+	}
+}
+
+// bookNodes walks book-level nodes in definition order
+func (rcv *extractor) bookNodes(nodes []model.BookNode, scope *extractScope) error {
+	for _, node := range nodes {
+		switch node.Type {
+		case model.BookNodeTypeImport:
+			if node.Import != nil {
+				if err := rcv.bookImport(*node.Import, scope); err != nil {
+					return err
+				}
+			}
+		case model.BookNodeTypeSheet:
+			if node.Sheet != nil {
+				if err := rcv.sheet(node.Sheet.Name, node.Sheet, scope); err != nil {
+					return err
+				}
+			}
+		case model.BookNodeTypeFor:
+			if node.For != nil {
+				if err := rcv.bookFor(*node.For, scope); err != nil {
+					return err
+				}
+			}
+		case model.BookNodeTypeIf:
+			if node.If != nil {
+				// The condition depends on the data being extracted: take the branch whose
+				// sheets are in the workbook
+				m := rcv.mark()
+				if err := rcv.bookNodes(node.If.Then, scope); err != nil {
+					return err
+				}
+				if !rcv.fits(m) {
+					rcv.rollback(m)
+					if err := rcv.bookNodes(node.If.Else, scope); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// bookFor repeats the body of a book-level loop while its iterations find sheets
+func (rcv *extractor) bookFor(tag model.BookForTag, scope *extractScope) error {
+	varName, items, ok, err := rcv.loopTarget(tag.Each, scope)
+	if err != nil || !ok {
+		return err
+	}
+	for i := 0; ; i++ {
+		m := rcv.mark()
+		used := len(rcv.used)
+		if err := rcv.bookNodes(tag.Body, loopScope(scope, varName, items.join(i), i)); err != nil {
+			return err
+		}
+		if len(rcv.used) == used {
+			rcv.rollback(m)
+			return nil
+		}
+	}
+}
+
+// bookImport walks the sheets of an <Import> at book level
+func (rcv *extractor) bookImport(tag model.ImportTag, scope *extractScope) error {
+	if tag.Sheet == "" {
+		return nil
+	}
+	normalizedPath, leave, err := rcv.importCtx.enter(tag.Src)
+	if err != nil {
+		return err
+	}
+	defer leave()
+	imported, err := rcv.importCtx.readGxl(normalizedPath, rcv.logger)
+	if err != nil {
+		return err
+	}
+	restore, err := rcv.scopeComponents(&imported, normalizedPath)
+	if err != nil {
+		return err
+	}
+	defer restore()
+
+	sheetScope := scope
+	if tag.Data != "" {
+		prefix, ok := scope.resolve(rcv.cell, tag.Data)
+		if !ok {
+			return fmt.Errorf("import data %q must be a data path", tag.Data)
+		}
+		sheetScope = &extractScope{prefix: prefix}
+	}
+	for i := range imported.Sheets {
+		sheetTag := &imported.Sheets[i]
+		if tag.Sheet != model.ImportAllSheets && sheetTag.Name != tag.Sheet {
+			continue
+		}
+		name := sheetTag.Name
+		if tag.As != "" {
+			name = tag.As
+		}
+		if err := rcv.sheet(name, sheetTag, sheetScope); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sheet finds the workbook sheet generated from a sheet tag and walks its nodes. A name with
+// expressions matches the first sheet not taken yet whose name fits it.
+func (rcv *extractor) sheet(name string, tag *model.SheetTag, scope *extractScope) error {
+	var target *model.Sheet
+	for _, sheet := range rcv.book.Sheets {
+		if rcv.isUsed(sheet) {
+			continue
+		}
+		if !rcv.cell.mustacheRe.MatchString(name) {
+			if sheet.Name == name {
+				target = sheet
+				break
+			}
+			continue
+		}
+		if rcv.staticNames[sheet.Name] {
+			continue
+		}
+		m := rcv.mark()
+		if rcv.match(scope, name, sheet.Name, model.CellTypeString, false, fmt.Sprintf("sheet %q", sheet.Name)) {
+			target = sheet
+			break
+		}
+		rcv.rollback(m)
+	}
+	if target == nil {
+		if !rcv.cell.mustacheRe.MatchString(name) {
+			rcv.warn("sheet %q is not in the workbook", name)
+		}
+		return nil
+	}
+	rcv.used = append(rcv.used, target)
+
+	w := &extractSheet{sheet: target, cells: make(map[string]*model.Cell), anchorRow: 1, anchorCol: 1}
+	for _, cell := range target.Cells {
+		w.cells[cell.Ref] = cell
+		if row, _, err := parseA1Ref(cell.Ref); err == nil && row > w.lastRow {
+			w.lastRow = row
+		}
+	}
+	if err := rcv.nodes(w, scope, tag.Nodes); err != nil {
+		return fmt.Errorf("extract sheet %q: %w", target.Name, err)
+	}
+	return nil
+}
+
+// isUsed reports whether a workbook sheet was already matched
+func (rcv *extractor) isUsed(sheet *model.Sheet) bool {
+	for _, used := range rcv.used {
+		if used == sheet {
+			return true
+		}
+	}
+	return false
+}
+
+// extractSheet is the position in a workbook sheet, like renderState
+type extractSheet struct {
+	sheet     *model.Sheet
+	cells     map[string]*model.Cell
+	lastRow   int
+	anchorRow int
+	anchorCol int
+	rowOffset int
+}
+
+// position returns the current position, to save and restore it
+func (rcv *extractSheet) position() [3]int {
+	return [3]int{rcv.anchorRow, rcv.anchorCol, rcv.rowOffset}
+}
+
+// restore moves back to a saved position
+func (rcv *extractSheet) restore(p [3]int) {
+	rcv.anchorRow, rcv.anchorCol, rcv.rowOffset = p[0], p[1], p[2]
+}
+
+// nodes walks sheet nodes in order. A <For> is given the nodes that follow it, so that it
+// stops before them.
+func (rcv *extractor) nodes(w *extractSheet, scope *extractScope, nodes []any) error {
+	for i, node := range nodes {
+		var err error
+		if v, ok := node.(model.ForTag); ok {
+			err = rcv.forLoop(w, scope, v, nodes[i+1:])
+		} else {
+			err = rcv.node(w, scope, node)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// node walks a single node, moving the position the way the renderer does
+func (rcv *extractor) node(w *extractSheet, scope *extractScope, node any) error {
+	switch v := node.(type) {
+	case model.AnchorTag:
+		row, col, err := parseA1Ref(v.Ref)
+		if err != nil {
+			return fmt.Errorf("invalid anchor ref %q: %w", v.Ref, err)
+		}
+		w.anchorRow, w.anchorCol, w.rowOffset = row, col, 0
+	case model.GridTag:
+		if v.Ref == "" {
+			rcv.gridRows(w, scope, v.Rows)
+			return nil
+		}
+		row, col, err := parseA1Ref(v.Ref)
+		if err != nil {
+			return fmt.Errorf("invalid grid ref %q: %w", v.Ref, err)
+		}
+		saved := w.position()
+		w.anchorRow, w.anchorCol, w.rowOffset = row, col, 0
+		rcv.gridRows(w, scope, v.Rows)
+		w.restore(saved)
+	case model.GridRowTag:
+		rcv.gridRows(w, scope, []model.GridRowTag{v})
+	case model.TableTag:
+		return rcv.table(w, scope, v)
+	case model.ForTag:
+		return rcv.forLoop(w, scope, v, nil)
+	case model.IncludeTag:
+		return rcv.include(w, scope, v)
+	case model.UseTag:
+		return rcv.use(w, scope, v)
+	case model.BlockTag:
+		return rcv.nodes(w, scope, v.Nodes)
+	}
+	return nil
+}
+
+// gridRows reads the cells of grid rows
+func (rcv *extractor) gridRows(w *extractSheet, scope *extractScope, rows []model.GridRowTag) {
+	for _, row := range rows {
+		currentRow := w.anchorRow + w.rowOffset
+		for colIndex, text := range row.Cells {
+			rcv.readCell(w, scope, currentRow, w.anchorCol+colIndex, text)
+		}
+		w.rowOffset++
+	}
+}
+
+// table reads the rows of a <Table>; rows and columns with each repeat until an empty one
+func (rcv *extractor) table(w *extractSheet, scope *extractScope, tag model.TableTag) error {
+	for _, row := range tag.Rows {
+		if row.Each == "" {
+			if err := rcv.tableCols(w, scope, row.Cols); err != nil {
+				return err
+			}
+			continue
+		}
+		varName, items, ok, err := rcv.loopTarget(row.Each, scope)
+		if err != nil {
+			return err
+		}
+		for i := 0; ok && w.anchorRow+w.rowOffset <= w.lastRow; i++ {
+			m, saved := rcv.mark(), w.position()
+			if err := rcv.tableCols(w, loopScope(scope, varName, items.join(i), i), row.Cols); err != nil {
+				return err
+			}
+			if !rcv.fits(m) {
+				rcv.rollback(m)
+				w.restore(saved)
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// tableCols reads one table row
+func (rcv *extractor) tableCols(w *extractSheet, scope *extractScope, cols []model.TableColTag) error {
+	currentRow := w.anchorRow + w.rowOffset
+	currentCol := w.anchorCol
+	for _, col := range cols {
+		if col.Each == "" {
+			rcv.readCell(w, scope, currentRow, currentCol, col.Content)
+			currentCol++
+			continue
+		}
+		varName, items, ok, err := rcv.loopTarget(col.Each, scope)
+		if err != nil {
+			return err
+		}
+		for i := 0; ok; i++ {
+			m := rcv.mark()
+			rcv.readCell(w, loopScope(scope, varName, items.join(i), i), currentRow, currentCol, col.Content)
+			if !rcv.fits(m) {
+				rcv.rollback(m)
+				break
+			}
+			currentCol++
+		}
+	}
+	w.rowOffset++
+	return nil
+}
+
+// forLoop repeats the loop body while its iterations find values and match the template.
+// Before each iteration the nodes after the loop are tried at the current position; when the
+// text they write as is (such as a "Total" label) is found there, the loop ends, so that a
+// footer is not read as an item. A body that does not move down (absolute grids, anchors)
+// writes every item to the same cells, so only the last item can be read back, as the first
+// iteration.
+func (rcv *extractor) forLoop(w *extractSheet, scope *extractScope, tag model.ForTag, after []any) error {
+	varName, items, ok, err := rcv.loopTarget(tag.Each, scope)
+	if err != nil || !ok {
+		return err
+	}
+	for i := 0; w.anchorRow+w.rowOffset <= w.lastRow; i++ {
+		if found, err := rcv.literalsFound(w, scope, after); err != nil || found {
+			return err
+		}
+		m, saved := rcv.mark(), w.position()
+		if err := rcv.nodes(w, loopScope(scope, varName, items.join(i), i), tag.Body); err != nil {
+			return err
+		}
+		if !rcv.fits(m) {
+			rcv.rollback(m)
+			w.restore(saved)
+			return nil
+		}
+		if w.position() == saved {
+			return nil
+		}
+	}
+	return nil
+}
+
+// literalsFound walks nodes without keeping what they find and reports whether they have
+// cells without expressions and the workbook holds the same text in all of them
+func (rcv *extractor) literalsFound(w *extractSheet, scope *extractScope, nodes []any) (bool, error) {
+	if len(nodes) == 0 {
+		return false, nil
+	}
+	m, saved, outer := rcv.mark(), w.position(), rcv.literals
+	check := &literalCheck{}
+	rcv.literals = check
+	err := rcv.nodes(w, scope, nodes)
+	rcv.literals = outer
+	rcv.rollback(m)
+	w.restore(saved)
+	return check.checked > 0 && check.mismatched == 0, err
+}
+
+// include walks the nodes of an included fragment
+func (rcv *extractor) include(w *extractSheet, scope *extractScope, tag model.IncludeTag) error {
+	normalizedPath, leave, err := rcv.importCtx.enter(tag.Src)
+	if err != nil {
+		return err
+	}
+	defer leave()
+	included, err := rcv.importCtx.readGxl(normalizedPath, rcv.logger)
+	if err != nil {
+		return err
+	}
+	restore, err := rcv.scopeComponents(&included, normalizedPath)
+	if err != nil {
+		return err
+	}
+	defer restore()
+	for _, fragment := range included.Fragments {
+		if fragment.Name != tag.Fragment {
+			continue
+		}
+		fragmentScope := scope
+		if tag.With != "" {
+			prefix, ok := scope.resolve(rcv.cell, tag.With)
+			if !ok {
+				return fmt.Errorf("include with %q must be a data path", tag.With)
+			}
+			fragmentScope = &extractScope{prefix: prefix, parent: scope}
+		}
+		return rcv.nodes(w, fragmentScope, fragment.Nodes)
+	}
+	return fmt.Errorf("fragment %q not found in %s", tag.Fragment, normalizedPath)
+}
+
+// scopeComponents makes the components of an imported or included file available until the
+// returned function is called
+func (rcv *extractor) scopeComponents(gxl *model.GXL, origin string) (func(), error) {
+	saved := rcv.components
+	components, err := scopeComponents(saved, rcv.importCtx, gxl, origin, rcv.logger)
+	if err != nil {
+		return nil, err
+	}
+	rcv.components = components
+	return func() { rcv.components = saved }, nil
+}
+
+// use walks the nodes of a component. A parameter passed a single {{ path }} stands for that
+// path; other arguments are not data.
+func (rcv *extractor) use(w *extractSheet, scope *extractScope, tag model.UseTag) error {
+	def, ok := rcv.components.get(tag.Component)
+	if !ok {
+		return fmt.Errorf("component %q is not defined", tag.Component)
+	}
+	for _, active := range rcv.activeComponents {
+		if active == def.Name {
+			return fmt.Errorf("recursive use of component %q", def.Name)
+		}
+	}
+
+	names := make(map[string]dataPath, len(def.Params))
+	for _, param := range def.Params {
+		names[param] = nil
+	}
+	for name, raw := range tag.Args {
+		if _, ok := names[name]; !ok {
+			return fmt.Errorf("component %q has no parameter %q (params: %s)", def.Name, name, strings.Join(def.Params, ", "))
+		}
+		trimmed := strings.TrimSpace(raw)
+		if m := rcv.cell.mustacheRe.FindStringSubmatch(trimmed); m != nil && m[0] == trimmed {
+			expr, _ := rcv.cell.ParseTypeHint(strings.TrimSpace(m[1]))
+			if p, ok := scope.resolve(rcv.cell, expr); ok {
+				names[name] = p
+			}
+		}
+	}
+
+	rcv.activeComponents = append(rcv.activeComponents, def.Name)
+	defer func() { rcv.activeComponents = rcv.activeComponents[:len(rcv.activeComponents)-1] }()
+	return rcv.nodes(w, &extractScope{names: names, parent: scope}, def.Nodes)
+}
+
+// loopTarget parses "varName in dataPath" and resolves the data path of the items
+func (rcv *extractor) loopTarget(each string, scope *extractScope) (string, dataPath, bool, error) {
+	parts := strings.Fields(each)
+	if len(parts) != 3 || parts[1] != "in" {
+		return "", nil, false, fmt.Errorf("invalid For syntax: %q (expected: 'varName in dataPath')", each)
+	}
+	items, ok := scope.resolve(rcv.cell, parts[2])
+	return parts[0], items, ok, nil
+}
+
+// loopScope binds the loop variable to an item; loop.index and loop.number are not data
+func loopScope(parent *extractScope, varName string, item dataPath, index int) *extractScope {
+	return &extractScope{names: map[string]dataPath{varName: item, "loop": nil}, index: index, parent: parent}
+}
+
+// readCell reads the values that a cell template placed in a cell. Empty cells hold nothing.
+func (rcv *extractor) readCell(w *extractSheet, scope *extractScope, row, col int, template string) {
+	ref := toA1Ref(row, col)
+	if !rcv.cell.mustacheRe.MatchString(template) {
+		if rcv.literals != nil && template != "" {
+			rcv.literals.checked++
+			if cell := w.cells[ref]; cell == nil || !sameLiteral(stripMarkdown(template), cell.Value) {
+				rcv.literals.mismatched++
+			}
+		}
+		return
+	}
+	cell := w.cells[ref]
+	if cell == nil || cell.Value == "" {
+		return
+	}
+	template = stripMarkdown(template)
+	where := fmt.Sprintf("sheet %q, cell %s", w.sheet.Name, ref)
+	if !rcv.match(scope, template, cell.Value, cell.Type, true, where) {
+		rcv.misses++
+		rcv.warn("%s: %q does not match %q", where, cell.Value, template)
+	}
+}
+
+// stripMarkdown removes the **bold** and _italic_ markers around a cell template, which the
+// renderer turns into cell styles
+func stripMarkdown(template string) string {
+	for {
+		switch {
+		case len(template) > 4 && strings.HasPrefix(template, "**") && strings.HasSuffix(template, "**"):
+			template = template[2 : len(template)-2]
+		case len(template) > 2 && strings.HasPrefix(template, "_") && strings.HasSuffix(template, "_"):
+			template = template[1 : len(template)-1]
+		default:
+			return template
+		}
+	}
+}
+
+// sameLiteral reports whether a cell holds the text of a template without expressions;
+// numbers may be written differently
+func sameLiteral(template, value string) bool {
+	if template == value {
+		return true
+	}
+	a, errA := strconv.ParseFloat(template, 64)
+	b, errB := strconv.ParseFloat(value, 64)
+	return errA == nil && errB == nil && a == b
+}
+
+// match matches text against a template and records the values of its expressions. A
+// template that is a single expression takes the whole text with the cell type; otherwise
+// the literal parts of the template have to match and the values in between are captured.
+func (rcv *extractor) match(scope *extractScope, template, text string, cellType model.CellType, typed bool, where string) bool {
+	locs := rcv.cell.mustacheRe.FindAllStringSubmatchIndex(template, -1)
+	if len(locs) == 1 && locs[0][0] == 0 && locs[0][1] == len(template) {
+		if !typed {
+			cellType = model.CellTypeAuto
+		}
+		return rcv.record(scope, template[locs[0][2]:locs[0][3]], text, cellType, where)
+	}
+
+	re, ok := rcv.patterns[template]
+	if !ok {
+		var sb strings.Builder
+		sb.WriteString(`(?s)^`)
+		last := 0
+		for _, loc := range locs {
+			sb.WriteString(regexp.QuoteMeta(template[last:loc[0]]))
+			sb.WriteString(`(.*?)`)
+			last = loc[1]
+		}
+		sb.WriteString(regexp.QuoteMeta(template[last:]) + `$`)
+		re = regexp.MustCompile(sb.String())
+		rcv.patterns[template] = re
+	}
+	m := re.FindStringSubmatch(text)
+	if m == nil {
+		return false
+	}
+	matched := true
+	for i, loc := range locs {
+		if m[i+1] != "" && !rcv.record(scope, template[loc[2]:loc[3]], m[i+1], model.CellTypeAuto, where) {
+			matched = false
+		}
+	}
+	return matched
+}
+
+// record converts a value read for an expression and records it at the expression's path. It
+// returns false when the expression is the loop counter and the value is not the iteration's.
+func (rcv *extractor) record(scope *extractScope, expr, text string, cellType model.CellType, where string) bool {
+	expr, hint := rcv.cell.ParseTypeHint(strings.TrimSpace(expr))
+	value := rcv.convert(text, hint, cellType)
+	if want, ok := scope.loopValue(expr); ok {
+		return value == want
+	}
+	if p, ok := scope.resolve(rcv.cell, expr); ok {
+		rcv.values = append(rcv.values, extractedValue{path: p, value: value, where: where})
+	}
+	return true
+}
+
+// convert turns cell text into a data value: the type hint wins, then the cell type. Text
+// captured from a longer cell is only read as a number when it is written the way numbers
+// are rendered, so "00123" stays a string.
+func (rcv *extractor) convert(text string, hint, cellType model.CellType) any {
+	typ := hint
+	if typ == model.CellTypeAuto {
+		typ = cellType
+	}
+	inferred := typ == model.CellTypeAuto
+	if inferred {
+		typ = rcv.cell.InferCellType(text)
+	}
+	switch typ {
+	case model.CellTypeNumber:
+		if f, err := strconv.ParseFloat(strings.TrimSpace(text), 64); err == nil && (!inferred || formatFloat(f) == text) {
+			return f
+		}
+	case model.CellTypeBoolean:
+		if b, err := strconv.ParseBool(strings.ToLower(strings.TrimSpace(text))); err == nil {
+			return b
+		}
+	}
+	return text
+}
+
+// data builds the data object from the recorded values. When a path was read twice with
+// different values the first one is kept.
+func (rcv *extractor) data() map[string]any {
+	var root any = map[string]any{}
+	for _, v := range rcv.values {
+		var conflict bool
+		root, conflict = putDataValue(root, v.path, v.value)
+		if conflict {
+			rcv.logger.WARN(util.UEXW1, fmt.Sprintf("%s: %s was already read with another value", v.where, v.path), nil)
+		}
+	}
+	return root.(map[string]any)
+}
+
+// putDataValue stores value at p below node, creating objects and arrays as needed, and
+// reports whether a different value was already there
+func putDataValue(node any, p dataPath, value any) (any, bool) {
+	if len(p) == 0 {
+		if node == nil {
+			return value, false
+		}
+		return node, !reflect.DeepEqual(node, value)
+	}
+	var conflict bool
+	switch key := p[0].(type) {
+	case int:
+		items, ok := node.([]any)
+		if !ok && node != nil {
+			return node, true
+		}
+		for len(items) <= key {
+			items = append(items, nil)
+		}
+		items[key], conflict = putDataValue(items[key], p[1:], value)
+		return items, conflict
+	default:
+		obj, ok := node.(map[string]any)
+		if !ok && node != nil {
+			return node, true
+		}
+		if obj == nil {
+			obj = map[string]any{}
+		}
+		name := key.(string)
+		obj[name], conflict = putDataValue(obj[name], p[1:], value)
+		return obj, conflict
+	}
+}
@@ -1,7 +1,12 @@
 package usecase
 
 import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
 	"path/filepath"
+	"strings"
 
 	"github.com/ryo-arima/goxcel/pkg/config"
 	"github.com/ryo-arima/goxcel/pkg/model"
@@ -41,13 +46,169 @@ func extractBaseDir(filePath string) string {
 	return dir
 }
 
-// readGxlFile reads a GXL file using the repository layer
-func readGxlFile(filePath string, logger util.Logger) (model.GXL, error) {
+// readGxlFile reads a GXL file using the repository layer (from fsys when it is not nil)
+func readGxlFile(fsys fs.FS, filePath string, logger util.Logger) (model.GXL, error) {
 	conf := config.BaseConfig{
 		FilePath: filePath,
 		Logger:   logger,
 		BaseDir:  extractBaseDir(filePath),
+		FS:       fsys,
 	}
 	repo := parser.NewGxlRepository(conf)
 	return repo.ReadGxl()
 }
+
+// newImportContext creates an import context rooted at the configured base directory
+func newImportContext(conf config.BaseConfig) *importContext {
+	baseDir := conf.BaseDir
+	if conf.FS != nil {
+		baseDir = fsBaseDir(baseDir)
+	}
+	return &importContext{
+		visitedFiles: make(map[string]bool),
+		importDepth:  0,
+		maxDepth:     conf.Limits.ImportDepth(),
+		maxFiles:     conf.Limits.MaxImportFiles,
+		baseDir:      baseDir,
+		fsys:         conf.FS,
+		sandbox:      conf.Sandbox,
+		filesSeen:    make(map[string]bool),
+		parsed:       make(map[string]model.GXL),
+	}
+}
+
+// fsBaseDir converts a base directory to a slash-separated path relative to the root of an fs.FS
+func fsBaseDir(dir string) string {
+	dir = path.Clean(strings.TrimPrefix(filepath.ToSlash(dir), "/"))
+	if dir == "" || dir == "/" {
+		return "."
+	}
+	return dir
+}
+
+// resolve returns the cleaned location of src relative to the current base directory.
+// On the OS filesystem the result is absolute and, in sandbox mode, must stay inside the
+// sandbox root. Within an fs.FS it is a path relative to the root; a leading "/" in src
+// refers to the root, and paths may not leave it.
+func (c *importContext) resolve(src string) (string, error) {
+	if c.fsys == nil && c.sandbox != "" {
+		return parser.SandboxPath(c.sandbox, c.baseDir, src)
+	}
+	if c.fsys == nil {
+		filePath := src
+		if !isAbsolutePath(src) && c.baseDir != "" {
+			filePath = joinPath(c.baseDir, src)
+		}
+		return normalizePath(filePath)
+	}
+
+	name := path.Join(c.baseDir, src)
+	if strings.HasPrefix(src, "/") {
+		name = path.Clean(strings.TrimPrefix(src, "/"))
+	}
+	if !fs.ValidPath(name) || name == "." {
+		return "", fmt.Errorf("path %q is outside the template filesystem", src)
+	}
+	return name, nil
+}
+
+// dir returns the directory of a resolved path
+func (c *importContext) dir(name string) string {
+	if c.fsys == nil {
+		return extractBaseDir(name)
+	}
+	return path.Dir(name)
+}
+
+// rebase rewrites a path relative to dir so that it resolves the same way from anywhere
+func (c *importContext) rebase(dir, src string) string {
+	if c.fsys == nil {
+		if src == "" || isAbsolutePath(src) {
+			return src
+		}
+		return joinPath(dir, src)
+	}
+	if src == "" || strings.HasPrefix(src, "/") {
+		return src
+	}
+	return "/" + path.Join(dir, src)
+}
+
+// readGxl reads a template at a path returned by enter. Each file is parsed once; later
+// reads (an <Include> inside a <For>, a file imported along two paths) share the result,
+// so callers must not modify the slices of the returned template.
+func (c *importContext) readGxl(name string, logger util.Logger) (model.GXL, error) {
+	if gxl, ok := c.parsed[name]; ok {
+		return gxl, nil
+	}
+	gxl, err := readGxlFile(c.fsys, name, logger)
+	if err != nil {
+		return model.GXL{}, err
+	}
+	c.parsed[name] = gxl
+	return gxl, nil
+}
+
+// enter resolves src against the current base directory, enforces the depth limit and
+// circular import detection, and marks the file as visited. Relative paths inside the
+// entered file resolve from its own directory until the returned leave function is called.
+func (c *importContext) enter(src string) (string, func(), error) {
+	// Check import depth limit
+	if c.importDepth >= c.maxDepth {
+		return "", nil, &config.LimitError{Limit: config.LimitImportDepth, Max: int64(c.maxDepth)}
+	}
+
+	// Resolve and normalize the path for circular detection
+	normalizedPath, err := c.resolve(src)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// Only files not entered before count towards the file limit
+	if c.maxFiles > 0 && !c.filesSeen[normalizedPath] && len(c.filesSeen) >= c.maxFiles {
+		return "", nil, &config.LimitError{Limit: config.LimitImportFiles, Max: int64(c.maxFiles)}
+	}
+
+	// Check for circular import
+	if c.visitedFiles[normalizedPath] {
+		return "", nil, errors.New("circular import detected: " + normalizedPath)
+	}
+
+	// Mark as visited
+	c.filesSeen[normalizedPath] = true
+	savedBaseDir := c.baseDir
+	c.visitedFiles[normalizedPath] = true
+	c.importDepth++
+	c.baseDir = c.dir(normalizedPath)
+	leave := func() {
+		delete(c.visitedFiles, normalizedPath)
+		c.importDepth--
+		c.baseDir = savedBaseDir
+	}
+	return normalizedPath, leave, nil
+}
+
+// bookImports returns every <Import> of a template, including those inside book-level
+// <For> and <If> bodies
+func bookImports(gxl *model.GXL) []model.ImportTag {
+	if len(gxl.BookNodes) == 0 {
+		return gxl.Imports
+	}
+	var imports []model.ImportTag
+	var walk func(nodes []model.BookNode)
+	walk = func(nodes []model.BookNode) {
+		for _, node := range nodes {
+			switch {
+			case node.Import != nil:
+				imports = append(imports, *node.Import)
+			case node.For != nil:
+				walk(node.For.Body)
+			case node.If != nil:
+				walk(node.If.Then)
+				walk(node.If.Else)
+			}
+		}
+	}
+	walk(gxl.BookNodes)
+	return imports
+}
@@ -0,0 +1,73 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ryo-arima/goxcel/pkg/config"
+)
+
+// renderBudget tracks the work of one render against its limits and its context
+type renderBudget struct {
+	ctx        context.Context // Render context, including the Timeout deadline
+	parent     context.Context // Caller's context, to tell its cancellation from the Timeout
+	limits     config.Limits
+	cells      int
+	iterations int
+}
+
+// newRenderBudget returns the budget for a render and the context to use for it. The
+// returned cancel function releases the Timeout timer.
+func newRenderBudget(ctx context.Context, limits config.Limits) (*renderBudget, context.CancelFunc) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	budget := &renderBudget{ctx: ctx, parent: ctx, limits: limits}
+	cancel := context.CancelFunc(func() {})
+	if limits.Timeout > 0 {
+		budget.ctx, cancel = context.WithTimeout(ctx, limits.Timeout)
+	}
+	return budget, cancel
+}
+
+// check returns an error once the render has been cancelled or has run out of time
+func (b *renderBudget) check() error {
+	if b == nil {
+		return nil
+	}
+	err := b.ctx.Err()
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, context.DeadlineExceeded) && b.parent.Err() == nil {
+		return &config.LimitError{Limit: config.LimitTimeout, Max: int64(b.limits.Timeout)}
+	}
+	return err
+}
+
+// iterate counts one loop iteration
+func (b *renderBudget) iterate() error {
+	if b == nil {
+		return nil
+	}
+	b.iterations++
+	if max := b.limits.MaxLoopIterations; max > 0 && b.iterations > max {
+		return &config.LimitError{Limit: config.LimitLoopIterations, Max: int64(max)}
+	}
+	return b.check()
+}
+
+// addCell counts a cell written at the given row
+func (b *renderBudget) addCell(row int) error {
+	if b == nil {
+		return nil
+	}
+	if max := b.limits.MaxRows; max > 0 && row > max {
+		return &config.LimitError{Limit: config.LimitRows, Max: int64(max)}
+	}
+	b.cells++
+	if max := b.limits.MaxCells; max > 0 && b.cells > max {
+		return &config.LimitError{Limit: config.LimitCells, Max: int64(max)}
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package usecase
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ryo-arima/goxcel/pkg/model"
+)
+
+// ParamError reports every data value that does not satisfy the template's <Params>
+type ParamError struct {
+	Problems []string // One entry per parameter, e.g. `invoice.number: required`
+}
+
+func (e *ParamError) Error() string {
+	return "template data does not match <Params>:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// applyParams checks data against the declared parameters and returns data with defaults
+// filled in. Maps on the path of a default are copied so the caller's data is not modified.
+func applyParams(params []model.ParamTag, data map[string]any) (map[string]any, error) {
+	var problems []string
+	for _, param := range params {
+		parts := strings.Split(param.Name, ".")
+		value, found := lookupParam(data, parts)
+
+		if !found || value == nil {
+			switch {
+			case param.Default != "":
+				def, err := convertParamDefault(param)
+				if err != nil {
+					problems = append(problems, err.Error())
+					continue
+				}
+				data = setParam(data, parts, def)
+			case param.Required:
+				problems = append(problems, fmt.Sprintf("%s: required", param.Name))
+			}
+			continue
+		}
+
+		if !paramTypeMatches(param.Type, value) {
+			problems = append(problems, fmt.Sprintf("%s: expected %s, got %s", param.Name, param.Type, describeValue(value)))
+			continue
+		}
+		if len(param.Enum) > 0 && !containsString(param.Enum, fmt.Sprint(value)) {
+			problems = append(problems, fmt.Sprintf("%s: %q is not one of %s", param.Name, fmt.Sprint(value), strings.Join(param.Enum, ", ")))
+		}
+	}
+
+	if len(problems) > 0 {
+		return nil, &ParamError{Problems: problems}
+	}
+	return data, nil
+}
+
+// lookupParam resolves a dotted parameter path in data
+func lookupParam(data map[string]any, parts []string) (any, bool) {
+	var current any = data
+	for _, part := range parts {
+		m, ok := current.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if current, ok = m[part]; !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// setParam returns a copy of data with value stored at the dotted path
+func setParam(data map[string]any, parts []string, value any) map[string]any {
+	out := make(map[string]any, len(data)+1)
+	for k, v := range data {
+		out[k] = v
+	}
+	if len(parts) == 1 {
+		out[parts[0]] = value
+		return out
+	}
+	child, _ := out[parts[0]].(map[string]any)
+	out[parts[0]] = setParam(child, parts[1:], value)
+	return out
+}
+
+// convertParamDefault converts the textual default of a parameter to its declared type
+func convertParamDefault(param model.ParamTag) (any, error) {
+	var (
+		value any
+		err   error
+	)
+	switch param.Type {
+	case model.ParamTypeNumber:
+		value, err = strconv.ParseFloat(param.Default, 64)
+	case model.ParamTypeInteger:
+		value, err = strconv.Atoi(param.Default)
+	case model.ParamTypeBoolean:
+		value, err = strconv.ParseBool(param.Default)
+	case model.ParamTypeObject, model.ParamTypeArray:
+		err = json.Unmarshal([]byte(param.Default), &value)
+		if err == nil && !paramTypeMatches(param.Type, value) {
+			err = fmt.Errorf("not a JSON %s", param.Type)
+		}
+	default:
+		value = param.Default
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid default %q for type %s", param.Name, param.Default, param.Type)
+	}
+	return value, nil
+}
+
+// paramTypeMatches reports whether value is acceptable for the parameter type
+func paramTypeMatches(paramType string, value any) bool {
+	switch paramType {
+	case "":
+		return true
+	case model.ParamTypeString:
+		_, ok := value.(string)
+		return ok
+	case model.ParamTypeNumber:
+		_, ok := schemaNumber(value)
+		return ok
+	case model.ParamTypeInteger:
+		f, ok := schemaNumber(value)
+		return ok && f == math.Trunc(f)
+	case model.ParamTypeBoolean:
+		_, ok := value.(bool)
+		return ok
+	case model.ParamTypeDate:
+		switch v := value.(type) {
+		case time.Time:
+			return true
+		case string:
+			_, err := time.Parse("2006-01-02", v)
+			return err == nil
+		}
+		return false
+	case model.ParamTypeObject:
+		_, ok := value.(map[string]any)
+		return ok
+	case model.ParamTypeArray:
+		switch value.(type) {
+		case []any, []map[string]any:
+			return true
+		}
+		return false
+	}
+	return false
+}
+
+// describeValue names the kind of a data value for error messages
+func describeValue(value any) string {
+	switch value.(type) {
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case map[string]any:
+		return "object"
+	case []any, []map[string]any:
+		return "array"
+	}
+	if _, ok := schemaNumber(value); ok {
+		return "number"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// containsString reports whether list contains s
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,670 @@
+package usecase
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ryo-arima/goxcel/pkg/config"
+	"github.com/ryo-arima/goxcel/pkg/util"
+)
+
+// SchemaUsecase validates template data against a JSON Schema.
+//
+// The validator implements the assertion keywords of JSON Schema draft 2020-12 that apply to
+// plain data documents: type, enum, const, the numeric, string, array and object keywords,
+// allOf/anyOf/oneOf/not, if/then/else, dependentRequired and local $ref into $defs.
+// The formats date, date-time and email are asserted; other formats are ignored.
+// A schema using any other keyword (unevaluatedProperties, dependentSchemas, $anchor,
+// $dynamicRef, a remote $ref, ...) is rejected rather than partially applied.
+type SchemaUsecase interface {
+	Validate(schema any, data any) error
+}
+
+// SchemaViolation is a single validation failure
+type SchemaViolation struct {
+	Pointer string // JSON pointer to the offending value ("" is the document root)
+	Message string
+}
+
+// SchemaError reports every violation found in a document
+type SchemaError struct {
+	Violations []SchemaViolation
+}
+
+func (e *SchemaError) Error() string {
+	lines := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		pointer := v.Pointer
+		if pointer == "" {
+			pointer = "(root)"
+		}
+		lines = append(lines, pointer+": "+v.Message)
+	}
+	return "data does not match schema:\n  - " + strings.Join(lines, "\n  - ")
+}
+
+// schemaUsecase is the default implementation of SchemaUsecase
+type schemaUsecase struct {
+	conf   config.BaseConfig
+	logger util.Logger
+}
+
+// NewSchemaUsecase creates a new schema use case with config.
+func NewSchemaUsecase(conf config.BaseConfig) SchemaUsecase {
+	return &schemaUsecase{conf: conf, logger: conf.Logger}
+}
+
+// Validate checks data against schema and returns a *SchemaError listing all violations
+func (rcv *schemaUsecase) Validate(schema any, data any) error {
+	if err := checkSchemaKeywords(schema, "#", true); err != nil {
+		return err
+	}
+	v := &schemaValidator{root: schema, patterns: make(map[string]*regexp.Regexp)}
+	v.validate(schema, data, "", 0)
+	if len(v.violations) > 0 {
+		return &SchemaError{Violations: v.violations}
+	}
+	return nil
+}
+
+// schemaAnnotations are keywords that do not affect validation
+var schemaAnnotations = map[string]bool{
+	"$schema": true, "$comment": true, "title": true, "description": true, "default": true,
+	"examples": true, "deprecated": true, "readOnly": true, "writeOnly": true, "format": true,
+	"contentEncoding": true, "contentMediaType": true,
+}
+
+// schemaAssertions are the keywords validated on the instance itself
+var schemaAssertions = map[string]bool{
+	"type": true, "enum": true, "const": true, "$ref": true,
+	"minimum": true, "maximum": true, "exclusiveMinimum": true, "exclusiveMaximum": true, "multipleOf": true,
+	"minLength": true, "maxLength": true, "pattern": true,
+	"minItems": true, "maxItems": true, "uniqueItems": true, "minContains": true, "maxContains": true,
+	"minProperties": true, "maxProperties": true, "required": true, "dependentRequired": true,
+}
+
+// schemaApplicators are the keywords holding a subschema, a map of subschemas
+// ("definitions" is the pre-2019 name of $defs) or a list of subschemas
+var (
+	schemaApplicators = map[string]bool{
+		"items": true, "contains": true, "additionalProperties": true, "propertyNames": true,
+		"not": true, "if": true, "then": true, "else": true,
+	}
+	schemaMapApplicators  = map[string]bool{"properties": true, "patternProperties": true, "$defs": true, "definitions": true}
+	schemaListApplicators = map[string]bool{"prefixItems": true, "allOf": true, "anyOf": true, "oneOf": true}
+)
+
+// checkSchemaKeywords returns an error for the first keyword of schema, or of one of its
+// subschemas, that the validator does not implement; location is the schema's JSON pointer
+func checkSchemaKeywords(schema any, location string, root bool) error {
+	s, ok := schema.(map[string]any)
+	if !ok {
+		return nil
+	}
+	for _, key := range sortedKeys(s) {
+		value := s[key]
+		keyLocation := location + "/" + escapePointer(key)
+		switch {
+		case key == "$id" && root:
+			// The root $id names the schema; nested resources would change how $ref resolves
+		case key == "$ref":
+			if ref, _ := value.(string); ref != "#" && !strings.HasPrefix(ref, "#/") {
+				return fmt.Errorf("schema: $ref %q at %s is not supported (only local references are supported)", ref, location)
+			}
+		case schemaAnnotations[key] || schemaAssertions[key]:
+		case schemaApplicators[key]:
+			if err := checkSchemaKeywords(value, keyLocation, false); err != nil {
+				return err
+			}
+		case schemaMapApplicators[key]:
+			subs, _ := value.(map[string]any)
+			for _, name := range sortedKeys(subs) {
+				if err := checkSchemaKeywords(subs[name], keyLocation+"/"+escapePointer(name), false); err != nil {
+					return err
+				}
+			}
+		case schemaListApplicators[key]:
+			subs, _ := value.([]any)
+			for i, sub := range subs {
+				if err := checkSchemaKeywords(sub, keyLocation+"/"+strconv.Itoa(i), false); err != nil {
+					return err
+				}
+			}
+		default:
+			return fmt.Errorf("schema: keyword %q at %s is not supported", key, location)
+		}
+	}
+	return nil
+}
+
+// maxSchemaDepth bounds $ref recursion
+const maxSchemaDepth = 64
+
+// schemaValidator walks a schema and a document together, collecting violations
+type schemaValidator struct {
+	root       any
+	patterns   map[string]*regexp.Regexp
+	violations []SchemaViolation
+}
+
+func (rcv *schemaValidator) fail(pointer, format string, args ...any) {
+	rcv.violations = append(rcv.violations, SchemaViolation{Pointer: pointer, Message: fmt.Sprintf(format, args...)})
+}
+
+// valid reports whether data matches schema without recording violations
+func (rcv *schemaValidator) valid(schema, data any, pointer string, depth int) bool {
+	saved := rcv.violations
+	rcv.violations = nil
+	rcv.validate(schema, data, pointer, depth)
+	ok := len(rcv.violations) == 0
+	rcv.violations = saved
+	return ok
+}
+
+func (rcv *schemaValidator) validate(schema, data any, pointer string, depth int) {
+	if depth > maxSchemaDepth {
+		rcv.fail(pointer, "schema nesting too deep (circular $ref?)")
+		return
+	}
+
+	switch s := schema.(type) {
+	case bool:
+		if !s {
+			rcv.fail(pointer, "no value is allowed here")
+		}
+		return
+	case map[string]any:
+		rcv.validateObjectSchema(s, data, pointer, depth)
+	}
+}
+
+func (rcv *schemaValidator) validateObjectSchema(s map[string]any, data any, pointer string, depth int) {
+	// YAML decodes unquoted timestamps; validate them as the strings they were written as
+	if t, ok := data.(time.Time); ok {
+		data = formatYAMLTime(t)
+	}
+
+	if ref, ok := s["$ref"].(string); ok {
+		target, err := rcv.resolveRef(ref)
+		if err != nil {
+			rcv.fail(pointer, "%v", err)
+		} else {
+			rcv.validate(target, data, pointer, depth+1)
+		}
+	}
+
+	if t, ok := s["type"]; ok && !typeMatches(t, data) {
+		rcv.fail(pointer, "expected %s, got %s", typeNames(t), jsonTypeOf(data))
+		return
+	}
+	if enum, ok := s["enum"].([]any); ok {
+		found := false
+		for _, e := range enum {
+			if jsonEqual(e, data) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			rcv.fail(pointer, "value %s is not one of %s", jsonText(data), jsonText(enum))
+		}
+	}
+	if c, ok := s["const"]; ok && !jsonEqual(c, data) {
+		rcv.fail(pointer, "value must be %s", jsonText(c))
+	}
+
+	if n, ok := schemaNumber(data); ok {
+		rcv.validateNumber(s, n, pointer)
+	}
+	if str, ok := data.(string); ok {
+		rcv.validateString(s, str, pointer)
+	}
+	if arr, ok := toArray(data); ok {
+		rcv.validateArray(s, arr, pointer, depth)
+	}
+	if obj, ok := data.(map[string]any); ok {
+		rcv.validateObject(s, obj, pointer, depth)
+	}
+
+	rcv.validateCombinators(s, data, pointer, depth)
+}
+
+func (rcv *schemaValidator) validateNumber(s map[string]any, n float64, pointer string) {
+	if v, ok := schemaNumber(s["minimum"]); ok && n < v {
+		rcv.fail(pointer, "%v is less than minimum %v", n, v)
+	}
+	if v, ok := schemaNumber(s["maximum"]); ok && n > v {
+		rcv.fail(pointer, "%v is greater than maximum %v", n, v)
+	}
+	if v, ok := schemaNumber(s["exclusiveMinimum"]); ok && n <= v {
+		rcv.fail(pointer, "%v must be greater than %v", n, v)
+	}
+	if v, ok := schemaNumber(s["exclusiveMaximum"]); ok && n >= v {
+		rcv.fail(pointer, "%v must be less than %v", n, v)
+	}
+	if v, ok := schemaNumber(s["multipleOf"]); ok && v > 0 {
+		if q := n / v; math.Abs(q-math.Round(q)) > 1e-9 {
+			rcv.fail(pointer, "%v is not a multiple of %v", n, v)
+		}
+	}
+}
+
+func (rcv *schemaValidator) validateString(s map[string]any, str, pointer string) {
+	length := utf8.RuneCountInString(str)
+	if v, ok := schemaNumber(s["minLength"]); ok && float64(length) < v {
+		rcv.fail(pointer, "string is shorter than %v characters", v)
+	}
+	if v, ok := schemaNumber(s["maxLength"]); ok && float64(length) > v {
+		rcv.fail(pointer, "string is longer than %v characters", v)
+	}
+	if p, ok := s["pattern"].(string); ok {
+		re, err := rcv.regexp(p)
+		if err != nil {
+			rcv.fail(pointer, "invalid pattern %q in schema: %v", p, err)
+		} else if !re.MatchString(str) {
+			rcv.fail(pointer, "%q does not match pattern %q", str, p)
+		}
+	}
+	if f, ok := s["format"].(string); ok && !formatMatches(f, str) {
+		rcv.fail(pointer, "%q is not a valid %s", str, f)
+	}
+}
+
+func (rcv *schemaValidator) validateArray(s map[string]any, arr []any, pointer string, depth int) {
+	if v, ok := schemaNumber(s["minItems"]); ok && float64(len(arr)) < v {
+		rcv.fail(pointer, "array has fewer than %v items", v)
+	}
+	if v, ok := schemaNumber(s["maxItems"]); ok && float64(len(arr)) > v {
+		rcv.fail(pointer, "array has more than %v items", v)
+	}
+	if unique, _ := s["uniqueItems"].(bool); unique {
+		for i := 1; i < len(arr); i++ {
+			for j := 0; j < i; j++ {
+				if jsonEqual(arr[i], arr[j]) {
+					rcv.fail(pointer, "items %d and %d are equal", j, i)
+				}
+			}
+		}
+	}
+
+	prefix, _ := s["prefixItems"].([]any)
+	for i, item := range arr {
+		itemPointer := pointer + "/" + strconv.Itoa(i)
+		if i < len(prefix) {
+			rcv.validate(prefix[i], item, itemPointer, depth+1)
+		} else if items, ok := s["items"]; ok {
+			rcv.validate(items, item, itemPointer, depth+1)
+		}
+	}
+
+	if contains, ok := s["contains"]; ok {
+		matches := 0
+		for i, item := range arr {
+			if rcv.valid(contains, item, pointer+"/"+strconv.Itoa(i), depth+1) {
+				matches++
+			}
+		}
+		minContains := 1.0
+		if v, ok := schemaNumber(s["minContains"]); ok {
+			minContains = v
+		}
+		if float64(matches) < minContains {
+			rcv.fail(pointer, "array must contain at least %v matching items", minContains)
+		}
+		if v, ok := schemaNumber(s["maxContains"]); ok && float64(matches) > v {
+			rcv.fail(pointer, "array must contain at most %v matching items", v)
+		}
+	}
+}
+
+func (rcv *schemaValidator) validateObject(s map[string]any, obj map[string]any, pointer string, depth int) {
+	if v, ok := schemaNumber(s["minProperties"]); ok && float64(len(obj)) < v {
+		rcv.fail(pointer, "object has fewer than %v properties", v)
+	}
+	if v, ok := schemaNumber(s["maxProperties"]); ok && float64(len(obj)) > v {
+		rcv.fail(pointer, "object has more than %v properties", v)
+	}
+	if required, ok := s["required"].([]any); ok {
+		for _, r := range required {
+			if name, ok := r.(string); ok {
+				if _, exists := obj[name]; !exists {
+					rcv.fail(pointer, "missing required property %q", name)
+				}
+			}
+		}
+	}
+	if deps, ok := s["dependentRequired"].(map[string]any); ok {
+		for _, name := range sortedKeys(deps) {
+			if _, exists := obj[name]; !exists {
+				continue
+			}
+			list, _ := deps[name].([]any)
+			for _, r := range list {
+				if dep, ok := r.(string); ok {
+					if _, exists := obj[dep]; !exists {
+						rcv.fail(pointer, "property %q requires property %q", name, dep)
+					}
+				}
+			}
+		}
+	}
+
+	props, _ := s["properties"].(map[string]any)
+	patternProps, _ := s["patternProperties"].(map[string]any)
+	additional, hasAdditional := s["additionalProperties"]
+	propertyNames, hasPropertyNames := s["propertyNames"]
+
+	for _, name := range sortedKeys(obj) {
+		value := obj[name]
+		childPointer := pointer + "/" + escapePointer(name)
+		if hasPropertyNames {
+			rcv.validate(propertyNames, name, childPointer, depth+1)
+		}
+
+		matched := false
+		if sub, ok := props[name]; ok {
+			matched = true
+			rcv.validate(sub, value, childPointer, depth+1)
+		}
+		for _, p := range sortedKeys(patternProps) {
+			re, err := rcv.regexp(p)
+			if err != nil {
+				rcv.fail(pointer, "invalid pattern %q in schema: %v", p, err)
+				continue
+			}
+			if re.MatchString(name) {
+				matched = true
+				rcv.validate(patternProps[p], value, childPointer, depth+1)
+			}
+		}
+		if !matched && hasAdditional {
+			if allowed, ok := additional.(bool); ok && !allowed {
+				rcv.fail(childPointer, "additional property %q is not allowed", name)
+			} else {
+				rcv.validate(additional, value, childPointer, depth+1)
+			}
+		}
+	}
+}
+
+func (rcv *schemaValidator) validateCombinators(s map[string]any, data any, pointer string, depth int) {
+	if all, ok := s["allOf"].([]any); ok {
+		for _, sub := range all {
+			rcv.validate(sub, data, pointer, depth+1)
+		}
+	}
+	if anyOf, ok := s["anyOf"].([]any); ok {
+		matched := false
+		for _, sub := range anyOf {
+			if rcv.valid(sub, data, pointer, depth+1) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			rcv.fail(pointer, "value does not match any schema in anyOf")
+		}
+	}
+	if oneOf, ok := s["oneOf"].([]any); ok {
+		matches := 0
+		for _, sub := range oneOf {
+			if rcv.valid(sub, data, pointer, depth+1) {
+				matches++
+			}
+		}
+		if matches != 1 {
+			rcv.fail(pointer, "value must match exactly one schema in oneOf (matched %d)", matches)
+		}
+	}
+	if not, ok := s["not"]; ok && rcv.valid(not, data, pointer, depth+1) {
+		rcv.fail(pointer, "value must not match the schema in not")
+	}
+	if cond, ok := s["if"]; ok {
+		if rcv.valid(cond, data, pointer, depth+1) {
+			if then, ok := s["then"]; ok {
+				rcv.validate(then, data, pointer, depth+1)
+			}
+		} else if els, ok := s["else"]; ok {
+			rcv.validate(els, data, pointer, depth+1)
+		}
+	}
+}
+
+// resolveRef resolves a local reference such as "#/$defs/address"
+func (rcv *schemaValidator) resolveRef(ref string) (any, error) {
+	if ref == "#" {
+		return rcv.root, nil
+	}
+	if !strings.HasPrefix(ref, "#/") {
+		return nil, fmt.Errorf("unsupported $ref %q (only local references are supported)", ref)
+	}
+	current := rcv.root
+	for _, token := range strings.Split(ref[2:], "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		switch c := current.(type) {
+		case map[string]any:
+			next, ok := c[token]
+			if !ok {
+				return nil, fmt.Errorf("$ref %q not found in schema", ref)
+			}
+			current = next
+		case []any:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(c) {
+				return nil, fmt.Errorf("$ref %q not found in schema", ref)
+			}
+			current = c[i]
+		default:
+			return nil, fmt.Errorf("$ref %q not found in schema", ref)
+		}
+	}
+	return current, nil
+}
+
+func (rcv *schemaValidator) regexp(pattern string) (*regexp.Regexp, error) {
+	if re, ok := rcv.patterns[pattern]; ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	rcv.patterns[pattern] = re
+	return re, nil
+}
+
+// typeMatches checks the "type" keyword, which is a type name or a list of names
+func typeMatches(t any, data any) bool {
+	switch v := t.(type) {
+	case string:
+		return jsonTypeIs(v, data)
+	case []any:
+		for _, name := range v {
+			if s, ok := name.(string); ok && jsonTypeIs(s, data) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func typeNames(t any) string {
+	if list, ok := t.([]any); ok {
+		names := make([]string, 0, len(list))
+		for _, name := range list {
+			names = append(names, fmt.Sprint(name))
+		}
+		return strings.Join(names, " or ")
+	}
+	return fmt.Sprint(t)
+}
+
+func jsonTypeIs(name string, data any) bool {
+	actual := jsonTypeOf(data)
+	if name == "number" && actual == "integer" {
+		return true
+	}
+	return name == actual
+}
+
+// jsonTypeOf names the JSON type of a decoded JSON or YAML value
+func jsonTypeOf(data any) string {
+	switch data.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case map[string]any:
+		return "object"
+	}
+	if _, ok := toArray(data); ok {
+		return "array"
+	}
+	if n, ok := schemaNumber(data); ok {
+		if n == math.Trunc(n) && !math.IsInf(n, 0) {
+			return "integer"
+		}
+		return "number"
+	}
+	return fmt.Sprintf("%T", data)
+}
+
+// schemaNumber converts JSON and YAML numeric values to float64
+func schemaNumber(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// toArray converts the slice types produced by the decoders (and by callers) to []any
+func toArray(v any) ([]any, bool) {
+	switch a := v.(type) {
+	case []any:
+		return a, true
+	case []map[string]any:
+		out := make([]any, len(a))
+		for i, item := range a {
+			out[i] = item
+		}
+		return out, true
+	}
+	return nil, false
+}
+
+// jsonEqual compares two values with JSON semantics (1 equals 1.0)
+func jsonEqual(a, b any) bool {
+	if x, ok := schemaNumber(a); ok {
+		y, ok := schemaNumber(b)
+		return ok && x == y
+	}
+	if x, ok := toArray(a); ok {
+		y, ok := toArray(b)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !jsonEqual(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	if x, ok := a.(map[string]any); ok {
+		y, ok := b.(map[string]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for k, v := range x {
+			if w, ok := y[k]; !ok || !jsonEqual(v, w) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// jsonText renders a value for error messages
+func jsonText(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+var emailRe = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+
+// formatMatches asserts the formats commonly used for report data
+func formatMatches(format, s string) bool {
+	switch format {
+	case "date":
+		_, err := time.Parse("2006-01-02", s)
+		return err == nil
+	case "date-time":
+		_, err := time.Parse(time.RFC3339, s)
+		return err == nil
+	case "email":
+		return emailRe.MatchString(s)
+	}
+	return true
+}
+
+// formatYAMLTime renders a decoded YAML timestamp as a date or RFC 3339 date-time string
+func formatYAMLTime(t time.Time) string {
+	if t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 && t.Nanosecond() == 0 && t.Location() == time.UTC {
+		return t.Format("2006-01-02")
+	}
+	return t.Format(time.RFC3339)
+}
+
+// escapePointer escapes a property name for use in a JSON pointer
+func escapePointer(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "~", "~0"), "/", "~1")
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -3,6 +3,7 @@ package usecase
 import (
 	"context"
 	"fmt"
+	"io/fs"
 	"strings"
 
 	"github.com/ryo-arima/goxcel/pkg/config"
@@ -12,9 +13,14 @@ import (
 
 // sheetRenderer is an internal renderer for sheet-level operations
 type sheetRenderer struct {
-	conf   config.BaseConfig
-	logger util.Logger
-	cell   *cellHelper
+	conf      config.BaseConfig
+	logger    util.Logger
+	cell      *cellHelper
+	importCtx *importContext // Shared with the book renderer for <Include> cycle and depth checks
+
+	components       *componentRegistry // Components available to <Use>
+	activeComponents []string           // Components currently being rendered, for recursion checks
+	budget           *renderBudget      // Cancellation and resource limits (nil means unlimited)
 }
 
 // newSheetRenderer creates a new internal sheet renderer
@@ -28,11 +34,18 @@ func newSheetRenderer(conf config.BaseConfig) *sheetRenderer {
 
 // RenderSheet renders a SheetTag with data context into a Sheet
 func (rcv *sheetRenderer) RenderSheet(ctx context.Context, sheetTag *model.SheetTag, data map[string]any) (*model.Sheet, error) {
+	return rcv.RenderSheetWithStack(ctx, sheetTag, []map[string]any{data})
+}
+
+// RenderSheetWithStack renders a SheetTag with an existing context stack (e.g. inside a book-level loop)
+func (rcv *sheetRenderer) RenderSheetWithStack(ctx context.Context, sheetTag *model.SheetTag, ctxStack []map[string]any) (*model.Sheet, error) {
 	if sheetTag == nil {
 		return nil, fmt.Errorf("sheet tag is nil")
 	}
 
-	sheet := model.NewSheet(sheetTag.Name)
+	// Sheet names may contain mustache expressions (e.g. name="{{ region }}")
+	sheet := model.NewSheet(rcv.cell.ExpandMustache(ctxStack, sheetTag.Name))
+	sheet.Mode = sheetTag.Mode
 
 	// Apply sheet-level defaults from tag config (if provided)
 	if sheetTag.Config != nil {
@@ -52,9 +65,6 @@ func (rcv *sheetRenderer) RenderSheet(ctx context.Context, sheetTag *model.Sheet
 		rowOffset: 0,
 	}
 
-	// Create context stack from data
-	ctxStack := []map[string]any{data}
-
 	// Render all nodes in the sheet
 	if err := rcv.renderNodes(state, ctxStack, sheetTag.Nodes); err != nil {
 		return nil, fmt.Errorf("render sheet %q: %w", sheetTag.Name, err)
@@ -74,6 +84,9 @@ type renderState struct {
 // renderNodes processes a list of nodes (tags) and renders them to the sheet
 func (rcv *sheetRenderer) renderNodes(state *renderState, ctxStack []map[string]any, nodes []any) error {
 	for _, node := range nodes {
+		if err := rcv.budget.check(); err != nil {
+			return err
+		}
 		if err := rcv.renderNode(state, ctxStack, node); err != nil {
 			return err
 		}
@@ -92,10 +105,18 @@ func (rcv *sheetRenderer) renderNode(state *renderState, ctxStack []map[string]a
 		return rcv.handleGridRow(state, ctxStack, v)
 	case model.MergeTag:
 		return rcv.handleMerge(state, v)
+	case model.ColumnTag:
+		return rcv.handleColumn(state, v)
 	case model.TableTag:
 		return rcv.handleTable(state, ctxStack, v)
 	case model.ForTag:
 		return rcv.handleFor(state, ctxStack, v)
+	case model.IncludeTag:
+		return rcv.handleInclude(state, ctxStack, v)
+	case model.UseTag:
+		return rcv.handleUse(state, ctxStack, v)
+	case model.BlockTag:
+		return rcv.renderNodes(state, ctxStack, v.Nodes)
 	case model.ImageTag:
 		return rcv.handleImage(state, v)
 	case model.ShapeTag:
@@ -202,13 +223,24 @@ func (rcv *sheetRenderer) handleGridRowWithStyle(state *renderState, ctxStack []
 	for colIndex, cellValue := range row.Cells {
 		col := state.anchorCol + colIndex
 		cell := rcv.createCell(currentRow, col, cellValue, ctxStack, baseStyle)
-		state.sheet.AddCell(cell)
+		if err := rcv.addCell(state, currentRow, cell); err != nil {
+			return err
+		}
 	}
 
 	state.rowOffset++
 	return nil
 }
 
+// addCell adds a cell to the sheet within the cell and row limits
+func (rcv *sheetRenderer) addCell(state *renderState, row int, cell *model.Cell) error {
+	if err := rcv.budget.addCell(row); err != nil {
+		return err
+	}
+	state.sheet.AddCell(cell)
+	return nil
+}
+
 // createCell creates a cell with proper type and style
 func (rcv *sheetRenderer) createCell(row, col int, cellValue string, ctxStack []map[string]any, baseStyle *model.CellStyle) *model.Cell {
 	ref := toA1Ref(row, col)
@@ -277,6 +309,18 @@ func (rcv *sheetRenderer) gridTagToStyle(ctxStack []map[string]any, tag model.Gr
 		st.Border = b
 		has = true
 	}
+	if tag.Bold || tag.Italic || tag.Underline {
+		st.Bold, st.Italic, st.Underline = tag.Bold, tag.Italic, tag.Underline
+		has = true
+	}
+	if tag.HAlign != "" || tag.VAlign != "" {
+		st.HAlign, st.VAlign = tag.HAlign, tag.VAlign
+		has = true
+	}
+	if tag.NumberFormat != "" {
+		st.NumberFormat = tag.NumberFormat
+		has = true
+	}
 	if !has {
 		return nil
 	}
@@ -334,9 +378,25 @@ func mergeStyles(a, b *model.CellStyle) *model.CellStyle {
 	if b.VAlign != "" {
 		c.VAlign = b.VAlign
 	}
+	if b.NumberFormat != "" {
+		c.NumberFormat = b.NumberFormat
+	}
 	return &c
 }
 
+// handleColumn sets the width of a column, replacing an earlier setting
+func (rcv *sheetRenderer) handleColumn(state *renderState, tag model.ColumnTag) error {
+	widths := state.sheet.Config.ColumnWidths
+	for i := range widths {
+		if widths[i].Column == tag.Index {
+			widths[i].Width = tag.Width
+			return nil
+		}
+	}
+	state.sheet.Config.ColumnWidths = append(widths, model.ColumnWidth{Column: tag.Index, Width: tag.Width})
+	return nil
+}
+
 // handleMerge adds a cell merge to the sheet
 func (rcv *sheetRenderer) handleMerge(state *renderState, tag model.MergeTag) error {
 	state.sheet.AddMerge(model.Merge{Range: tag.Range})
@@ -372,6 +432,9 @@ func (rcv *sheetRenderer) handleTableRow(state *renderState, ctxStack []map[stri
 	switch arr := items.(type) {
 	case []any:
 		for idx, item := range arr {
+			if err := rcv.budget.iterate(); err != nil {
+				return err
+			}
 			loopScope := rcv.createLoopScope(varName, item, idx)
 			newStack := append([]map[string]any{loopScope}, ctxStack...)
 			if err := rcv.renderTableCols(state, newStack, row.Cols); err != nil {
@@ -380,6 +443,9 @@ func (rcv *sheetRenderer) handleTableRow(state *renderState, ctxStack []map[stri
 		}
 	case []map[string]any:
 		for idx, item := range arr {
+			if err := rcv.budget.iterate(); err != nil {
+				return err
+			}
 			loopScope := rcv.createLoopScope(varName, item, idx)
 			newStack := append([]map[string]any{loopScope}, ctxStack...)
 			if err := rcv.renderTableCols(state, newStack, row.Cols); err != nil {
@@ -400,7 +466,9 @@ func (rcv *sheetRenderer) renderTableCols(state *renderState, ctxStack []map[str
 			// No loop, just render cell
 			expanded := rcv.cell.ExpandMustache(ctxStack, col.Content)
 			cell := rcv.createCell(currentRow, currentCol, expanded, ctxStack, nil)
-			state.sheet.AddCell(cell)
+			if err := rcv.addCell(state, currentRow, cell); err != nil {
+				return err
+			}
 			currentCol++
 		} else {
 			// Col loop: iterate horizontally
@@ -414,20 +482,30 @@ func (rcv *sheetRenderer) renderTableCols(state *renderState, ctxStack []map[str
 			switch arr := items.(type) {
 			case []any:
 				for idx, item := range arr {
+					if err := rcv.budget.iterate(); err != nil {
+						return err
+					}
 					loopScope := rcv.createLoopScope(varName, item, idx)
 					newStack := append([]map[string]any{loopScope}, ctxStack...)
 					expanded := rcv.cell.ExpandMustache(newStack, col.Content)
 					cell := rcv.createCell(currentRow, currentCol, expanded, ctxStack, nil)
-					state.sheet.AddCell(cell)
+					if err := rcv.addCell(state, currentRow, cell); err != nil {
+						return err
+					}
 					currentCol++
 				}
 			case []map[string]any:
 				for idx, item := range arr {
+					if err := rcv.budget.iterate(); err != nil {
+						return err
+					}
 					loopScope := rcv.createLoopScope(varName, item, idx)
 					newStack := append([]map[string]any{loopScope}, ctxStack...)
 					expanded := rcv.cell.ExpandMustache(newStack, col.Content)
 					cell := rcv.createCell(currentRow, currentCol, expanded, ctxStack, nil)
-					state.sheet.AddCell(cell)
+					if err := rcv.addCell(state, currentRow, cell); err != nil {
+						return err
+					}
 					currentCol++
 				}
 			}
@@ -451,6 +529,61 @@ func (rcv *sheetRenderer) handleFor(state *renderState, ctxStack []map[string]an
 	return rcv.iterateAndRender(state, ctxStack, varName, items, tag.Body)
 }
 
+// handleInclude splices the nodes of a fragment from another file at the current position
+func (rcv *sheetRenderer) handleInclude(state *renderState, ctxStack []map[string]any, tag model.IncludeTag) error {
+	if rcv.importCtx == nil {
+		rcv.importCtx = newImportContext(rcv.conf)
+	}
+
+	// Resolve the path, check depth and circular includes, and mark the file as visited
+	normalizedPath, leave, err := rcv.importCtx.enter(tag.Src)
+	if err != nil {
+		return err
+	}
+	defer leave()
+
+	rcv.logger.DEBUG(util.UBR1, "Loading included fragment", map[string]interface{}{
+		"file":     normalizedPath,
+		"fragment": tag.Fragment,
+	})
+
+	includedGxl, err := rcv.importCtx.readGxl(normalizedPath, rcv.logger)
+	if err != nil {
+		return err
+	}
+
+	var fragment *model.FragmentTag
+	for i := range includedGxl.Fragments {
+		if includedGxl.Fragments[i].Name == tag.Fragment {
+			fragment = &includedGxl.Fragments[i]
+			break
+		}
+	}
+	if fragment == nil {
+		return fmt.Errorf("fragment %q not found in %s", tag.Fragment, normalizedPath)
+	}
+
+	// The fragment can use the components defined in its own file
+	saved := rcv.components
+	if rcv.components, err = scopeComponents(saved, rcv.importCtx, &includedGxl, normalizedPath, rcv.logger); err != nil {
+		rcv.components = saved
+		return err
+	}
+	defer func() { rcv.components = saved }()
+
+	// Scope the data context when a with path is given
+	fragmentStack := ctxStack
+	if tag.With != "" {
+		scope, ok := rcv.cell.ResolvePath(ctxStack, tag.With).(map[string]any)
+		if !ok {
+			return fmt.Errorf("include with %q must resolve to an object", tag.With)
+		}
+		fragmentStack = append(ctxStack, scope)
+	}
+
+	return rcv.renderNodes(state, fragmentStack, fragment.Nodes)
+}
+
 // parseForSyntax parses "varName in dataPath" syntax
 func (rcv *sheetRenderer) parseForSyntax(each string) (varName, dataPath string, err error) {
 	parts := strings.Fields(each)
@@ -476,6 +609,9 @@ func (rcv *sheetRenderer) iterateAndRender(state *renderState, ctxStack []map[st
 // renderLoop renders loop body for []any array
 func (rcv *sheetRenderer) renderLoop(state *renderState, ctxStack []map[string]any, varName string, items []any, body []any) error {
 	for i, item := range items {
+		if err := rcv.budget.iterate(); err != nil {
+			return err
+		}
 		scope := rcv.createLoopScope(varName, item, i)
 		newStack := append(ctxStack, scope)
 		if err := rcv.renderNodes(state, newStack, body); err != nil {
@@ -488,6 +624,9 @@ func (rcv *sheetRenderer) renderLoop(state *renderState, ctxStack []map[string]a
 // renderMapLoop renders loop body for []map[string]any array
 func (rcv *sheetRenderer) renderMapLoop(state *renderState, ctxStack []map[string]any, varName string, items []map[string]any, body []any) error {
 	for i, item := range items {
+		if err := rcv.budget.iterate(); err != nil {
+			return err
+		}
 		scope := rcv.createLoopScope(varName, item, i)
 		newStack := append(ctxStack, scope)
 		if err := rcv.renderNodes(state, newStack, body); err != nil {
@@ -510,6 +649,15 @@ func (rcv *sheetRenderer) createLoopScope(varName string, item any, index int) m
 
 // handleImage adds an image to the sheet
 func (rcv *sheetRenderer) handleImage(state *renderState, tag model.ImageTag) error {
+	// Image files are read when the workbook is written; keep them inside the sandbox
+	if rcv.conf.Sandbox != "" {
+		if rcv.importCtx == nil {
+			rcv.importCtx = newImportContext(rcv.conf)
+		}
+		if _, err := rcv.importCtx.resolve(tag.Src); err != nil {
+			return fmt.Errorf("image: %w", err)
+		}
+	}
 	state.sheet.AddImage(model.Image{
 		Ref:      tag.Ref,
 		Source:   tag.Src,
@@ -641,8 +789,11 @@ func toA1Ref(row, col int) string {
 type importContext struct {
 	visitedFiles map[string]bool
 	importDepth  int
+	maxDepth     int             // Maximum nesting level for imports
+	maxFiles     int             // Maximum number of distinct files entered (0 means unlimited)
+	filesSeen    map[string]bool // Distinct files entered so far, by normalized path
 	baseDir      string
+	fsys         fs.FS                // Filesystem templates are read from (nil means the OS filesystem)
+	sandbox      string               // Root that files on the OS filesystem must stay inside (empty means anywhere)
+	parsed       map[string]model.GXL // Templates already read, by normalized path
 }
-
-// maxImportDepth is the maximum nesting level for imports
-const maxImportDepth = 10
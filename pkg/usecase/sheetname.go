@@ -0,0 +1,104 @@
+package usecase
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ryo-arima/goxcel/pkg/config"
+)
+
+// maxSheetNameLength is the maximum number of characters Excel allows in a sheet name
+const maxSheetNameLength = 31
+
+// invalidSheetNameChars are characters Excel rejects in sheet names
+const invalidSheetNameChars = `[]:*?/\`
+
+// sheetNamer validates sheet names and keeps them unique within a workbook
+type sheetNamer struct {
+	policy string
+	used   map[string]bool // lower-cased names already assigned (Excel compares case-insensitively)
+}
+
+// newSheetNamer creates a sheet namer for the given policy
+func newSheetNamer(policy string) (*sheetNamer, error) {
+	switch policy {
+	case "":
+		policy = config.SheetNamePolicyError
+	case config.SheetNamePolicyError, config.SheetNamePolicySuffix:
+	default:
+		return nil, fmt.Errorf("unknown sheet name policy %q (expected %q or %q)", policy, config.SheetNamePolicyError, config.SheetNamePolicySuffix)
+	}
+	return &sheetNamer{policy: policy, used: make(map[string]bool)}, nil
+}
+
+// Assign validates name and returns the name to use in the workbook.
+// With the error policy any problem is returned as an error; with the suffix policy
+// invalid names are sanitized and duplicates receive a " (n)" suffix.
+func (rcv *sheetNamer) Assign(name string) (string, error) {
+	if rcv.policy == config.SheetNamePolicySuffix {
+		base := sanitizeSheetName(name)
+		candidate := base
+		for n := 2; rcv.used[strings.ToLower(candidate)]; n++ {
+			suffix := fmt.Sprintf(" (%d)", n)
+			candidate = truncateRunes(base, maxSheetNameLength-len(suffix)) + suffix
+		}
+		rcv.used[strings.ToLower(candidate)] = true
+		return candidate, nil
+	}
+
+	if err := validateSheetName(name); err != nil {
+		return "", err
+	}
+	if rcv.used[strings.ToLower(name)] {
+		return "", fmt.Errorf("duplicate sheet name %q", name)
+	}
+	rcv.used[strings.ToLower(name)] = true
+	return name, nil
+}
+
+// validateSheetName checks a sheet name against Excel's naming rules
+func validateSheetName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return fmt.Errorf("sheet name is empty")
+	}
+	if n := utf8.RuneCountInString(name); n > maxSheetNameLength {
+		return fmt.Errorf("sheet name %q is %d characters long (max %d)", name, n, maxSheetNameLength)
+	}
+	if i := strings.IndexAny(name, invalidSheetNameChars); i >= 0 {
+		return fmt.Errorf("sheet name %q contains invalid character %q", name, name[i])
+	}
+	if strings.HasPrefix(name, "'") || strings.HasSuffix(name, "'") {
+		return fmt.Errorf("sheet name %q must not begin or end with an apostrophe", name)
+	}
+	if strings.EqualFold(name, "History") {
+		return fmt.Errorf("sheet name %q is reserved by Excel", name)
+	}
+	return nil
+}
+
+// sanitizeSheetName rewrites a name so that it satisfies validateSheetName
+func sanitizeSheetName(name string) string {
+	s := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(invalidSheetNameChars, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	s = strings.Trim(strings.TrimSpace(s), "'")
+	if s == "" {
+		s = "Sheet"
+	}
+	if strings.EqualFold(s, "History") {
+		s += "_"
+	}
+	return truncateRunes(s, maxSheetNameLength)
+}
+
+// truncateRunes shortens s to at most n runes
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
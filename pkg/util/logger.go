@@ -76,10 +76,17 @@ var (
 	USF1 = MCode{"US-F1", "For loop processing"}
 	USA1 = MCode{"US-A1", "Anchor positioning"}
 
+	// UseCase Convert Layer Codes - UCV_* (UseCase Convert)
+	UCVW1 = MCode{"UCV-W1", "Conversion warning"}
+
+	// UseCase Extract Layer Codes - UEX_* (UseCase Extract)
+	UEXW1 = MCode{"UEX-W1", "Extraction warning"}
+
 	// UseCase Book Layer Codes - UB_* (UseCase Book)
 	UBR1 = MCode{"UB-R1", "Book rendering started"}
 	UBR2 = MCode{"UB-R2", "Book rendering completed"}
 	UBN1 = MCode{"UB-N1", "Data normalization"}
+	UBW1 = MCode{"UB-W1", "Book rendering warning"}
 
 	// Model Layer Codes - M_* (Model)
 	MV1 = MCode{"M-V1", "Model validation success"}
@@ -159,7 +166,7 @@ type LoggerConfig struct {
 	Level        string `json:"level" yaml:"level"`
 	Structured   bool   `json:"structured" yaml:"structured"`
 	EnableCaller bool   `json:"enable_caller" yaml:"enable_caller"`
-	Output       string `json:"output" yaml:"output"`
+	Output       string `json:"output" yaml:"output"` // "stderr" (default), "stdout" or a file path to append to
 }
 
 // LOGGER represents the application logger
@@ -182,7 +189,7 @@ type Logger interface {
 func NewLogger(loggerConfig LoggerConfig) Logger {
 	logger := &LOGGER{
 		config: &loggerConfig,
-		output: os.Stdout,
+		output: os.Stderr,
 	}
 
 	// Set log level
@@ -203,16 +210,16 @@ func NewLogger(loggerConfig LoggerConfig) Logger {
 
 	// Set output
 	switch loggerConfig.Output {
-	case "stderr":
+	case "stderr", "":
 		logger.output = os.Stderr
-	case "stdout", "":
+	case "stdout":
 		logger.output = os.Stdout
 	default:
 		// File output
 		if file, err := os.OpenFile(loggerConfig.Output, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o666); err == nil {
 			logger.output = file
 		} else {
-			logger.output = os.Stdout
+			logger.output = os.Stderr
 			logger.ERROR(FSW2, fmt.Sprintf("file: %s, error: %s", loggerConfig.Output, err.Error()))
 		}
 	}
@@ -345,3 +352,17 @@ func (rcv *LOGGER) FATAL(mcode MCode, optionalMessage string, fields ...map[stri
 
 // exitFunc allows tests to override process exit behavior for FATAL.
 var exitFunc = os.Exit
+
+// nopLogger discards every entry
+type nopLogger struct{}
+
+// NewNopLogger returns a logger that discards all output (FATAL still exits)
+func NewNopLogger() Logger {
+	return nopLogger{}
+}
+
+func (nopLogger) DEBUG(MCode, string, ...map[string]interface{}) {}
+func (nopLogger) INFO(MCode, string, ...map[string]interface{})  {}
+func (nopLogger) WARN(MCode, string, ...map[string]interface{})  {}
+func (nopLogger) ERROR(MCode, string, ...map[string]interface{}) {}
+func (nopLogger) FATAL(MCode, string, ...map[string]interface{}) { exitFunc(1) }
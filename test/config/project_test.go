@@ -0,0 +1,125 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ryo-arima/goxcel/pkg/config"
+)
+
+func TestFindProjectConfig(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "reports", "monthly")
+	if err := os.MkdirAll(nested, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := config.FindProjectConfig(nested)
+	if err != nil {
+		t.Fatalf("FindProjectConfig: %v", err)
+	}
+	if got != "" && strings.HasPrefix(got, root) {
+		t.Errorf("found %q before any file was written", got)
+	}
+
+	want := filepath.Join(root, config.ProjectConfigFile)
+	if err := os.WriteFile(want, []byte("strict: true\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, err = config.FindProjectConfig(nested)
+	if err != nil {
+		t.Fatalf("FindProjectConfig: %v", err)
+	}
+	if got != want {
+		t.Errorf("FindProjectConfig = %q, want %q", got, want)
+	}
+}
+
+func TestLoadProjectConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, config.ProjectConfigFile)
+	content := `templates_dir: templates
+data_paths: [data, /srv/shared]
+strict: true
+sheet_names: suffix
+locale: ja-JP
+timezone: Asia/Tokyo
+log:
+  level: debug
+  format: json
+output_dir: out
+writer:
+  compression: best
+  creator: Reports Team
+sandbox: .
+limits:
+  max_cells: 100000
+  timeout: 30s
+`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := config.LoadProjectConfig(path)
+	if err != nil {
+		t.Fatalf("LoadProjectConfig: %v", err)
+	}
+	want := config.ProjectConfig{
+		Path:            path,
+		TemplatesDir:    filepath.Join(dir, "templates"),
+		DataPaths:       []string{filepath.Join(dir, "data"), "/srv/shared"},
+		Strict:          true,
+		SheetNamePolicy: config.SheetNamePolicySuffix,
+		Locale:          "ja-JP",
+		Timezone:        "Asia/Tokyo",
+		Log:             config.ProjectLogConfig{Level: "debug", Format: "json"},
+		OutputDir:       filepath.Join(dir, "out"),
+		Writer:          config.WriterOptions{Compression: config.CompressionBest, Creator: "Reports Team"},
+		Sandbox:         dir,
+		Limits:          config.Limits{MaxCells: 100000, Timeout: 30 * time.Second},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("LoadProjectConfig mismatch (-want +got):\n%s", diff)
+	}
+
+	var conf config.BaseConfig
+	if err := got.Apply(&conf); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	if conf.Location == nil || conf.Location.String() != "Asia/Tokyo" {
+		t.Errorf("Location = %v, want Asia/Tokyo", conf.Location)
+	}
+	if conf.OutputDir != want.OutputDir || conf.Writer != want.Writer || conf.Sandbox != dir {
+		t.Errorf("Apply did not copy the settings: %+v", conf)
+	}
+}
+
+func TestLoadProjectConfig_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"unknown field", "stirct: true\n", "field stirct not found"},
+		{"timezone", "timezone: Mars/Olympus\n", "timezone"},
+		{"log format", "log: {format: xml}\n", "log.format"},
+		{"compression", "writer: {compression: max}\n", "unknown compression"},
+		{"sheet names", "sheet_names: rename\n", "sheet_names"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), config.ProjectConfigFile)
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := config.LoadProjectConfig(path)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -1,14 +1,18 @@
 package controller_test
 
 import (
+	"archive/zip"
 	"bytes"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
 	"github.com/ryo-arima/goxcel/pkg/controller"
 	"github.com/ryo-arima/goxcel/pkg/model"
+	parser "github.com/ryo-arima/goxcel/pkg/repository"
 )
 
 func TestGenerateCmd_BasicExecution(t *testing.T) {
@@ -751,3 +755,320 @@ func TestRunGenerate_AutoDetectFallbackYAML(t *testing.T) {
 		t.Errorf("output file not created: %v", err)
 	}
 }
+
+func TestRunGenerate_SchemaValidation(t *testing.T) {
+	dir := t.TempDir()
+	gxlPath := filepath.Join("..", ".testdata", "schema_invoice.gxl")
+	outputPath := filepath.Join(dir, "output.xlsx")
+
+	badData := filepath.Join(dir, "bad.yaml")
+	if err := os.WriteFile(badData, []byte("invoice:\n  number: \"42\"\nitems: []\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The template's <Header schema> applies when --schema is not given
+	err := controller.RunGenerate(gxlPath, badData, outputPath, false)
+	if err == nil {
+		t.Fatal("expected schema validation error")
+	}
+	for _, want := range []string{"/invoice/number", "/items: array has fewer than 1 items"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+	if _, statErr := os.Stat(outputPath); statErr == nil {
+		t.Error("output must not be written when validation fails")
+	}
+
+	// --schema overrides the template's schema
+	looseSchema := filepath.Join(dir, "loose.schema.json")
+	if err := os.WriteFile(looseSchema, []byte(`{"type": "object"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	err = controller.RunGenerateWithOptions(controller.GenerateOptions{
+		TemplatePath: gxlPath,
+		DataPath:     badData,
+		OutputPath:   outputPath,
+		SchemaPath:   looseSchema,
+	})
+	if err != nil {
+		t.Fatalf("RunGenerateWithOptions: %v", err)
+	}
+}
+
+// TestRunGenerate_SchemaFromBaseTemplate tests that a schema declared by a base template
+// applies to templates extending it, resolved from the base's directory
+func TestRunGenerate_SchemaFromBaseTemplate(t *testing.T) {
+	dir := t.TempDir()
+	base, err := filepath.Abs(filepath.Join("..", ".testdata", "schema_invoice.gxl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gxlPath := filepath.Join(dir, "child.gxl")
+	if err := os.WriteFile(gxlPath, []byte(`<Book extends="`+base+`"></Book>`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	badData := filepath.Join(dir, "bad.yaml")
+	if err := os.WriteFile(badData, []byte("invoice:\n  number: \"42\"\nitems: []\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err = controller.RunGenerate(gxlPath, badData, filepath.Join(dir, "output.xlsx"), false)
+	if err == nil || !strings.Contains(err.Error(), "/invoice/number") {
+		t.Fatalf("err = %v, want schema validation error from the base template's schema", err)
+	}
+}
+
+func TestRunGenerate_CSVData(t *testing.T) {
+	dir := t.TempDir()
+	outputPath := filepath.Join(dir, "output.xlsx")
+	err := controller.RunGenerateWithOptions(controller.GenerateOptions{
+		TemplatePath: filepath.Join("..", ".testdata", "csv_report.gxl"),
+		DataPath:     filepath.Join("..", ".testdata", "sales.csv"),
+		OutputPath:   outputPath,
+	})
+	if err != nil {
+		t.Fatalf("RunGenerateWithOptions: %v", err)
+	}
+	if _, err := os.Stat(outputPath); err != nil {
+		t.Errorf("output file not created: %v", err)
+	}
+
+	err = controller.RunGenerateWithOptions(controller.GenerateOptions{
+		TemplatePath: filepath.Join("..", ".testdata", "csv_report.gxl"),
+		DataPath:     filepath.Join("..", ".testdata", "sales.csv"),
+		CSV:          controller.CSVOptions{Delimiter: ";;"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "single character") {
+		t.Errorf("err = %v, want delimiter error", err)
+	}
+}
+
+func TestRunGenerate_MergedDataAndOverrides(t *testing.T) {
+	dir := t.TempDir()
+	invoice := filepath.Join(dir, "invoice.json")
+	if err := os.WriteFile(invoice, []byte(`{"invoice": {"number": "INV-1", "total": 10}, "company": {"address": {"city": "Osaka"}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := controller.RunGenerateWithOptions(controller.GenerateOptions{
+		TemplatePath: filepath.Join("..", ".testdata", "merge_report.gxl"),
+		DataPaths: []string{
+			filepath.Join("..", ".testdata", "company.yaml"),
+			invoice,
+			"sales=" + filepath.Join("..", ".testdata", "sales.csv"),
+		},
+		Sets:       []string{"invoice.total=120"},
+		SetStrings: []string{"invoice.number=0012"},
+		OutputPath: filepath.Join(dir, "out.xlsx"),
+	})
+	if err != nil {
+		t.Fatalf("RunGenerateWithOptions: %v", err)
+	}
+
+	// --set keeps the leading zeros of a value the template declares as a string
+	err = controller.RunGenerateWithOptions(controller.GenerateOptions{
+		TemplatePath: filepath.Join("..", ".testdata", "merge_report.gxl"),
+		DataPaths: []string{
+			filepath.Join("..", ".testdata", "company.yaml"),
+			"sales=" + filepath.Join("..", ".testdata", "sales.csv"),
+		},
+		Sets:       []string{"company.address.city=Osaka", "invoice.total=120", "invoice.number=0012"},
+		OutputPath: filepath.Join(dir, "out.xlsx"),
+	})
+	if err != nil {
+		t.Fatalf("RunGenerateWithOptions with --set for a string parameter: %v", err)
+	}
+
+	err = controller.RunGenerateWithOptions(controller.GenerateOptions{
+		TemplatePath: filepath.Join("..", ".testdata", "merge_report.gxl"),
+		Sets:         []string{"missing-equals"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "expected path=value") {
+		t.Errorf("err = %v, want override error", err)
+	}
+
+	// Without the overrides the later file's values are checked by the template's <Params>
+	err = controller.RunGenerateWithOptions(controller.GenerateOptions{
+		TemplatePath: filepath.Join("..", ".testdata", "merge_report.gxl"),
+		DataPaths:    []string{filepath.Join("..", ".testdata", "company.yaml"), invoice},
+	})
+	for _, want := range []string{`invoice.number: "INV-1" is not one of 0012`, `invoice.total: "10" is not one of 120`, "sales: required"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("err = %v, want containing %q", err, want)
+		}
+	}
+}
+
+// TestRunGenerate_DataSourceKey tests that "key=path" mounts a file whatever files exist, and
+// that a path starting with "./" is read as a plain path even when it contains "="
+func TestRunGenerate_DataSourceKey(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"report.gxl": `<Book><Params><Param name="info.city" type="string" enum="Osaka" required="true" /></Params>` +
+			`<Sheet name="S"><Grid>| {{ info.city }} |</Grid></Sheet></Book>`,
+		"city.json":      `{"city": "Osaka"}`,
+		"info=city.json": `{"info": {"city": "Osaka"}}`,
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	for _, source := range []string{"info=city.json", "./info=city.json"} {
+		err := controller.RunGenerateWithOptions(controller.GenerateOptions{
+			TemplatePath: "report.gxl",
+			DataPaths:    []string{source},
+			DryRun:       true,
+		})
+		if err != nil {
+			t.Errorf("RunGenerateWithOptions(--data %s): %v", source, err)
+		}
+	}
+
+	// The file named "info=city.json" does not turn the keyed spelling into a path
+	if err := os.Remove("city.json"); err != nil {
+		t.Fatal(err)
+	}
+	err = controller.RunGenerateWithOptions(controller.GenerateOptions{
+		TemplatePath: "report.gxl",
+		DataPaths:    []string{"info=city.json"},
+		DryRun:       true,
+	})
+	if err == nil || !strings.Contains(err.Error(), "city.json") {
+		t.Errorf("err = %v, want an error reading city.json", err)
+	}
+}
+
+func TestGenerateCmd_Stdio(t *testing.T) {
+	tmpl := []byte(`<Book name="Piped"><Sheet name="S1"><Grid>| {{ name }} | {{ value }} |</Grid></Sheet></Book>`)
+	dataPath := filepath.Join(t.TempDir(), "data.json")
+	if err := os.WriteFile(dataPath, []byte(`{"name": "Piped", "value": 7}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	cmd := controller.InitGenerateCmd()
+	cmd.SetIn(bytes.NewReader(tmpl))
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"-t", "-", "-d", dataPath, "-o", "-"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(out.Bytes()), int64(out.Len()))
+	if err != nil {
+		t.Fatalf("stdout is not an xlsx package: %v", err)
+	}
+	found := false
+	for _, f := range zr.File {
+		if f.Name != "xl/worksheets/sheet1.xml" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := io.ReadAll(rc)
+		rc.Close()
+		found = strings.Contains(string(b), "Piped")
+	}
+	if !found {
+		t.Errorf("sheet1.xml does not contain the piped data")
+	}
+}
+
+func TestRunGenerate_DataFromStdin(t *testing.T) {
+	outputPath := filepath.Join(t.TempDir(), "output.xlsx")
+	err := controller.RunGenerateWithOptions(controller.GenerateOptions{
+		TemplatePath: filepath.Join("..", ".testdata", "with_variables.gxl"),
+		DataPaths:    []string{"-"},
+		OutputPath:   outputPath,
+		Stdin:        strings.NewReader("name: FromYAML\nvalue: 1\n"),
+	})
+	if err != nil {
+		t.Fatalf("RunGenerateWithOptions: %v", err)
+	}
+	if _, err := os.Stat(outputPath); err != nil {
+		t.Errorf("output file not created: %v", err)
+	}
+
+	err = controller.RunGenerateWithOptions(controller.GenerateOptions{
+		TemplatePath: "-",
+		DataPaths:    []string{"-"},
+		Stdin:        strings.NewReader(""),
+	})
+	if err == nil || !strings.Contains(err.Error(), "only one of the template and data files") {
+		t.Errorf("err = %v, want stdin conflict error", err)
+	}
+}
+
+func TestGenerateCmd_Base(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "letterhead.xlsx")
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	letterhead := write("letterhead.gxl", `<Book><Sheet name="Cover"><Grid>| ACME Corp. |</Grid></Sheet><Sheet name="Invoice"><Grid>| Invoice | Qty |
+| Old | 1 |</Grid></Sheet></Book>`)
+	if err := controller.RunGenerate(letterhead, "", base, false); err != nil {
+		t.Fatalf("RunGenerate letterhead: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		tmpl  string
+		flags []string
+		want  map[string][]string // Cell values by sheet
+	}{
+		{
+			name: "book base",
+			tmpl: `<Book base="letterhead.xlsx"><Sheet name="Invoice"><Anchor ref="A2" /><Grid>| Widget |</Grid></Sheet></Book>`,
+			want: map[string][]string{"Cover": {"ACME Corp."}, "Invoice": {"Invoice", "Qty", "Widget", "1"}},
+		},
+		{
+			name:  "flag overrides",
+			tmpl:  `<Book base="missing.xlsx"><Sheet name="Invoice" mode="replace"><Grid>| New |</Grid></Sheet><Sheet name="Extra"><Grid>| x |</Grid></Sheet></Book>`,
+			flags: []string{"--base", base},
+			want:  map[string][]string{"Cover": {"ACME Corp."}, "Invoice": {"New"}, "Extra": {"x"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl := write("report.gxl", tt.tmpl)
+			out := filepath.Join(t.TempDir(), "out.xlsx")
+			cmd := controller.InitGenerateCmd()
+			cmd.SetArgs(append([]string{"-t", tmpl, "-o", out}, tt.flags...))
+			if err := cmd.Execute(); err != nil {
+				t.Fatalf("Execute failed: %v", err)
+			}
+			book, err := parser.ReadBookFromFile(out)
+			if err != nil {
+				t.Fatalf("ReadBookFromFile: %v", err)
+			}
+			got := map[string][]string{}
+			for _, sheet := range book.Sheets {
+				got[sheet.Name] = []string{}
+				for _, cell := range sheet.Cells {
+					got[sheet.Name] = append(got[sheet.Name], cell.Value)
+				}
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("cells mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,327 @@
+package controller_test
+
+import (
+	"archive/zip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ryo-arima/goxcel/pkg/controller"
+	"github.com/spf13/cobra"
+)
+
+// newRootWithGlobalFlags wires the generate command below a root carrying the global flags
+func newRootWithGlobalFlags() *cobra.Command {
+	root := &cobra.Command{Use: "goxcel"}
+	controller.AddGlobalFlags(root)
+	root.AddCommand(controller.InitGenerateCmd())
+	return root
+}
+
+// readZipPart returns the content of a part of an xlsx package ("" if it is missing)
+func readZipPart(t *testing.T, path, name string) string {
+	t.Helper()
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatalf("open %s: %v", path, err)
+	}
+	defer zr.Close()
+	for _, f := range zr.File {
+		if f.Name != name {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rc.Close()
+		b, err := io.ReadAll(rc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+	return ""
+}
+
+func TestGenerateCmd_ProjectConfig(t *testing.T) {
+	project := t.TempDir()
+	files := map[string]string{
+		".goxcel.yaml": `data_paths: [data]
+strict: true
+locale: ja-JP
+timezone: Asia/Tokyo
+output_dir: out
+writer:
+  creator: Reports Team
+`,
+		"reports/monthly.gxl": `<Book><Sheet name="S1"><Grid>| {{ title }} | {{ closedAt }} |</Grid></Sheet></Book>`,
+		"data/values.yaml":    "title: Monthly\nclosedAt: 2024-01-15T09:30:00Z\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(project, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The configuration is found above the template; the data file is found in data_paths and
+	// the relative output path lands in output_dir
+	root := newRootWithGlobalFlags()
+	root.SetArgs([]string{"generate", "-t", filepath.Join(project, "reports", "monthly.gxl"), "-d", "values.yaml", "-o", "monthly.xlsx"})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	output := filepath.Join(project, "out", "monthly.xlsx")
+	sheet := readZipPart(t, output, "xl/worksheets/sheet1.xml")
+	if !strings.Contains(sheet, "2024-01-15 18:30:00") {
+		t.Errorf("timestamp not shown in Asia/Tokyo:\n%s", sheet)
+	}
+	props := readZipPart(t, output, "docProps/core.xml")
+	for _, want := range []string{"<dc:creator>Reports Team</dc:creator>", "<dc:language>ja-JP</dc:language>"} {
+		if !strings.Contains(props, want) {
+			t.Errorf("core.xml missing %q:\n%s", want, props)
+		}
+	}
+
+	// strict: true from the file fails on the unresolved expression, --strict=false overrides it
+	if err := os.WriteFile(filepath.Join(project, "data", "partial.yaml"), []byte("title: Partial\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	args := []string{"generate", "-t", filepath.Join(project, "reports", "monthly.gxl"), "-d", "partial.yaml", "-o", "partial.xlsx"}
+	root = newRootWithGlobalFlags()
+	root.SilenceErrors, root.SilenceUsage = true, true
+	root.SetArgs(args)
+	if err := root.Execute(); err == nil || !strings.Contains(err.Error(), "closedAt") {
+		t.Errorf("expected an unresolved expression error, got %v", err)
+	}
+	root = newRootWithGlobalFlags()
+	root.SetArgs(append(args, "--strict=false"))
+	if err := root.Execute(); err != nil {
+		t.Errorf("--strict=false should override the project config: %v", err)
+	}
+}
+
+func TestGenerateCmd_ConfigFlag(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "ci.yaml")
+	if err := os.WriteFile(configPath, []byte("writer: {compression: fastest}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tmpl := filepath.Join(dir, "t.gxl")
+	if err := os.WriteFile(tmpl, []byte(`<Book><Sheet name="S1"><Grid>| x |</Grid></Sheet></Book>`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	root := newRootWithGlobalFlags()
+	root.SilenceErrors, root.SilenceUsage = true, true
+	root.SetArgs([]string{"--config", configPath, "generate", "-t", tmpl, "-o", filepath.Join(dir, "t.xlsx")})
+	if err := root.Execute(); err == nil || !strings.Contains(err.Error(), "unknown compression") {
+		t.Errorf("expected the --config file to be loaded and rejected, got %v", err)
+	}
+}
+
+func TestGenerateCmd_Sandbox(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	tmpl := filepath.Join(root, "t.gxl")
+	if err := os.WriteFile(tmpl, []byte(`<Book><Sheet name="S"><Grid>| {{ v }} |</Grid></Sheet></Book>`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	escape := filepath.Join(root, "escape.gxl")
+	if err := os.WriteFile(escape, []byte(`<Book><Import src="../other.gxl" sheet="S"/></Book>`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range []string{root, outside} {
+		if err := os.WriteFile(filepath.Join(dir, "data.yaml"), []byte("v: 1\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		args    []string
+		blocked string
+	}{
+		{"inside", []string{"-t", tmpl, "-d", filepath.Join(root, "data.yaml")}, ""},
+		{"data outside", []string{"-t", tmpl, "-d", filepath.Join(outside, "data.yaml")}, filepath.Join(outside, "data.yaml")},
+		{"import outside", []string{"-t", escape}, "../other.gxl"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := newRootWithGlobalFlags()
+			args := append([]string{"generate", "--sandbox", root, "--dry-run"}, tt.args...)
+			cmd.SetArgs(args)
+			err := cmd.Execute()
+			if tt.blocked == "" {
+				if err != nil {
+					t.Fatalf("Execute failed: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), "sandbox: access to \""+tt.blocked+"\" blocked") {
+				t.Errorf("err = %v, want the sandbox to block %q", err, tt.blocked)
+			}
+		})
+	}
+}
+
+func TestGenerateCmd_SandboxIgnoresTemplateConfig(t *testing.T) {
+	caller := t.TempDir()
+	reports := filepath.Join(caller, "reports")
+	if err := os.MkdirAll(reports, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for path, content := range map[string]string{
+		filepath.Join(caller, "secret.gxl"):    `<Book><Sheet name="S"><Grid>| secret |</Grid></Sheet></Book>`,
+		filepath.Join(reports, "escape.gxl"):   `<Book><Import src="../secret.gxl" sheet="S"/></Book>`,
+		filepath.Join(reports, ".goxcel.yaml"): "sandbox: .\n",
+	} {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(caller); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	run := func() error {
+		cmd := newRootWithGlobalFlags()
+		cmd.SetArgs([]string{"generate", "--dry-run", "-t", filepath.Join("reports", "escape.gxl")})
+		return cmd.Execute()
+	}
+
+	// A template cannot choose its own sandbox
+	if err := run(); err != nil {
+		t.Fatalf("Execute without a caller sandbox: %v", err)
+	}
+
+	// The template's .goxcel.yaml cannot lift the sandbox set by the caller's configuration
+	for path, content := range map[string]string{
+		filepath.Join(caller, ".goxcel.yaml"):  "sandbox: reports\n",
+		filepath.Join(reports, ".goxcel.yaml"): "sandbox: /\n",
+	} {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = run()
+	if err == nil || !strings.Contains(err.Error(), `sandbox: access to "../secret.gxl" blocked`) {
+		t.Errorf("err = %v, want the caller's sandbox to block the import", err)
+	}
+}
+
+func TestGenerateCmd_TemplateConfigOnlyLowersLimits(t *testing.T) {
+	caller := t.TempDir()
+	reports := filepath.Join(caller, "reports")
+	if err := os.MkdirAll(reports, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(reports, "cells.gxl"), []byte(`<Book><Sheet name="S"><Grid>| a | b | c |</Grid></Sheet></Book>`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(caller); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	run := func(callerConfig, templateConfig string) error {
+		for path, content := range map[string]string{
+			filepath.Join(caller, ".goxcel.yaml"):  callerConfig,
+			filepath.Join(reports, ".goxcel.yaml"): templateConfig,
+		} {
+			if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		cmd := newRootWithGlobalFlags()
+		cmd.SetArgs([]string{"generate", "--dry-run", "-t", filepath.Join("reports", "cells.gxl")})
+		return cmd.Execute()
+	}
+
+	// The template's .goxcel.yaml cannot raise the caller's limit
+	err = run("limits:\n  max_cells: 2\n", "limits:\n  max_cells: 1000\n")
+	if err == nil || !strings.Contains(err.Error(), "cell limit exceeded (max 2)") {
+		t.Errorf("err = %v, want the caller's cell limit", err)
+	}
+
+	// It can lower it, also where the caller sets no limit
+	err = run("{}\n", "limits:\n  max_cells: 1\n")
+	if err == nil || !strings.Contains(err.Error(), "cell limit exceeded (max 1)") {
+		t.Errorf("err = %v, want the template's lower cell limit", err)
+	}
+	if err := run("limits:\n  max_cells: 10\n", "{}\n"); err != nil {
+		t.Errorf("Execute within the limits: %v", err)
+	}
+}
+
+func TestGlobalLogFlags(t *testing.T) {
+	dir := t.TempDir()
+	tmpl := filepath.Join(dir, "t.gxl")
+	if err := os.WriteFile(tmpl, []byte(`<Book><Sheet name="S1"><Grid>| x |</Grid></Sheet></Book>`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	logFile := filepath.Join(dir, "goxcel.log")
+
+	newRoot := func() *cobra.Command {
+		root := &cobra.Command{Use: "goxcel", SilenceErrors: true, SilenceUsage: true}
+		controller.AddGlobalFlags(root)
+		root.AddCommand(controller.InitFormatCmd())
+		return root
+	}
+
+	// Logs never mix with the formatted template on stdout
+	var out strings.Builder
+	root := newRoot()
+	root.SetOut(&out)
+	root.SetArgs([]string{"--log-file", logFile, "--log-format", "json", "--log-level", "debug", "format", tmpl})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if !strings.HasPrefix(out.String(), "<?xml") || strings.Contains(out.String(), "goxcel/") {
+		t.Errorf("stdout is not just the formatted template:\n%s", out.String())
+	}
+	logged, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(logged), `"level":"DEBUG"`) || !strings.Contains(string(logged), `"component":"goxcel"`) {
+		t.Errorf("expected JSON debug entries in the log file:\n%s", logged)
+	}
+
+	// --quiet discards everything
+	if err := os.Remove(logFile); err != nil {
+		t.Fatal(err)
+	}
+	root = newRoot()
+	root.SetOut(&strings.Builder{})
+	root.SetArgs([]string{"--log-file", logFile, "--quiet", "format", tmpl})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if _, err := os.Stat(logFile); !os.IsNotExist(err) {
+		t.Errorf("--quiet should not write a log file (stat err = %v)", err)
+	}
+
+	root = newRoot()
+	root.SetArgs([]string{"--log-level", "verbose", "format", tmpl})
+	if err := root.Execute(); err == nil || !strings.Contains(err.Error(), "--log-level") {
+		t.Errorf("expected an invalid --log-level error, got %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package controller_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ryo-arima/goxcel/pkg/controller"
+	parser "github.com/ryo-arima/goxcel/pkg/repository"
+)
+
+func TestConvertCmd_RoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	tmpl := filepath.Join(dir, "report.gxl")
+	if err := os.WriteFile(tmpl, []byte(`<Book><Sheet name="Report">
+  <Column index="1" width="20" />
+  <Grid font_size="14" fill_color="#DDEEFF" bold="true" h_align="center">
+    | Name | Amount |
+  </Grid>
+  <Grid number_format="#,##0.00">
+    | Widget | 1234.5 |
+    | Gadget | =B2*2  |
+  </Grid>
+  <Merge range="A4:B4" />
+</Sheet></Book>`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	original := filepath.Join(dir, "report.xlsx")
+	if err := controller.RunGenerate(tmpl, "", original, false); err != nil {
+		t.Fatalf("RunGenerate: %v", err)
+	}
+
+	converted := filepath.Join(dir, "converted.gxl")
+	cmd := controller.InitConvertCmd()
+	cmd.SetArgs([]string{original, "-o", converted})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("convert: %v", err)
+	}
+	regenerated := filepath.Join(dir, "regenerated.xlsx")
+	if err := controller.RunGenerate(converted, "", regenerated, false); err != nil {
+		t.Fatalf("RunGenerate converted: %v", err)
+	}
+
+	want, err := parser.ReadBookFromFile(original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := parser.ReadBookFromFile(regenerated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("regenerated workbook differs (-want +got):\n%s", diff)
+	}
+
+	// Without --output the template goes to stdout
+	var out bytes.Buffer
+	cmd = controller.InitConvertCmd()
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{original})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("convert to stdout: %v", err)
+	}
+	if !strings.Contains(out.String(), `<Sheet name="Report">`) {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+}
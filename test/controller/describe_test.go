@@ -0,0 +1,41 @@
+package controller_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ryo-arima/goxcel/pkg/controller"
+)
+
+func TestInitDescribeCmd_Formats(t *testing.T) {
+	in := filepath.Join("..", ".testdata", "params_invoice.gxl")
+
+	cmd := controller.InitDescribeCmd()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{in})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if !strings.Contains(out.String(), "invoice.number  string  yes") {
+		t.Errorf("unexpected table:\n%s", out.String())
+	}
+
+	cmd = controller.InitDescribeCmd()
+	out.Reset()
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"--format", "json", in})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	var schema map[string]any
+	if err := json.Unmarshal(out.Bytes(), &schema); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, out.String())
+	}
+	if schema["$schema"] != "https://json-schema.org/draft/2020-12/schema" {
+		t.Errorf("$schema = %v", schema["$schema"])
+	}
+}
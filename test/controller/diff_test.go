@@ -0,0 +1,103 @@
+package controller_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ryo-arima/goxcel/pkg/controller"
+)
+
+// runDiff runs the diff command and returns its output and exit code
+func runDiff(t *testing.T, args ...string) (string, int) {
+	t.Helper()
+	var out bytes.Buffer
+	cmd := controller.InitDiffCmd()
+	cmd.SetOut(&out)
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs(args)
+	err := cmd.Execute()
+	if err == nil {
+		return out.String(), 0
+	}
+	var exitErr *controller.ExitError
+	if !errors.As(err, &exitErr) {
+		t.Fatalf("diff returned %v, want an ExitError", err)
+	}
+	return out.String(), exitErr.Code
+}
+
+func TestDiffCmd(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	oldTmpl := write("old.gxl", `<Book><Sheet name="Report">
+  <Grid>
+    | Total | {{ total }} |
+  </Grid>
+</Sheet></Book>`)
+	newTmpl := write("new.gxl", `<Book><Sheet name="Report">
+  <Grid bold="true">
+    | Total | {{ total }} |
+  </Grid>
+</Sheet></Book>`)
+	data := write("data.json", `{"total": 120}`)
+	other := write("other.json", `{"total": 150}`)
+	oldXlsx := filepath.Join(dir, "old.xlsx")
+	if err := controller.RunGenerate(oldTmpl, data, oldXlsx, false); err != nil {
+		t.Fatalf("RunGenerate: %v", err)
+	}
+
+	// A template and the workbook it generated are the same
+	out, code := runDiff(t, oldXlsx, oldTmpl, "--data", data)
+	if code != controller.DiffExitSame || !strings.Contains(out, "are the same") {
+		t.Errorf("same: exit %d, output:\n%s", code, out)
+	}
+
+	// A template change shows as style changes
+	out, code = runDiff(t, oldTmpl, newTmpl, "-d", data)
+	if code != controller.DiffExitChanged {
+		t.Errorf("template change: exit %d, want %d", code, controller.DiffExitChanged)
+	}
+	for _, line := range []string{"--- " + oldTmpl, `+ Report!A1 style.bold: "true"`, `+ Report!B1 style.bold: "true"`, "2 difference(s)"} {
+		if !strings.Contains(out, line) {
+			t.Errorf("output does not contain %q:\n%s", line, out)
+		}
+	}
+
+	// Different data for each side, as JSON
+	out, code = runDiff(t, oldTmpl, oldTmpl, "--data-a", data, "--data-b", other, "--format", "json")
+	if code != controller.DiffExitChanged {
+		t.Errorf("data change: exit %d, want %d", code, controller.DiffExitChanged)
+	}
+	var report struct {
+		Equal   bool
+		Changes []map[string]string
+	}
+	if err := json.Unmarshal([]byte(out), &report); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, out)
+	}
+	if report.Equal || len(report.Changes) != 1 || report.Changes[0]["ref"] != "B1" || report.Changes[0]["b"] != "150" {
+		t.Errorf("unexpected report:\n%s", out)
+	}
+
+	// Errors exit with 2
+	if _, code := runDiff(t, oldXlsx, filepath.Join(dir, "missing.xlsx")); code != controller.DiffExitError {
+		t.Errorf("missing file: exit %d, want %d", code, controller.DiffExitError)
+	}
+	if _, code := runDiff(t, oldXlsx, oldTmpl, "--data-a", data); code != controller.DiffExitError {
+		t.Errorf("data for a workbook: exit %d, want %d", code, controller.DiffExitError)
+	}
+	if _, code := runDiff(t, oldXlsx, oldXlsx, "--format", "xml"); code != controller.DiffExitError {
+		t.Errorf("unknown format: exit %d, want %d", code, controller.DiffExitError)
+	}
+}
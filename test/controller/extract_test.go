@@ -0,0 +1,96 @@
+package controller_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ryo-arima/goxcel/pkg/controller"
+	"gopkg.in/yaml.v3"
+)
+
+func TestExtractCmd(t *testing.T) {
+	dir := t.TempDir()
+	tmpl := filepath.Join(dir, "form.gxl")
+	if err := os.WriteFile(tmpl, []byte(`<Book><Sheet name="Form">
+  <Grid>
+    | Name | {{ name }} |
+  </Grid>
+  <For each="item in items">
+    <Grid>
+      | {{ loop.number }} | {{ item.title }} | {{ item.qty }} |
+    </Grid>
+  </For>
+</Sheet></Book>`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	data := filepath.Join(dir, "data.json")
+	if err := os.WriteFile(data, []byte(`{"name": "Ann", "items": [{"title": "Bolt", "qty": 100}, {"title": "Nut", "qty": 2.5}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	filled := filepath.Join(dir, "filled.xlsx")
+	if err := controller.RunGenerate(tmpl, data, filled, false); err != nil {
+		t.Fatalf("RunGenerate: %v", err)
+	}
+	want := map[string]any{
+		"name": "Ann",
+		"items": []any{
+			map[string]any{"title": "Bolt", "qty": float64(100)},
+			map[string]any{"title": "Nut", "qty": 2.5},
+		},
+	}
+
+	// JSON to stdout by default
+	var out bytes.Buffer
+	cmd := controller.InitExtractCmd()
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"--template", tmpl, filled})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, out.String())
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("JSON output mismatch (-want +got):\n%s", diff)
+	}
+
+	// YAML when the output file says so
+	output := filepath.Join(dir, "data.yaml")
+	cmd = controller.InitExtractCmd()
+	cmd.SetArgs([]string{"-t", tmpl, "-o", output, filled})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("extract to file: %v", err)
+	}
+	b, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = nil
+	if err := yaml.Unmarshal(b, &got); err != nil {
+		t.Fatalf("output is not YAML: %v\n%s", err, b)
+	}
+	want["items"].([]any)[0].(map[string]any)["qty"] = 100 // YAML reads whole numbers as int
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("YAML output mismatch (-want +got):\n%s", diff)
+	}
+
+	cmd = controller.InitExtractCmd()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{filled})
+	if err := cmd.Execute(); err == nil {
+		t.Error("expected an error without --template")
+	}
+	cmd = controller.InitExtractCmd()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"-t", tmpl, "-f", "xml", filled})
+	if err := cmd.Execute(); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
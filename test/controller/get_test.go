@@ -0,0 +1,118 @@
+package controller_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ryo-arima/goxcel/pkg/controller"
+)
+
+func TestGetTemplates_ListAndCopy(t *testing.T) {
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+	userTemplate := filepath.Join(configHome, "goxcel", "templates", "invoice")
+	if err := os.MkdirAll(userTemplate, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(userTemplate, "base.gxl"), []byte(`<Book><Sheet name="Mine"><Grid>| {{ who }} |</Grid></Sheet></Book>`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, content := range map[string]string{
+		"template.yaml":  "description: Our invoice\nfiles: [logo.png, parts]\n",
+		"logo.png":       "png",
+		"parts/item.gxl": "<Book/>",
+	} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(userTemplate, name)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(userTemplate, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var out bytes.Buffer
+	cmd := controller.InitGetCmd()
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"templates"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	for _, want := range []string{"NAME", "DESCRIPTION", "Our invoice", "Titled table of name, value and description rows"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("listing missing %q:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	cmd = controller.InitGetCmd()
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"templates", "--json"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	var listed []struct {
+		Name     string `json:"name"`
+		Source   string `json:"source"`
+		Manifest struct {
+			Version string `json:"version"`
+		} `json:"manifest"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &listed); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, out.String())
+	}
+	if len(listed) != 3 || listed[2].Name != "simple" || listed[2].Manifest.Version != "1.0.0" {
+		t.Errorf("unexpected JSON listing: %+v", listed)
+	}
+
+	dir := t.TempDir()
+	out.Reset()
+	cmd = controller.InitGetCmd()
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"templates", "simple", "-o", dir})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	for _, name := range []string{"base.gxl", "base.yaml", "template.yaml"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s not copied: %v", name, err)
+		}
+	}
+
+	// Every file listed in the manifest is copied
+	dir = t.TempDir()
+	cmd = controller.InitGetCmd()
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"templates", "invoice", "-o", dir})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	for _, name := range []string{"base.gxl", "template.yaml", "logo.png", filepath.Join("parts", "item.gxl")} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s not copied: %v", name, err)
+		}
+	}
+}
+
+func TestGenerateCmd_TemplateName(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	dir := t.TempDir()
+
+	// Built-in templates render from any directory with their sample data
+	outputPath := filepath.Join(dir, "simple.xlsx")
+	if err := controller.RunGenerateWithOptions(controller.GenerateOptions{TemplateName: "simple", OutputPath: outputPath}); err != nil {
+		t.Fatalf("RunGenerateWithOptions: %v", err)
+	}
+	if _, err := os.Stat(outputPath); err != nil {
+		t.Errorf("output file not created: %v", err)
+	}
+
+	err := controller.RunGenerateWithOptions(controller.GenerateOptions{TemplateName: "no-such-template", DryRun: true})
+	if err == nil || !strings.Contains(err.Error(), `template "no-such-template" not found`) {
+		t.Errorf("err = %v, want not found error", err)
+	}
+}
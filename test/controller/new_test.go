@@ -0,0 +1,122 @@
+package controller_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ryo-arima/goxcel/pkg/controller"
+)
+
+func TestNewCmd_FromTemplate(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	dir := filepath.Join(t.TempDir(), "q3-report")
+
+	var out bytes.Buffer
+	cmd := controller.InitNewCmd()
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{dir, "--from", "invoice"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	for _, name := range []string{"template.yaml", "base.gxl", "base.yaml", "README.md", "Makefile"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("expected %s: %v", name, err)
+		}
+	}
+
+	makefile, err := os.ReadFile(filepath.Join(dir, "Makefile"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"TEMPLATE := base.gxl", "DATA := base.yaml", "OUTPUT := q3-report.xlsx"} {
+		if !strings.Contains(string(makefile), want) {
+			t.Errorf("Makefile missing %q:\n%s", want, makefile)
+		}
+	}
+
+	readme, err := os.ReadFile(filepath.Join(dir, "README.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(readme), "# q3-report") || !strings.Contains(string(readme), "`invoice`") {
+		t.Errorf("unexpected README:\n%s", readme)
+	}
+	if !strings.Contains(out.String(), "Next: cd "+dir+" && make") {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+}
+
+func TestNewCmd_Errors(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	notEmpty := t.TempDir()
+	if err := os.WriteFile(filepath.Join(notEmpty, "keep.txt"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"not empty", []string{notEmpty}, "not empty"},
+		{"unknown template", []string{filepath.Join(t.TempDir(), "p"), "--from", "nope"}, `template "nope" not found`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := controller.InitNewCmd()
+			cmd.SetOut(&bytes.Buffer{})
+			cmd.SetErr(&bytes.Buffer{})
+			cmd.SetArgs(tt.args)
+			err := cmd.Execute()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestNewCmd_KeepsTemplateReadme(t *testing.T) {
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+	userTemplate := filepath.Join(configHome, "goxcel", "templates", "report")
+	if err := os.MkdirAll(userTemplate, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{
+		"template.yaml": "files: [README.md]\n",
+		"base.gxl":      `<Book><Sheet name="S"><Grid>| x |</Grid></Sheet></Book>`,
+		"README.md":     "# Our report\n",
+	} {
+		if err := os.WriteFile(filepath.Join(userTemplate, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dir := filepath.Join(t.TempDir(), "q3")
+	var out bytes.Buffer
+	cmd := controller.InitNewCmd()
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{dir, "--from", "report"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	readme, err := os.ReadFile(filepath.Join(dir, "README.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(readme) != "# Our report\n" {
+		t.Errorf("README.md was overwritten:\n%s", readme)
+	}
+	if !strings.Contains(out.String(), "Kept: "+filepath.Join(dir, "README.md")) {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+	if _, err := os.Stat(filepath.Join(dir, "Makefile")); err != nil {
+		t.Errorf("expected a generated Makefile: %v", err)
+	}
+}
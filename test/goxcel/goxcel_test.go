@@ -0,0 +1,265 @@
+package goxcel_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ryo-arima/goxcel"
+)
+
+func sheetValues(s *goxcel.Sheet) map[string]string {
+	values := map[string]string{}
+	for _, c := range s.Cells {
+		values[c.Ref] = c.Value
+	}
+	return values
+}
+
+func TestRender_WritesXLSX(t *testing.T) {
+	tmpl := `<Book name="Facade"><Sheet name="S1"><Grid>| {{ customer.name }} | {{ total }} |</Grid></Sheet></Book>`
+	data := struct {
+		Customer struct {
+			Name string `json:"name"`
+		} `json:"customer"`
+		Total int `json:"total"`
+	}{Total: 42}
+	data.Customer.Name = "Acme"
+
+	var buf bytes.Buffer
+	if err := goxcel.Render(context.Background(), strings.NewReader(tmpl), data, &buf); err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if _, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len())); err != nil {
+		t.Fatalf("output is not an xlsx package: %v", err)
+	}
+
+	book, err := goxcel.RenderBook(context.Background(), strings.NewReader(tmpl), data)
+	if err != nil {
+		t.Fatalf("RenderBook: %v", err)
+	}
+	want := map[string]string{"A1": "Acme", "B1": "42"}
+	if diff := cmp.Diff(want, sheetValues(book.Sheets[0])); diff != "" {
+		t.Errorf("cells mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRender_BaseDir(t *testing.T) {
+	dir := filepath.Join("..", ".testdata")
+	f, err := os.Open(filepath.Join(dir, "extends_sales.gxl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	data := map[string]any{"title": "Q1", "rows": []any{map[string]any{"region": "East", "amount": 10}}}
+	if _, err := goxcel.RenderBook(context.Background(), f, data, goxcel.WithBaseDir(dir)); err != nil {
+		t.Fatalf("RenderBook: %v", err)
+	}
+}
+
+func TestRender_Strict(t *testing.T) {
+	tmpl := `<Book><Sheet name="{{ sheet }}"><Grid>| {{ name }} | {{ missing.value }} | {{ missing.value }} |</Grid></Sheet></Book>`
+	data := map[string]any{"name": "ok"}
+
+	// Without strict mode unresolved expressions render empty
+	if _, err := goxcel.RenderBook(context.Background(), strings.NewReader(tmpl), map[string]any{"name": "ok", "sheet": "S"}); err != nil {
+		t.Fatalf("RenderBook: %v", err)
+	}
+
+	_, err := goxcel.RenderBook(context.Background(), strings.NewReader(tmpl), data, goxcel.WithStrict(true), goxcel.WithSheetNamePolicy("suffix"))
+	var unresolved *goxcel.UnresolvedError
+	if !errors.As(err, &unresolved) {
+		t.Fatalf("err = %v, want *UnresolvedError", err)
+	}
+	if diff := cmp.Diff([]string{"sheet", "missing.value"}, unresolved.Paths); diff != "" {
+		t.Errorf("paths mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRender_Limits(t *testing.T) {
+	dir := filepath.Join("..", ".testdata")
+	extends, err := os.ReadFile(filepath.Join(dir, "extends_sales_team.gxl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = goxcel.RenderBook(context.Background(), bytes.NewReader(extends), nil,
+		goxcel.WithBaseDir(dir), goxcel.WithLimits(goxcel.Limits{MaxImportDepth: 1}))
+	if err == nil || !strings.Contains(err.Error(), "import depth limit exceeded (max 1)") {
+		t.Errorf("err = %v, want import depth error", err)
+	}
+
+	loop := `<Book><Sheet name="S"><For each="r in rows"><Grid>| {{ r }} | x |</Grid></For></Sheet></Book>`
+	rows := map[string]any{"rows": []any{1, 2, 3, 4, 5}}
+
+	tests := []struct {
+		name   string
+		tmpl   []byte
+		data   any
+		limits goxcel.Limits
+		want   goxcel.LimitError
+	}{
+		{"cells", []byte(loop), rows, goxcel.Limits{MaxCells: 9}, goxcel.LimitError{Limit: "cell", Max: 9}},
+		{"rows", []byte(loop), rows, goxcel.Limits{MaxRows: 4}, goxcel.LimitError{Limit: "row", Max: 4}},
+		{"loop iterations", []byte(loop), rows, goxcel.Limits{MaxLoopIterations: 3}, goxcel.LimitError{Limit: "loop iteration", Max: 3}},
+		{"import files", extends, nil, goxcel.Limits{MaxImportFiles: 1}, goxcel.LimitError{Limit: "import file", Max: 1}},
+		{"output bytes", []byte(loop), rows, goxcel.Limits{MaxOutputBytes: 512}, goxcel.LimitError{Limit: "output size", Max: 512}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := goxcel.Render(context.Background(), bytes.NewReader(tt.tmpl), tt.data, &buf,
+				goxcel.WithBaseDir(dir), goxcel.WithLimits(tt.limits))
+			var limitErr *goxcel.LimitError
+			if !errors.As(err, &limitErr) {
+				t.Fatalf("err = %v, want *LimitError", err)
+			}
+			if diff := cmp.Diff(tt.want, *limitErr); diff != "" {
+				t.Errorf("limit error mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	// The same template renders within generous limits
+	generous := goxcel.Limits{MaxCells: 10, MaxRows: 5, MaxLoopIterations: 5, MaxOutputBytes: 1 << 20}
+	if err := goxcel.Render(context.Background(), strings.NewReader(loop), rows, &bytes.Buffer{}, goxcel.WithLimits(generous)); err != nil {
+		t.Errorf("Render within limits: %v", err)
+	}
+}
+
+func TestRender_Cancellation(t *testing.T) {
+	tmpl := `<Book><Sheet name="S"><For each="r in rows"><For each="c in rows"></For></For></Sheet></Book>`
+	items := make([]any, 3000)
+	data := map[string]any{"rows": items}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := goxcel.RenderBook(ctx, strings.NewReader(tmpl), data)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+
+	// Nine million iterations do not finish within a millisecond
+	_, err = goxcel.RenderBook(context.Background(), strings.NewReader(tmpl), data, goxcel.WithLimits(goxcel.Limits{Timeout: time.Millisecond}))
+	var limitErr *goxcel.LimitError
+	if !errors.As(err, &limitErr) || limitErr.Limit != "time" {
+		t.Fatalf("err = %v, want a time limit error", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("errors.Is(%v, context.DeadlineExceeded) = false", err)
+	}
+}
+
+func TestRender_Sandbox(t *testing.T) {
+	root := t.TempDir()
+	part := `<Book><Sheet name="Part"><Grid>| part |</Grid></Sheet></Book>`
+	if err := os.WriteFile(filepath.Join(root, "part.gxl"), []byte(part), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		tmpl    string
+		blocked string
+	}{
+		{"import inside", `<Book><Import src="part.gxl" sheet="Part"/><Sheet name="S"><Grid>| a |</Grid></Sheet></Book>`, ""},
+		{"image inside", `<Book><Sheet name="S"><Image ref="A1" src="logo.png"/></Sheet></Book>`, ""},
+		{"import escape", `<Book><Import src="../part.gxl" sheet="Part"/><Sheet name="S"><Grid>| a |</Grid></Sheet></Book>`, "../part.gxl"},
+		{"include escape", `<Book><Sheet name="S"><Include src="/etc/passwd" fragment="F"/></Sheet></Book>`, "/etc/passwd"},
+		{"image escape", `<Book><Sheet name="S"><Image ref="A1" src="../../logo.png"/></Sheet></Book>`, "../../logo.png"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := goxcel.RenderBook(context.Background(), strings.NewReader(tt.tmpl), nil,
+				goxcel.WithBaseDir(root), goxcel.WithSandbox(root))
+			if tt.blocked == "" {
+				if err != nil {
+					t.Fatalf("RenderBook: %v", err)
+				}
+				return
+			}
+			var sbErr *goxcel.SandboxError
+			if !errors.As(err, &sbErr) {
+				t.Fatalf("err = %v, want *SandboxError", err)
+			}
+			if sbErr.Path != tt.blocked {
+				t.Errorf("blocked path = %q, want %q", sbErr.Path, tt.blocked)
+			}
+		})
+	}
+}
+
+func TestRender_InvalidInput(t *testing.T) {
+	_, err := goxcel.RenderBook(context.Background(), strings.NewReader(`<Book><Sheet name="S">`), nil)
+	if err == nil || !strings.Contains(err.Error(), "parse template") {
+		t.Errorf("err = %v, want parse error", err)
+	}
+
+	_, err = goxcel.RenderBook(context.Background(), strings.NewReader(`<Book/>`), []string{"not", "an", "object"})
+	if err == nil || !strings.Contains(err.Error(), "JSON object") {
+		t.Errorf("err = %v, want data error", err)
+	}
+}
+
+func TestRenderFS(t *testing.T) {
+	fsys := os.DirFS(filepath.Join("..", ".testdata"))
+	data := map[string]any{"title": "Q1", "rows": []any{map[string]any{"region": "East", "amount": 10}}}
+
+	var buf bytes.Buffer
+	if err := goxcel.RenderFS(context.Background(), fsys, "extends_sales_team.gxl", data, &buf); err != nil {
+		t.Fatalf("RenderFS: %v", err)
+	}
+	if buf.Len() == 0 {
+		t.Errorf("no output written")
+	}
+
+	if err := goxcel.RenderFS(context.Background(), fsys, "missing.gxl", data, &buf); err == nil {
+		t.Errorf("expected an error for a missing template")
+	}
+}
+
+// recordingLogger is a Logger written against the goxcel package only
+type recordingLogger struct {
+	codes []goxcel.LogCode
+}
+
+func (l *recordingLogger) record(code goxcel.LogCode) { l.codes = append(l.codes, code) }
+
+func (l *recordingLogger) DEBUG(code goxcel.LogCode, _ string, _ ...map[string]interface{}) {
+	l.record(code)
+}
+func (l *recordingLogger) INFO(code goxcel.LogCode, _ string, _ ...map[string]interface{}) {
+	l.record(code)
+}
+func (l *recordingLogger) WARN(code goxcel.LogCode, _ string, _ ...map[string]interface{}) {
+	l.record(code)
+}
+func (l *recordingLogger) ERROR(code goxcel.LogCode, _ string, _ ...map[string]interface{}) {
+	l.record(code)
+}
+func (l *recordingLogger) FATAL(code goxcel.LogCode, _ string, _ ...map[string]interface{}) {
+	l.record(code)
+}
+
+// TestWithLogger tests that a Logger implemented with the re-exported types receives entries
+func TestWithLogger(t *testing.T) {
+	logger := &recordingLogger{}
+	book, err := goxcel.RenderBook(context.Background(), strings.NewReader(`<Book><Sheet name="S"><Grid>| x |</Grid></Sheet></Book>`), nil, goxcel.WithLogger(logger))
+	if err != nil {
+		t.Fatalf("RenderBook: %v", err)
+	}
+	if len(book.Sheets) != 1 {
+		t.Fatalf("sheets = %d, want 1", len(book.Sheets))
+	}
+	if len(logger.codes) == 0 {
+		t.Error("logger received no entries")
+	}
+}
@@ -3,6 +3,7 @@ package command_test
 import (
 	"os"
 	"os/exec"
+	"path/filepath"
 	"testing"
 
 	command "github.com/ryo-arima/goxcel/pkg"
@@ -35,3 +36,35 @@ func TestExecute_Error_Exits(t *testing.T) {
 		}
 	}
 }
+
+// TestExecute_ExitCode ensures Execute exits with the code a command chooses: diff exits 1
+// when the workbooks differ and 2 on errors
+func TestExecute_ExitCode(t *testing.T) {
+	if args := os.Getenv("WANT_EXECUTE_DIFF"); args != "" {
+		os.Args = append([]string{"goxcel", "diff", "--quiet"}, filepath.SplitList(args)...)
+		command.Execute()
+		return
+	}
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"a.gxl": `<Book><Sheet name="S"><Grid>| a |</Grid></Sheet></Book>`,
+		"b.gxl": `<Book><Sheet name="S"><Grid>| b |</Grid></Sheet></Book>`,
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for b, want := range map[string]int{"b.gxl": 1, "missing.xlsx": 2} {
+		cmd := exec.Command(os.Args[0], "-test.run=TestExecute_ExitCode")
+		args := filepath.Join(dir, "a.gxl") + string(filepath.ListSeparator) + filepath.Join(dir, b)
+		cmd.Env = append(os.Environ(), "WANT_EXECUTE_DIFF="+args)
+		err := cmd.Run()
+		ee, ok := err.(*exec.ExitError)
+		if !ok {
+			t.Fatalf("diff with %s: expected a non-zero exit from Execute, got %v", b, err)
+		}
+		if code := ee.ExitCode(); code != want {
+			t.Errorf("diff with %s: expected exit code %d, got %d", b, want, code)
+		}
+	}
+}
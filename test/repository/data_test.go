@@ -0,0 +1,77 @@
+package parser_test
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	parser "github.com/ryo-arima/goxcel/pkg/repository"
+	"golang.org/x/text/encoding/japanese"
+)
+
+func TestReadDataFromFile_CSV(t *testing.T) {
+	data, err := parser.ReadDataFromFile(filepath.Join("..", ".testdata", "sales.csv"), parser.DataOptions{})
+	if err != nil {
+		t.Fatalf("ReadDataFromFile: %v", err)
+	}
+	want := map[string]any{"rows": []any{
+		map[string]any{"region": "East", "amount": int64(1200), "date": "2026-01-31", "code": "007", "active": true},
+		map[string]any{"region": "West", "amount": 99.5, "date": "2026-02-01", "code": "010", "active": false},
+	}}
+	if diff := cmp.Diff(want, data); diff != "" {
+		t.Fatalf("data mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestReadData_TSVWithKeyAndNoInfer(t *testing.T) {
+	in := "name\tqty\nA \"x\"\t3\nB\n"
+	data, err := parser.ReadData(strings.NewReader(in), parser.DataOptions{Format: parser.DataFormatTSV, Key: "items", NoInfer: true})
+	if err != nil {
+		t.Fatalf("ReadData: %v", err)
+	}
+	want := map[string]any{"items": []any{
+		map[string]any{"name": `A "x"`, "qty": "3"},
+		map[string]any{"name": "B", "qty": ""},
+	}}
+	if diff := cmp.Diff(want, data); diff != "" {
+		t.Fatalf("data mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestReadData_ShiftJISAndDelimiter(t *testing.T) {
+	var buf bytes.Buffer
+	w := japanese.ShiftJIS.NewEncoder().Writer(&buf)
+	if _, err := w.Write([]byte("支店;売上\n東京;100\n")); err != nil {
+		t.Fatal(err)
+	}
+	data, err := parser.ReadData(&buf, parser.DataOptions{Format: parser.DataFormatCSV, Delimiter: ';', Encoding: "Shift_JIS"})
+	if err != nil {
+		t.Fatalf("ReadData: %v", err)
+	}
+	want := map[string]any{"rows": []any{map[string]any{"支店": "東京", "売上": int64(100)}}}
+	if diff := cmp.Diff(want, data); diff != "" {
+		t.Fatalf("data mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestReadData_Errors(t *testing.T) {
+	for _, tc := range []struct {
+		in      string
+		opts    parser.DataOptions
+		wantErr string
+	}{
+		{in: "", opts: parser.DataOptions{Format: parser.DataFormatCSV}, wantErr: "missing header row"},
+		{in: "a,,c\n", opts: parser.DataOptions{Format: parser.DataFormatCSV}, wantErr: "column 2 has an empty header"},
+		{in: "id,name, id\n1,a,2\n", opts: parser.DataOptions{Format: parser.DataFormatCSV}, wantErr: `columns 1 and 3 have the same header "id"`},
+		{in: "a,b\n1,2,3\n", opts: parser.DataOptions{Format: parser.DataFormatCSV}, wantErr: "line 2 has 3 fields"},
+		{in: "a\n1\n", opts: parser.DataOptions{Format: parser.DataFormatCSV, Encoding: "latin1"}, wantErr: "unsupported data encoding"},
+		{in: "{", opts: parser.DataOptions{Format: parser.DataFormatJSON}, wantErr: "parse data json"},
+	} {
+		_, err := parser.ReadData(strings.NewReader(tc.in), tc.opts)
+		if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+			t.Errorf("ReadData(%q) err = %v, want containing %q", tc.in, err, tc.wantErr)
+		}
+	}
+}
@@ -1,7 +1,9 @@
 package parser_test
 
 import (
+	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -319,3 +321,272 @@ func TestParse_InvalidImportInSheet(t *testing.T) {
 	t.Log("Import tags inside Sheet tags are rejected by parser with: invalid nesting error")
 	_ = invalidGxl
 }
+
+func TestParse_BookLevelForAndIf(t *testing.T) {
+	path := filepath.Join("..", ".testdata", "book_for.gxl")
+	lg := util.NewLogger(util.LoggerConfig{Component: "test", Service: "repo", Level: "ERROR", Output: "stdout"})
+	gxl, err := parser.ReadGxlFromFile(path, lg)
+	if err != nil {
+		t.Fatalf("ReadGxlFromFile: %v", err)
+	}
+
+	// Only top-level sheets are reported in the legacy list
+	if len(gxl.Sheets) != 1 || gxl.Sheets[0].Name != "Summary" {
+		t.Fatalf("top-level sheets = %+v, want only Summary", gxl.Sheets)
+	}
+
+	var types []model.BookNodeType
+	for _, n := range gxl.BookNodes {
+		types = append(types, n.Type)
+	}
+	want := []model.BookNodeType{model.BookNodeTypeSheet, model.BookNodeTypeFor, model.BookNodeTypeIf}
+	if diff := cmp.Diff(want, types); diff != "" {
+		t.Fatalf("book node types mismatch (-want +got):\n%s", diff)
+	}
+
+	forTag := gxl.BookNodes[1].For
+	if forTag.Each != "r in regions" || len(forTag.Body) != 1 || forTag.Body[0].Sheet.Name != "{{ r.name }}" {
+		t.Fatalf("unexpected For tag: %+v", forTag)
+	}
+	ifTag := gxl.BookNodes[2].If
+	if ifTag.Cond != "showNotes" || len(ifTag.Then) != 1 || len(ifTag.Else) != 1 {
+		t.Fatalf("unexpected If tag: %+v", ifTag)
+	}
+}
+
+func TestParse_ImportTag_AsAndData(t *testing.T) {
+	lg := util.NewLogger(util.LoggerConfig{Component: "test", Service: "repo", Level: "ERROR", Output: "stdout"})
+
+	gxl, err := parser.ReadGxlFromFile(filepath.Join("..", ".testdata", "import_scoped.gxl"), lg)
+	if err != nil {
+		t.Fatalf("ReadGxlFromFile: %v", err)
+	}
+	want := []model.ImportTag{
+		{Src: "./import_quarter.gxl", Sheet: "Summary", As: "Q1 Summary", Data: "quarters.q1"},
+		{Src: "./import_quarter.gxl", Sheet: "Summary", As: "{{ quarters.q2.label }} Summary", Data: "quarters.q2"},
+		{Src: "./import_common.gxl", Sheet: model.ImportAllSheets},
+	}
+	if diff := cmp.Diff(want, gxl.Imports); diff != "" {
+		t.Fatalf("imports mismatch (-want +got):\n%s", diff)
+	}
+
+	// 'as' cannot rename several sheets at once
+	path := filepath.Join(t.TempDir(), "bad.gxl")
+	if err := os.WriteFile(path, []byte(`<Book><Import src="x.gxl" sheet="*" as="X" /></Book>`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parser.ReadGxlFromFile(path, lg); err == nil {
+		t.Fatal("expected error for sheet=\"*\" combined with as")
+	}
+}
+
+func TestParse_FragmentAndInclude(t *testing.T) {
+	lg := util.NewLogger(util.LoggerConfig{Component: "test", Service: "repo", Level: "ERROR", Output: "stdout"})
+
+	parts, err := parser.ReadGxlFromFile(filepath.Join("..", ".testdata", "parts", "invoice_parts.gxl"), lg)
+	if err != nil {
+		t.Fatalf("ReadGxlFromFile: %v", err)
+	}
+	if len(parts.Fragments) != 2 || parts.Fragments[0].Name != "InvoiceHeader" || len(parts.Fragments[0].Nodes) != 1 {
+		t.Fatalf("unexpected fragments: %+v", parts.Fragments)
+	}
+
+	gxl, err := parser.ReadGxlFromFile(filepath.Join("..", ".testdata", "include_main.gxl"), lg)
+	if err != nil {
+		t.Fatalf("ReadGxlFromFile: %v", err)
+	}
+	want := model.IncludeTag{Src: "./parts/invoice_parts.gxl", Fragment: "InvoiceHeader", With: "customer"}
+	if diff := cmp.Diff(want, gxl.Sheets[0].Nodes[1]); diff != "" {
+		t.Fatalf("include mismatch (-want +got):\n%s", diff)
+	}
+
+	for _, body := range []string{
+		`<Book><Sheet name="S"><Include src="x.gxl" /></Sheet></Book>`,
+		`<Book><Sheet name="S"><Fragment name="F" /></Sheet></Book>`,
+		`<Book><Fragment /></Book>`,
+	} {
+		path := filepath.Join(t.TempDir(), "bad.gxl")
+		if err := os.WriteFile(path, []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := parser.ReadGxlFromFile(path, lg); err == nil {
+			t.Errorf("expected error for %s", body)
+		}
+	}
+}
+
+func TestParse_ComponentAndUse(t *testing.T) {
+	lg := util.NewLogger(util.LoggerConfig{Component: "test", Service: "repo", Level: "ERROR", Output: "stdout"})
+
+	gxl, err := parser.ReadGxlFromFile(filepath.Join("..", ".testdata", "components_use.gxl"), lg)
+	if err != nil {
+		t.Fatalf("ReadGxlFromFile: %v", err)
+	}
+	if diff := cmp.Diff([]model.ImportTag{{Src: "./parts/widgets.gxl", Components: "KeyValue"}}, gxl.Imports); diff != "" {
+		t.Fatalf("imports mismatch (-want +got):\n%s", diff)
+	}
+	if len(gxl.Components) != 1 || gxl.Components[0].Name != "LineItems" {
+		t.Fatalf("unexpected components: %+v", gxl.Components)
+	}
+	if diff := cmp.Diff([]string{"items"}, gxl.Components[0].Params); diff != "" {
+		t.Fatalf("params mismatch (-want +got):\n%s", diff)
+	}
+	want := model.UseTag{Component: "KeyValue", Args: map[string]string{"label": "Customer", "value": "{{ customer }}"}}
+	if diff := cmp.Diff(want, gxl.Sheets[0].Nodes[0]); diff != "" {
+		t.Fatalf("use mismatch (-want +got):\n%s", diff)
+	}
+
+	for _, body := range []string{
+		`<Book><Sheet name="S"><Use label="x" /></Sheet></Book>`,
+		`<Book><Sheet name="S"><Component name="C" /></Sheet></Book>`,
+		`<Book><Component params="a" /></Book>`,
+		`<Book><Import src="x.gxl" /></Book>`,
+	} {
+		path := filepath.Join(t.TempDir(), "bad.gxl")
+		if err := os.WriteFile(path, []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := parser.ReadGxlFromFile(path, lg); err == nil {
+			t.Errorf("expected error for %s", body)
+		}
+	}
+}
+
+func TestParse_ExtendsAndBlocks(t *testing.T) {
+	lg := util.NewLogger(util.LoggerConfig{Component: "test", Service: "repo", Level: "ERROR", Output: "stdout"})
+
+	gxl, err := parser.ReadGxlFromFile(filepath.Join("..", ".testdata", "extends_sales.gxl"), lg)
+	if err != nil {
+		t.Fatalf("ReadGxlFromFile: %v", err)
+	}
+	if gxl.BookTag.Extends != "./extends/base.gxl" {
+		t.Errorf("extends = %q", gxl.BookTag.Extends)
+	}
+	if len(gxl.Blocks) != 1 || gxl.Blocks[0].Name != "body" || len(gxl.Blocks[0].Nodes) != 1 {
+		t.Fatalf("unexpected blocks: %+v", gxl.Blocks)
+	}
+
+	base, err := parser.ReadGxlFromFile(filepath.Join("..", ".testdata", "extends", "base.gxl"), lg)
+	if err != nil {
+		t.Fatalf("ReadGxlFromFile: %v", err)
+	}
+	if block, ok := base.Sheets[0].Nodes[1].(model.BlockTag); !ok || block.Name != "body" {
+		t.Fatalf("expected body block placeholder, got %+v", base.Sheets[0].Nodes[1])
+	}
+}
+
+func TestParse_Params(t *testing.T) {
+	lg := util.NewLogger(util.LoggerConfig{Component: "test", Service: "repo", Level: "ERROR", Output: "stdout"})
+
+	gxl, err := parser.ReadGxlFromFile(filepath.Join("..", ".testdata", "params_invoice.gxl"), lg)
+	if err != nil {
+		t.Fatalf("ReadGxlFromFile: %v", err)
+	}
+	want := []model.ParamTag{
+		{Name: "invoice.number", Type: "string", Required: true, Description: "Invoice number"},
+		{Name: "invoice.status", Type: "string", Default: "draft", Enum: []string{"draft", "sent", "paid"}},
+		{Name: "invoice.date", Type: "date"},
+		{Name: "items", Type: "array", Required: true},
+		{Name: "taxRate", Type: "number", Default: "0.1"},
+	}
+	if diff := cmp.Diff(want, gxl.HeaderTag.Params); diff != "" {
+		t.Fatalf("params mismatch (-want +got):\n%s", diff)
+	}
+
+	for _, body := range []string{
+		`<Book><Params><Param type="string" /></Params></Book>`,
+		`<Book><Params><Param name="x" type="text" /></Params></Book>`,
+		`<Book><Params><Param name="x" required="maybe" /></Params></Book>`,
+		`<Book><Params><Sheet name="S" /></Params></Book>`,
+	} {
+		path := filepath.Join(t.TempDir(), "bad.gxl")
+		if err := os.WriteFile(path, []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := parser.ReadGxlFromFile(path, lg); err == nil {
+			t.Errorf("expected error for %s", body)
+		}
+	}
+}
+
+func TestReadGxlFromReader(t *testing.T) {
+	lg := util.NewLogger(util.LoggerConfig{Component: "test", Service: "repo", Level: "ERROR", Output: "stderr"})
+	gxl, err := parser.ReadGxlFromReader(strings.NewReader(`<Book name="Piped"><Sheet name="S1"><Grid>| a |</Grid></Sheet></Book>`), lg)
+	if err != nil {
+		t.Fatalf("ReadGxlFromReader: %v", err)
+	}
+	if gxl.BookTag.Name != "Piped" || len(gxl.Sheets) != 1 || gxl.Sheets[0].Name != "S1" {
+		t.Errorf("unexpected template: book %q, sheets %+v", gxl.BookTag.Name, gxl.Sheets)
+	}
+
+	if _, err := parser.ReadGxlFromReader(strings.NewReader(`<Book><Sheet name="S1">`), lg); err == nil {
+		t.Errorf("expected an error for truncated input")
+	}
+}
+
+func TestParse_BookBaseAndSheetMode(t *testing.T) {
+	lg := util.NewLogger(util.LoggerConfig{Component: "test", Service: "repo", Level: "ERROR", Output: "stderr"})
+	gxl, err := parser.ReadGxlFromReader(strings.NewReader(`<Book base="letterhead.xlsx"><Sheet name="Invoice" mode="replace"><Grid>| a |</Grid></Sheet><Sheet name="Notes"><Grid>| b |</Grid></Sheet></Book>`), lg)
+	if err != nil {
+		t.Fatalf("ReadGxlFromReader: %v", err)
+	}
+	if gxl.BookTag.Base != "letterhead.xlsx" {
+		t.Errorf("base = %q, want letterhead.xlsx", gxl.BookTag.Base)
+	}
+	if gxl.Sheets[0].Mode != model.SheetModeReplace || gxl.Sheets[1].Mode != "" {
+		t.Errorf("modes = %q, %q, want replace and empty", gxl.Sheets[0].Mode, gxl.Sheets[1].Mode)
+	}
+
+	if _, err := parser.ReadGxlFromReader(strings.NewReader(`<Book><Sheet name="S" mode="merge"><Grid>| a |</Grid></Sheet></Book>`), lg); err == nil || !strings.Contains(err.Error(), "mode must be overlay or replace") {
+		t.Errorf("err = %v, want an invalid mode error", err)
+	}
+}
+
+func TestParse_GridStyleAndColumnWidth(t *testing.T) {
+	lg := util.NewLogger(util.LoggerConfig{Component: "test", Service: "repo", Level: "ERROR", Output: "stderr"})
+	gxl, err := parser.ReadGxlFromReader(strings.NewReader(`<Book><Sheet name="S">
+  <Column index="2" width="30" />
+  <Grid ref="A1" bold="true" underline="true" h_align="Center" v_align="middle" number_format="#,##0.00">
+    | a | b |
+  </Grid>
+</Sheet></Book>`), lg)
+	if err != nil {
+		t.Fatalf("ReadGxlFromReader: %v", err)
+	}
+	nodes := gxl.Sheets[0].Nodes
+	if diff := cmp.Diff(model.ColumnTag{Index: 2, Width: 30}, nodes[0]); diff != "" {
+		t.Errorf("column mismatch (-want +got):\n%s", diff)
+	}
+	grid := nodes[1].(model.GridTag)
+	want := model.GridTag{Ref: "A1", Bold: true, Underline: true, HAlign: "center", VAlign: "middle", NumberFormat: "#,##0.00",
+		Rows: []model.GridRowTag{{Cells: []string{"a", "b"}}}}
+	grid.Content = ""
+	if diff := cmp.Diff(want, grid); diff != "" {
+		t.Errorf("grid mismatch (-want +got):\n%s", diff)
+	}
+
+	for _, body := range []string{
+		`<Column width="30" />`,
+		`<Column index="2" width="wide" />`,
+		`<Column index="0" width="20" />`,
+	} {
+		if _, err := parser.ReadGxlFromReader(strings.NewReader(`<Book><Sheet name="S">`+body+`</Sheet></Book>`), lg); err == nil {
+			t.Errorf("expected error for %s", body)
+		}
+	}
+}
+
+func TestParse_BookLevelIfContentAfterElse(t *testing.T) {
+	lg := util.NewLogger(util.LoggerConfig{Component: "test", Service: "repo", Level: "ERROR", Output: "stdout"})
+	src := `<Book>
+  <If cond="showNotes">
+    <Sheet name="Notes"></Sheet>
+    <Else><Sheet name="NoNotes"></Sheet></Else>
+    <Sheet name="Stray"></Sheet>
+  </If>
+</Book>`
+	_, err := parser.ReadGxlFromReader(strings.NewReader(src), lg)
+	if err == nil || !strings.Contains(err.Error(), "after </Else>") {
+		t.Fatalf("expected an error for a sheet after </Else>, got %v", err)
+	}
+}
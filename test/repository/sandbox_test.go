@@ -0,0 +1,60 @@
+package parser_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	parser "github.com/ryo-arima/goxcel/pkg/repository"
+)
+
+func TestSandboxPath(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "parts"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(outside, "secret.gxl"), []byte("<Book/>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(outside, "secret.gxl"), filepath.Join(root, "link.gxl")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		dir     string
+		src     string
+		want    string
+		blocked bool
+	}{
+		{"relative", root, "parts/a.gxl", filepath.Join(root, "parts", "a.gxl"), false},
+		{"parent within root", filepath.Join(root, "parts"), "../b.gxl", filepath.Join(root, "b.gxl"), false},
+		{"absolute within root", outside, filepath.Join(root, "c.gxl"), filepath.Join(root, "c.gxl"), false},
+		{"parent escape", root, "../x.gxl", "", true},
+		{"absolute escape", root, filepath.Join(outside, "secret.gxl"), "", true},
+		{"symlink escape", root, "link.gxl", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parser.SandboxPath(root, tt.dir, tt.src)
+			if tt.blocked {
+				var sbErr *parser.SandboxError
+				if !errors.As(err, &sbErr) {
+					t.Fatalf("err = %v, want *SandboxError", err)
+				}
+				if sbErr.Path != tt.src {
+					t.Errorf("Path = %q, want %q", sbErr.Path, tt.src)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SandboxPath: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("SandboxPath = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
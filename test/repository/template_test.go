@@ -0,0 +1,123 @@
+package parser_test
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/ryo-arima/goxcel/pkg/config"
+	parser "github.com/ryo-arima/goxcel/pkg/repository"
+)
+
+func TestTemplateRepository_BuiltinAndUser(t *testing.T) {
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+	userDir := filepath.Join(configHome, "goxcel", "templates")
+	for _, name := range []string{"simple", "custom"} {
+		if err := os.MkdirAll(filepath.Join(userDir, name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(userDir, name, "base.gxl"), []byte(`<Book name="`+name+`"/>`), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	repo := parser.NewTemplateRepository(config.NewBaseConfig())
+	templates, err := repo.ListTemplates()
+	if err != nil {
+		t.Fatalf("ListTemplates: %v", err)
+	}
+	want := []parser.TemplateInfo{
+		{Name: "b4-landscape", Source: parser.TemplateSourceBuiltin},
+		{Name: "custom", Source: parser.TemplateSourceUser, Dir: filepath.Join(userDir, "custom")},
+		{Name: "invoice", Source: parser.TemplateSourceBuiltin},
+		{Name: "simple", Source: parser.TemplateSourceUser, Overrides: true, Dir: filepath.Join(userDir, "simple")},
+	}
+	if diff := cmp.Diff(want, templates, cmpopts.IgnoreFields(parser.TemplateInfo{}, "Manifest")); diff != "" {
+		t.Errorf("templates mismatch (-want +got):\n%s", diff)
+	}
+
+	// The user template hides the built-in one
+	fsys, info, err := repo.OpenTemplate("simple")
+	if err != nil {
+		t.Fatalf("OpenTemplate: %v", err)
+	}
+	b, err := fs.ReadFile(fsys, "base.gxl")
+	if err != nil || string(b) != `<Book name="simple"/>` || info.Source != parser.TemplateSourceUser {
+		t.Errorf("simple = %q (%v, source %s), want the user template", b, err, info.Source)
+	}
+
+	// Built-in templates are embedded
+	fsys, info, err = repo.OpenTemplate("invoice")
+	if err != nil || info.Source != parser.TemplateSourceBuiltin {
+		t.Fatalf("OpenTemplate(invoice) = %+v, %v", info, err)
+	}
+	if _, err := fs.Stat(fsys, "base.gxl"); err != nil {
+		t.Errorf("built-in invoice has no base.gxl: %v", err)
+	}
+
+	for _, name := range []string{"missing", "../simple", ""} {
+		if _, _, err := repo.OpenTemplate(name); err == nil {
+			t.Errorf("OpenTemplate(%q): expected an error", name)
+		}
+	}
+}
+
+func TestTemplateManifest_Files(t *testing.T) {
+	fsys := fstest.MapFS{
+		"template.yaml":        {Data: []byte("title: Report\nversion: 2.1.0\ntags: [sales]\nparams: [region]\npreview: samples/q1.json\nfiles:\n  - parts\n  - images/*.png\n")},
+		"base.gxl":             {Data: []byte("<Book/>")},
+		"samples/q1.json":      {Data: []byte(`{"region": "East"}`)},
+		"parts/header.gxl":     {Data: []byte("<Book/>")},
+		"parts/sub/footer.gxl": {Data: []byte("<Book/>")},
+		"images/logo.png":      {Data: []byte("png")},
+		"images/notes.txt":     {Data: []byte("not listed")},
+		"unlisted/scratch.gxl": {Data: []byte("<Book/>")},
+	}
+
+	manifest, err := parser.ReadTemplateManifest(fsys)
+	if err != nil {
+		t.Fatalf("ReadTemplateManifest: %v", err)
+	}
+	wantManifest := parser.TemplateManifest{
+		Title:   "Report",
+		Version: "2.1.0",
+		Tags:    []string{"sales"},
+		Params:  []string{"region"},
+		Preview: "samples/q1.json",
+		Files:   []string{"parts", "images/*.png"},
+	}
+	if diff := cmp.Diff(wantManifest, manifest); diff != "" {
+		t.Errorf("manifest mismatch (-want +got):\n%s", diff)
+	}
+
+	files, err := parser.TemplateFiles(fsys, manifest)
+	if err != nil {
+		t.Fatalf("TemplateFiles: %v", err)
+	}
+	wantFiles := []string{"template.yaml", "base.gxl", "samples/q1.json", "parts/header.gxl", "parts/sub/footer.gxl", "images/logo.png"}
+	if diff := cmp.Diff(wantFiles, files); diff != "" {
+		t.Errorf("files mismatch (-want +got):\n%s", diff)
+	}
+
+	// Without a manifest the preview defaults to base.yaml
+	manifest, err = parser.ReadTemplateManifest(fstest.MapFS{"base.gxl": {}, "base.yaml": {}})
+	if err != nil || manifest.Preview != "base.yaml" {
+		t.Errorf("manifest = %+v, %v; want preview base.yaml", manifest, err)
+	}
+
+	for _, bad := range []string{"files: [../secret]\n", "files: [missing.png]\n", "title: [unclosed\n"} {
+		fsys := fstest.MapFS{"template.yaml": {Data: []byte(bad)}, "base.gxl": {}}
+		manifest, err := parser.ReadTemplateManifest(fsys)
+		if err == nil {
+			_, err = parser.TemplateFiles(fsys, manifest)
+		}
+		if err == nil {
+			t.Errorf("manifest %q: expected an error", bad)
+		}
+	}
+}
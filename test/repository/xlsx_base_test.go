@@ -0,0 +1,200 @@
+package parser_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ryo-arima/goxcel/pkg/config"
+	"github.com/ryo-arima/goxcel/pkg/model"
+	parser "github.com/ryo-arima/goxcel/pkg/repository"
+)
+
+// baseParts is a letterhead workbook as Excel saves it: shared strings, a theme, a drawing on
+// the cover sheet, print settings and a calculation chain
+var baseParts = map[string]string{
+	"[Content_Types].xml": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/><Override PartName="/xl/worksheets/sheet2.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/><Override PartName="/xl/theme/theme1.xml" ContentType="application/vnd.openxmlformats-officedocument.theme+xml"/><Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/><Override PartName="/xl/sharedStrings.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sharedStrings+xml"/><Override PartName="/xl/calcChain.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.calcChain+xml"/></Types>`,
+	"_rels/.rels": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`,
+	"xl/workbook.xml": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Cover" sheetId="1" r:id="rId1"/><sheet name="Invoice" sheetId="3" r:id="rId2"/></sheets><calcPr calcId="191029"/></workbook>`,
+	"xl/_rels/workbook.xml.rels": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/><Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet2.xml"/><Relationship Id="rId3" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/theme" Target="theme/theme1.xml"/><Relationship Id="rId4" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/><Relationship Id="rId5" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/sharedStrings" Target="sharedStrings.xml"/><Relationship Id="rId6" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/calcChain" Target="calcChain.xml"/></Relationships>`,
+	"xl/theme/theme1.xml": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<a:theme xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" name="Letterhead"><a:themeElements/></a:theme>`,
+	"xl/styles.xml": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="18"/><name val="Georgia"/></font></fonts><fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills><borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders><cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs><cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs><cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles></styleSheet>`,
+	"xl/sharedStrings.xml": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" count="3" uniqueCount="3"><si><t>ACME Corp.</t></si><si><t>Invoice</t></si><si><t>Old total</t></si></sst>`,
+	"xl/calcChain.xml": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<calcChain xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><c r="B3" i="2"/></calcChain>`,
+	"xl/worksheets/_rels/sheet1.xml.rels": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/drawing" Target="../drawings/drawing1.xml"/></Relationships>`,
+	"xl/drawings/drawing1.xml": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<xdr:wsDr xmlns:xdr="http://schemas.openxmlformats.org/drawingml/2006/spreadsheetDrawing"/>`,
+	"xl/worksheets/sheet1.xml": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><dimension ref="A1"/><sheetData><row r="1"><c r="A1" s="1" t="s"><v>0</v></c></row></sheetData><pageMargins left="0.7" right="0.7" top="0.75" bottom="0.75" header="0.3" footer="0.3"/><drawing r:id="rId1"/></worksheet>`,
+	"xl/worksheets/sheet2.xml": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><dimension ref="A1:B3"/><sheetData><row r="1" spans="1:2"><c r="A1" s="1" t="s"><v>1</v></c></row><row r="3" spans="1:2"><c r="A3" t="s"><v>2</v></c><c r="B3"><f>SUM(B2:B2)</f><v>0</v></c></row></sheetData><mergeCells count="1"><mergeCell ref="A1:B1"/></mergeCells><pageSetup orientation="landscape"/></worksheet>`,
+}
+
+// writeBase writes baseParts to a file and returns its path
+func writeBase(t *testing.T) string {
+	t.Helper()
+	r := buildXLSX(t, baseParts)
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "letterhead.xlsx")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// readParts unzips an .xlsx file into its parts
+func readParts(t *testing.T, path string) map[string]string {
+	t.Helper()
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	parts := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts[f.Name] = string(data)
+	}
+	return parts
+}
+
+func TestWriteBook_Base(t *testing.T) {
+	base := writeBase(t)
+	out := filepath.Join(t.TempDir(), "out.xlsx")
+
+	book := model.NewBook()
+	book.Base = base
+	invoice := model.NewSheet("Invoice")
+	invoice.AddCell(&model.Cell{Ref: "A2", Value: "Widget", Type: model.CellTypeString, Style: &model.CellStyle{Italic: true}})
+	invoice.AddCell(&model.Cell{Ref: "B2", Value: "42", Type: model.CellTypeNumber})
+	invoice.AddCell(&model.Cell{Ref: "A3", Value: "Total", Type: model.CellTypeString})
+	book.AddSheet(invoice)
+	summary := model.NewSheet("Summary")
+	summary.Mode = model.SheetModeReplace
+	summary.AddCell(&model.Cell{Ref: "A1", Value: "Done", Type: model.CellTypeString, Style: &model.CellStyle{Bold: true}})
+	book.AddSheet(summary)
+	if err := parser.WriteBookToFile(book, out); err != nil {
+		t.Fatalf("WriteBookToFile: %v", err)
+	}
+
+	parts := readParts(t, out)
+	for _, name := range []string{"xl/theme/theme1.xml", "xl/worksheets/sheet1.xml", "xl/worksheets/_rels/sheet1.xml.rels", "xl/drawings/drawing1.xml", "xl/sharedStrings.xml"} {
+		if parts[name] != baseParts[name] {
+			t.Errorf("%s changed:\n%s", name, parts[name])
+		}
+	}
+	if _, ok := parts["xl/calcChain.xml"]; ok {
+		t.Error("calcChain.xml should be removed")
+	}
+	for _, name := range []string{"xl/_rels/workbook.xml.rels", "[Content_Types].xml"} {
+		if strings.Contains(parts[name], "calcChain") {
+			t.Errorf("%s still refers to the calculation chain", name)
+		}
+	}
+	if !strings.Contains(parts["xl/worksheets/sheet2.xml"], `<pageSetup orientation="landscape"/>`) {
+		t.Errorf("print settings of the overlaid sheet were lost:\n%s", parts["xl/worksheets/sheet2.xml"])
+	}
+	if !strings.Contains(parts["xl/workbook.xml"], `<sheet name="Summary" sheetId="4" r:id="rId7"/>`) {
+		t.Errorf("new sheet not added to the workbook:\n%s", parts["xl/workbook.xml"])
+	}
+
+	got, err := parser.NewXlsxRepository(config.BaseConfig{}).ReadBook(out)
+	if err != nil {
+		t.Fatalf("ReadBook: %v", err)
+	}
+	title := &model.CellStyle{Bold: true, FontName: "Georgia", FontSize: 18}
+	cover := model.NewSheet("Cover")
+	cover.Cells = []*model.Cell{{Ref: "A1", Value: "ACME Corp.", Type: model.CellTypeString, Style: title}}
+	wantInvoice := model.NewSheet("Invoice")
+	wantInvoice.Cells = []*model.Cell{
+		{Ref: "A1", Value: "Invoice", Type: model.CellTypeString, Style: title},
+		{Ref: "A2", Value: "Widget", Type: model.CellTypeString, Style: &model.CellStyle{Italic: true}},
+		{Ref: "B2", Value: "42", Type: model.CellTypeNumber},
+		{Ref: "A3", Value: "Total", Type: model.CellTypeString},
+		{Ref: "B3", Value: "=SUM(B2:B2)", Type: model.CellTypeFormula},
+	}
+	wantInvoice.Merges = []model.Merge{{Range: "A1:B1"}}
+	wantSummary := model.NewSheet("Summary")
+	wantSummary.Cells = []*model.Cell{{Ref: "A1", Value: "Done", Type: model.CellTypeString, Style: &model.CellStyle{Bold: true}}}
+	want := &model.Book{Sheets: []*model.Sheet{cover, wantInvoice, wantSummary}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("book mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestWriteBook_BaseReplace(t *testing.T) {
+	base := writeBase(t)
+
+	book := model.NewBook()
+	book.Base = base
+	invoice := model.NewSheet("invoice")
+	invoice.Mode = model.SheetModeReplace
+	invoice.AddCell(&model.Cell{Ref: "C5", Value: "Only", Type: model.CellTypeString})
+	book.AddSheet(invoice)
+	// The base may also be the output file
+	if err := parser.WriteBookToFile(book, base); err != nil {
+		t.Fatalf("WriteBookToFile: %v", err)
+	}
+
+	got, err := parser.ReadBookFromFile(base)
+	if err != nil {
+		t.Fatalf("ReadBook: %v", err)
+	}
+	if len(got.Sheets) != 2 || got.Sheets[1].Name != "Invoice" {
+		t.Fatalf("sheets = %+v, want Cover and Invoice", got.Sheets)
+	}
+	want := []*model.Cell{{Ref: "C5", Value: "Only", Type: model.CellTypeString}}
+	if diff := cmp.Diff(want, got.Sheets[1].Cells); diff != "" {
+		t.Errorf("replaced sheet mismatch (-want +got):\n%s", diff)
+	}
+	if len(got.Sheets[1].Merges) != 0 {
+		t.Errorf("merges = %v, want none", got.Sheets[1].Merges)
+	}
+	if parts := readParts(t, base); !strings.Contains(parts["xl/worksheets/sheet2.xml"], `<dimension ref="C5"/>`) {
+		t.Errorf("dimension not updated:\n%s", parts["xl/worksheets/sheet2.xml"])
+	}
+}
+
+func TestWriteBook_BaseErrors(t *testing.T) {
+	book := model.NewBook()
+	book.Base = filepath.Join(t.TempDir(), "missing.xlsx")
+	book.AddSheet(model.NewSheet("Sheet1"))
+	var buf bytes.Buffer
+	if err := parser.WriteBook(book, &buf); err == nil || !strings.Contains(err.Error(), "read base workbook") {
+		t.Errorf("err = %v, want a read error", err)
+	}
+
+	notZip := filepath.Join(t.TempDir(), "plain.xlsx")
+	if err := os.WriteFile(notZip, []byte("not a zip"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	book.Base = notZip
+	if err := parser.WriteBook(book, &buf); err == nil || !strings.Contains(err.Error(), "is not an xlsx package") {
+		t.Errorf("err = %v, want a package error", err)
+	}
+}
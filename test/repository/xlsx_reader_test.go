@@ -0,0 +1,183 @@
+package parser_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ryo-arima/goxcel/pkg/config"
+	"github.com/ryo-arima/goxcel/pkg/model"
+	parser "github.com/ryo-arima/goxcel/pkg/repository"
+)
+
+// buildXLSX zips the given parts into an .xlsx package
+func buildXLSX(t *testing.T, parts map[string]string) *bytes.Reader {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range parts {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return bytes.NewReader(buf.Bytes())
+}
+
+func TestReadBook_ExcelPackage(t *testing.T) {
+	// Parts as Excel writes them: shared strings with rich text, a stylesheet with custom
+	// number formats and theme colors, and a second sheet referenced out of order
+	parts := map[string]string{
+		"_rels/.rels": `<?xml version="1.0"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+  <Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>`,
+		"xl/workbook.xml": `<?xml version="1.0"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+  <workbookPr date1904="false"/>
+  <sheets>
+    <sheet name="Invoice" sheetId="1" r:id="rId2"/>
+    <sheet name="Notes" sheetId="2" r:id="rId1"/>
+  </sheets>
+</workbook>`,
+		"xl/_rels/workbook.xml.rels": `<?xml version="1.0"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+  <Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet2.xml"/>
+  <Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="/xl/worksheets/sheet1.xml"/>
+  <Relationship Id="rId3" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>
+  <Relationship Id="rId4" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/sharedStrings" Target="sharedStrings.xml"/>
+</Relationships>`,
+		"xl/sharedStrings.xml": `<?xml version="1.0"?>
+<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" count="2" uniqueCount="2">
+  <si><t>Total</t></si>
+  <si><r><rPr><b/></rPr><t>Rich </t></r><r><t xml:space="preserve">text</t></r></si>
+</sst>`,
+		"xl/styles.xml": `<?xml version="1.0"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+  <numFmts count="1"><numFmt numFmtId="164" formatCode="yyyy/mm/dd"/></numFmts>
+  <fonts count="2">
+    <font><sz val="11"/><color theme="1"/><name val="Calibri"/></font>
+    <font><b/><u/><sz val="14"/><color rgb="FFFF0000"/><name val="Arial"/></font>
+  </fonts>
+  <fills count="3">
+    <fill><patternFill patternType="none"/></fill>
+    <fill><patternFill patternType="gray125"/></fill>
+    <fill><patternFill patternType="solid"><fgColor rgb="FFFFFF00"/><bgColor indexed="64"/></patternFill></fill>
+  </fills>
+  <borders count="2">
+    <border><left/><right/><top/><bottom/></border>
+    <border><left/><right/><top style="thin"><color rgb="FF445566"/></top><bottom style="thin"><color rgb="FF445566"/></bottom></border>
+  </borders>
+  <cellXfs count="5">
+    <xf numFmtId="0" fontId="0" fillId="0" borderId="0"/>
+    <xf numFmtId="0" fontId="1" fillId="2" borderId="1" applyFont="1"><alignment horizontal="center" vertical="center"/></xf>
+    <xf numFmtId="164" fontId="0" fillId="0" borderId="0" applyNumberFormat="1"/>
+    <xf numFmtId="4" fontId="0" fillId="0" borderId="0" applyNumberFormat="1"/>
+    <xf numFmtId="22" fontId="0" fillId="0" borderId="0" applyNumberFormat="1"/>
+  </cellXfs>
+</styleSheet>`,
+		"xl/worksheets/sheet1.xml": `<?xml version="1.0"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+  <sheetViews><sheetView showGridLines="0" workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>
+  <sheetFormatPr defaultRowHeight="15"/>
+  <cols><col min="1" max="2" width="20.5" customWidth="1"/></cols>
+  <sheetData>
+    <row r="1" ht="24" customHeight="1">
+      <c r="A1" s="1" t="s"><v>0</v></c>
+      <c r="B1" t="s"><v>1</v></c>
+      <c r="C1" s="1"/>
+    </row>
+    <row r="2">
+      <c r="A2" s="2"><v>45306</v></c>
+      <c r="B2" s="3"><v>1234.5</v></c>
+      <c r="C2" s="4"><v>45306.75</v></c>
+      <c r="D2" t="b"><v>1</v></c>
+      <c r="E2"><f>SUM(B2,1)</f><v>1235.5</v></c>
+      <c r="F2" t="inlineStr"><is><t>inline</t></is></c>
+      <c r="G2" t="e"><v>#DIV/0!</v></c>
+      <c r="H2"/>
+    </row>
+  </sheetData>
+  <mergeCells count="1"><mergeCell ref="A1:C1"/></mergeCells>
+</worksheet>`,
+		"xl/worksheets/sheet2.xml": `<?xml version="1.0"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+  <sheetData><row><c t="inlineStr"><is><t>first</t></is></c><c t="inlineStr"><is><t>second</t></is></c></row></sheetData>
+</worksheet>`,
+	}
+	r := buildXLSX(t, parts)
+	book, err := parser.ReadBook(r, r.Size())
+	if err != nil {
+		t.Fatalf("ReadBook: %v", err)
+	}
+
+	header := &model.CellStyle{
+		Bold: true, Underline: true, FontName: "Arial", FontSize: 14, FontColor: "FF0000", FillColor: "FFFF00",
+		HAlign: "center", VAlign: "middle",
+		Border: &model.CellBorder{Style: "thin", Color: "445566", Top: true, Bottom: true},
+	}
+	invoice := model.NewSheet("Invoice")
+	invoice.Config.ShowGridLines = false
+	invoice.Config.FreezePane = "A2"
+	invoice.Config.ColumnWidths = []model.ColumnWidth{{Column: 1, Width: 20.5}, {Column: 2, Width: 20.5}}
+	invoice.Config.RowHeights = []model.RowHeight{{Row: 1, Height: 24}}
+	invoice.Cells = []*model.Cell{
+		{Ref: "A1", Value: "Total", Type: model.CellTypeString, Style: header},
+		{Ref: "B1", Value: "Rich text", Type: model.CellTypeString},
+		{Ref: "C1", Type: model.CellTypeString, Style: header},
+		{Ref: "A2", Value: "2024-01-15", Type: model.CellTypeDate, Style: &model.CellStyle{NumberFormat: "yyyy/mm/dd"}},
+		{Ref: "B2", Value: "1234.5", Type: model.CellTypeNumber, Style: &model.CellStyle{NumberFormat: "#,##0.00"}},
+		{Ref: "C2", Value: "2024-01-15 18:00:00", Type: model.CellTypeDate, Style: &model.CellStyle{NumberFormat: "m/d/yy h:mm"}},
+		{Ref: "D2", Value: "true", Type: model.CellTypeBoolean},
+		{Ref: "E2", Value: "=SUM(B2,1)", Type: model.CellTypeFormula},
+		{Ref: "F2", Value: "inline", Type: model.CellTypeString},
+		{Ref: "G2", Value: "#DIV/0!", Type: model.CellTypeString},
+	}
+	invoice.Merges = []model.Merge{{Range: "A1:C1"}}
+	notes := model.NewSheet("Notes")
+	notes.Cells = []*model.Cell{
+		{Ref: "A1", Value: "first", Type: model.CellTypeString},
+		{Ref: "B1", Value: "second", Type: model.CellTypeString},
+	}
+	want := &model.Book{Sheets: []*model.Sheet{invoice, notes}}
+	if diff := cmp.Diff(want, book); diff != "" {
+		t.Errorf("ReadBook mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestReadBookFromFile_RoundTrip(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "round.xlsx")
+	b := model.NewBook()
+	s := model.NewSheet("Data")
+	s.Config.ColumnWidths = []model.ColumnWidth{{Column: 2, Width: 30}}
+	s.Config.RowHeights = []model.RowHeight{{Row: 2, Height: 28}}
+	s.AddCell(&model.Cell{Ref: "A1", Value: "Name", Type: model.CellTypeString, Style: &model.CellStyle{Bold: true, FillColor: "DDEEFF"}})
+	s.AddCell(&model.Cell{Ref: "B1", Value: "1500", Type: model.CellTypeNumber, Style: &model.CellStyle{NumberFormat: "#,##0 \"JPY\""}})
+	s.AddCell(&model.Cell{Ref: "A2", Value: "false", Type: model.CellTypeBoolean})
+	s.AddCell(&model.Cell{Ref: "B2", Value: "=B1*2", Type: model.CellTypeFormula, Style: &model.CellStyle{Italic: true, FontName: "Georgia", FontSize: 12}})
+	s.AddMerge(model.Merge{Range: "C1:D1"})
+	b.AddSheet(s)
+	if err := parser.WriteBookToFile(b, out); err != nil {
+		t.Fatalf("WriteBookToFile: %v", err)
+	}
+
+	got, err := parser.NewXlsxRepository(config.BaseConfig{}).ReadBook(out)
+	if err != nil {
+		t.Fatalf("ReadBook: %v", err)
+	}
+	if diff := cmp.Diff(b, got); diff != "" {
+		t.Errorf("round trip mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := parser.ReadBookFromFile(filepath.Join(t.TempDir(), "missing.xlsx")); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
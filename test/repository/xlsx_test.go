@@ -2,6 +2,7 @@ package parser_test
 
 import (
 	"archive/zip"
+	"bytes"
 	"fmt"
 	"io"
 	"os"
@@ -1309,3 +1310,29 @@ func TestWriteBookToFile_SharedStringsTable(t *testing.T) {
 		t.Error("sharedStrings.xml not found in ZIP")
 	}
 }
+
+func TestWriteBook_ToWriter(t *testing.T) {
+	book := &model.Book{Sheets: []*model.Sheet{{Name: "S1", Cells: []*model.Cell{{Ref: "A1", Value: "streamed", Type: model.CellTypeString}}}}}
+
+	var buf bytes.Buffer
+	if err := parser.WriteBook(book, &buf); err != nil {
+		t.Fatalf("WriteBook: %v", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("not a zip package: %v", err)
+	}
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	for _, want := range []string{"[Content_Types].xml", "xl/workbook.xml", "xl/worksheets/sheet1.xml", "xl/styles.xml"} {
+		found := false
+		for _, n := range names {
+			found = found || n == want
+		}
+		if !found {
+			t.Errorf("package is missing %s (have %v)", want, names)
+		}
+	}
+}
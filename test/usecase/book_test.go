@@ -525,3 +525,43 @@ func TestBookUsecase_Render_MustacheInAttributesNoData(t *testing.T) {
 		t.Errorf("FontColor: got %q, want %q", cell.Style.FontColor, "000000")
 	}
 }
+
+func TestBookUsecase_Render_BookLevelForAndIf(t *testing.T) {
+	path := filepath.Join("..", ".testdata", "book_for.gxl")
+	conf := config.NewBaseConfigWithFile(path)
+	gxl, err := parser.NewGxlRepository(conf).ReadGxl()
+	if err != nil {
+		t.Fatalf("ReadGxl: %v", err)
+	}
+
+	data := map[string]any{
+		"regions": []any{
+			map[string]any{"name": "EMEA", "total": 10},
+			map[string]any{"name": "APAC", "total": 20},
+		},
+		"showNotes": false,
+	}
+	book, err := usecase.NewBookUsecase(conf).Render(context.Background(), &gxl, data)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+
+	var names []string
+	for _, s := range book.Sheets {
+		names = append(names, s.Name)
+	}
+	if diff := cmp.Diff([]string{"Summary", "EMEA", "APAC", "NoNotes"}, names); diff != "" {
+		t.Fatalf("sheet names mismatch (-want +got):\n%s", diff)
+	}
+
+	// Loop variable and loop metadata are in scope for each generated sheet
+	apac := book.Sheets[2]
+	got := map[string]string{}
+	for _, c := range apac.Cells {
+		got[c.Ref] = c.Value
+	}
+	want := map[string]string{"A1": "Region", "B1": "APAC", "A2": "Total", "B2": "20", "A3": "No.", "B3": "2"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("APAC cells mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -257,3 +257,55 @@ func TestParseTypeHint_AllCases(t *testing.T) {
 		_ = cell.Value
 	}
 }
+
+func TestIfTag_ConditionOperators(t *testing.T) {
+	data := map[string]any{
+		"total":  1500,
+		"status": "paid",
+		"items":  []any{1},
+		"empty":  []any{},
+		"zero":   0,
+		"flag":   true,
+		"user":   map[string]any{"premium": false},
+	}
+	cases := []struct {
+		cond string
+		want bool
+	}{
+		{"flag", true},
+		{"!flag", false},
+		{"missing", false},
+		{"zero", false},
+		{"items", true},
+		{"empty", false},
+		{"user.premium", false},
+		{"total > 1000", true},
+		{"total <= 1000", false},
+		{"total == 1500", true},
+		{"status == 'paid'", true},
+		{"status != \"paid\"", false},
+		{"flag && total >= 1500", true},
+		{"user.premium || status == 'paid'", true},
+		{"user.premium && flag", false},
+		{"status == 'a || b'", false},
+		{"missing == null", true},
+	}
+	for _, tc := range cases {
+		gxl := &model.GXL{BookNodes: []model.BookNode{{
+			Type: model.BookNodeTypeIf,
+			If: &model.BookIfTag{
+				Cond: tc.cond,
+				Then: []model.BookNode{{Type: model.BookNodeTypeSheet, Sheet: &model.SheetTag{Name: "then"}}},
+				Else: []model.BookNode{{Type: model.BookNodeTypeSheet, Sheet: &model.SheetTag{Name: "else"}}},
+			},
+		}}}
+		book, err := usecase.NewBookUsecase(config.NewBaseConfig()).Render(context.Background(), gxl, data)
+		if err != nil {
+			t.Fatalf("%q: Render: %v", tc.cond, err)
+		}
+		got := book.Sheets[0].Name == "then"
+		if got != tc.want {
+			t.Errorf("cond %q = %v, want %v", tc.cond, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package usecase_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ryo-arima/goxcel/pkg/config"
+	"github.com/ryo-arima/goxcel/pkg/model"
+	parser "github.com/ryo-arima/goxcel/pkg/repository"
+	"github.com/ryo-arima/goxcel/pkg/usecase"
+)
+
+// TestComponent_InlineAndImported tests <Use> of inline and imported components
+func TestComponent_InlineAndImported(t *testing.T) {
+	path := filepath.Join("..", ".testdata", "components_use.gxl")
+	conf := config.NewBaseConfigWithFile(path)
+	gxl, err := parser.NewGxlRepository(conf).ReadGxl()
+	if err != nil {
+		t.Fatalf("ReadGxl: %v", err)
+	}
+
+	data := map[string]any{
+		"customer": "Acme",
+		"total":    30,
+		"lines": []any{
+			map[string]any{"name": "Apples", "amount": 10},
+			map[string]any{"name": "Pears", "amount": 20},
+		},
+	}
+	book, err := usecase.NewBookUsecase(conf).Render(context.Background(), &gxl, data)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+
+	got := map[string]string{}
+	for _, c := range book.Sheets[0].Cells {
+		got[c.Ref] = c.Value
+	}
+	want := map[string]string{
+		"A1": "Customer", "B1": "Acme",
+		"A2": "Apples", "B2": "10",
+		"A3": "Pears", "B3": "20",
+		"A4": "Total", "B4": "30",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("cells mismatch (-want +got):\n%s", diff)
+	}
+	if c := findCellByRef(book, "B4"); c == nil || c.Style == nil || c.Style.Border == nil {
+		t.Errorf("B4 should keep the component's border style")
+	}
+}
+
+// TestComponent_Errors tests unknown components, unknown parameters and recursion
+func TestComponent_Errors(t *testing.T) {
+	kv := model.ComponentTag{Name: "KeyValue", Params: []string{"label", "value"}}
+	loop := model.ComponentTag{Name: "Loop", Nodes: []any{model.UseTag{Component: "Loop"}}}
+	for _, tc := range []struct {
+		use     model.UseTag
+		wantErr string
+	}{
+		{use: model.UseTag{Component: "Missing"}, wantErr: "is not defined"},
+		{use: model.UseTag{Component: "KeyValue", Args: map[string]string{"lable": "x"}}, wantErr: "has no parameter \"lable\""},
+		{use: model.UseTag{Component: "Loop"}, wantErr: "recursive use"},
+	} {
+		gxl := &model.GXL{
+			Components: []model.ComponentTag{kv, loop},
+			Sheets:     []model.SheetTag{{Name: "S", Nodes: []any{tc.use}}},
+		}
+		_, err := usecase.NewBookUsecase(config.NewBaseConfig()).Render(context.Background(), gxl, nil)
+		if err == nil || !contains(err.Error(), tc.wantErr) {
+			t.Errorf("%s: err = %v, want containing %q", tc.use.Component, err, tc.wantErr)
+		}
+	}
+
+	// Duplicate definitions are rejected
+	gxl := &model.GXL{Components: []model.ComponentTag{kv, kv}}
+	if _, err := usecase.NewBookUsecase(config.NewBaseConfig()).Render(context.Background(), gxl, nil); err == nil || !contains(err.Error(), "already defined") {
+		t.Errorf("err = %v, want duplicate component error", err)
+	}
+}
+
+// TestComponent_ScopedToImportedFile tests components defined in an imported file, component
+// imports inside a book-level <If>, and a component file imported along two paths
+func TestComponent_ScopedToImportedFile(t *testing.T) {
+	path := filepath.Join("..", ".testdata", "components_scoped.gxl")
+	conf := config.NewBaseConfigWithFile(path)
+	gxl, err := parser.NewGxlRepository(conf).ReadGxl()
+	if err != nil {
+		t.Fatalf("ReadGxl: %v", err)
+	}
+
+	data := map[string]any{"labels": true, "owner": "Ann", "total": 30}
+	book, err := usecase.NewBookUsecase(conf).Render(context.Background(), &gxl, data)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+
+	want := []map[string]string{
+		{"A1": "Total", "B1": "30", "A2": "Owner", "B2": "Ann"},
+		{"A1": "Label", "B1": "Ann"},
+	}
+	if len(book.Sheets) != len(want) {
+		t.Fatalf("sheets=%d, want %d", len(book.Sheets), len(want))
+	}
+	for i, sheet := range book.Sheets {
+		got := map[string]string{}
+		for _, c := range sheet.Cells {
+			got[c.Ref] = c.Value
+		}
+		if diff := cmp.Diff(want[i], got); diff != "" {
+			t.Errorf("sheet %q cells mismatch (-want +got):\n%s", sheet.Name, diff)
+		}
+	}
+
+	// A fragment can use the components of the file it is included from
+	imports := gxl.Imports
+	gxl.Sheets = []model.SheetTag{{Name: "Main", Nodes: []any{model.IncludeTag{Src: "./parts/summary.gxl", Fragment: "TotalRow"}}}}
+	gxl.BookNodes, gxl.Imports = nil, nil
+	book, err = usecase.NewBookUsecase(conf).Render(context.Background(), &gxl, data)
+	if err != nil {
+		t.Fatalf("Render with include: %v", err)
+	}
+	if c := findCellByRef(book, "B1"); c == nil || c.Value != "30" {
+		t.Errorf("B1 = %+v, want the included fragment's total", c)
+	}
+
+	// The imported file's components are not visible to the importing template
+	gxl.Sheets = []model.SheetTag{{Name: "Main", Nodes: []any{model.UseTag{Component: "KV"}}}}
+	gxl.Imports = imports
+	if _, err := usecase.NewBookUsecase(conf).Render(context.Background(), &gxl, data); err == nil || !contains(err.Error(), "is not defined") {
+		t.Errorf("err = %v, want KV to be undefined in the importing template", err)
+	}
+}
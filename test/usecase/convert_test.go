@@ -0,0 +1,163 @@
+package usecase_test
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ryo-arima/goxcel/pkg/config"
+	"github.com/ryo-arima/goxcel/pkg/model"
+	parser "github.com/ryo-arima/goxcel/pkg/repository"
+	"github.com/ryo-arima/goxcel/pkg/usecase"
+	"github.com/ryo-arima/goxcel/pkg/util"
+)
+
+// convertFixture is a workbook with the cell types, styles and layout a converted template
+// has to reproduce
+func convertFixture() *model.Book {
+	header := &model.CellStyle{Bold: true, FontName: "Arial", FontSize: 14, FillColor: "DDEEFF", HAlign: "center", VAlign: "middle",
+		Border: &model.CellBorder{Style: "thin", Color: "445566", Bottom: true}}
+	money := &model.CellStyle{NumberFormat: `#,##0 "JPY"`, Border: &model.CellBorder{Style: "thin", Top: true, Right: true, Bottom: true, Left: true}}
+
+	invoice := model.NewSheet("Invoice")
+	invoice.Config.DefaultColumnWidth = 10
+	invoice.Config.ColumnWidths = []model.ColumnWidth{{Column: 1, Width: 24}, {Column: 3, Width: 12.5}}
+	for _, c := range []*model.Cell{
+		{Ref: "A1", Value: "Item", Type: model.CellTypeString, Style: header},
+		{Ref: "B1", Value: "Qty", Type: model.CellTypeString, Style: header},
+		{Ref: "C1", Value: "Price", Type: model.CellTypeString, Style: header},
+		{Ref: "A2", Value: "First item", Type: model.CellTypeString},
+		{Ref: "B2", Value: "2", Type: model.CellTypeNumber},
+		{Ref: "C2", Value: "1500", Type: model.CellTypeNumber, Style: money},
+		{Ref: "A3", Value: "a & <c>", Type: model.CellTypeString},
+		{Ref: "B3", Value: "0.25", Type: model.CellTypeNumber},
+		{Ref: "C3", Value: "=B3*C2", Type: model.CellTypeFormula, Style: money},
+		{Ref: "A5", Value: "00123", Type: model.CellTypeString},
+		{Ref: "B5", Value: "true", Type: model.CellTypeBoolean},
+		{Ref: "C5", Value: "  padded ", Type: model.CellTypeString},
+		{Ref: "D5", Value: "true", Type: model.CellTypeString},
+		{Ref: "E5", Type: model.CellTypeString, Style: header},
+	} {
+		invoice.AddCell(c)
+	}
+	invoice.AddMerge(model.Merge{Range: "D1:E1"})
+
+	notes := model.NewSheet("Notes & More")
+	notes.AddCell(&model.Cell{Ref: "B2", Value: `He said "hi"`, Type: model.CellTypeString, Style: &model.CellStyle{Italic: true, Underline: true, FontColor: "FF0000"}})
+	return &model.Book{Sheets: []*model.Sheet{invoice, notes}}
+}
+
+// renderTemplate parses and renders a converted template
+func renderTemplate(t *testing.T, gxl []byte) *model.Book {
+	t.Helper()
+	conf := config.NewBaseConfig()
+	tmpl, err := parser.ReadGxlFromReader(bytes.NewReader(gxl), conf.Logger)
+	if err != nil {
+		t.Fatalf("ReadGxlFromReader: %v\n%s", err, gxl)
+	}
+	book, err := usecase.NewBookUsecase(conf).Render(context.Background(), &tmpl, nil)
+	if err != nil {
+		t.Fatalf("Render: %v\n%s", err, gxl)
+	}
+	for _, sheet := range book.Sheets {
+		sort.Slice(sheet.Cells, func(i, j int) bool {
+			ri, ci := splitRef(sheet.Cells[i].Ref)
+			rj, cj := splitRef(sheet.Cells[j].Ref)
+			return ri < rj || ri == rj && (len(ci) < len(cj) || len(ci) == len(cj) && ci < cj)
+		})
+	}
+	return book
+}
+
+// splitRef returns the row number and column letters of an A1 reference
+func splitRef(ref string) (int, string) {
+	i := strings.IndexAny(ref, "0123456789")
+	row := 0
+	for _, ch := range ref[i:] {
+		row = row*10 + int(ch-'0')
+	}
+	return row, ref[:i]
+}
+
+func TestConvertBook_RoundTrip(t *testing.T) {
+	want := convertFixture()
+	gxl, err := usecase.NewConvertUsecase(config.NewBaseConfig()).ConvertBook(want)
+	if err != nil {
+		t.Fatalf("ConvertBook: %v", err)
+	}
+	got := renderTemplate(t, gxl)
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("round trip mismatch (-want +got):\n%s\ntemplate:\n%s", diff, gxl)
+	}
+
+	// Header cells share one grid, the body rows another
+	for _, fragment := range []string{
+		`<Grid ref="A1" font="Arial" font_size="14" fill_color="#DDEEFF" bold="true" h_align="center" v_align="middle" border="thin" border_color="#445566" border_sides="bottom">`,
+		`<Grid ref="A2">`,
+		`| a &amp; &lt;c&gt; | 0.25 |`,
+		`| {{ "00123":string }} | true | {{ "  padded ":string }} | {{ "true":string }} |`,
+		`<Column index="3" width="12.5" />`,
+		`<Merge range="D1:E1" />`,
+		`<Sheet name="Notes &amp; More">`,
+	} {
+		if !strings.Contains(string(gxl), fragment) {
+			t.Errorf("template does not contain %s:\n%s", fragment, gxl)
+		}
+	}
+}
+
+func TestConvertBook_ExpressionText(t *testing.T) {
+	sheet := model.NewSheet("Text")
+	for _, c := range []*model.Cell{
+		{Ref: "A1", Value: "{{ x }}", Type: model.CellTypeString},
+		{Ref: "B1", Value: "Total: {{ total }} }}", Type: model.CellTypeString},
+		{Ref: "C1", Value: "{{{x}}}", Type: model.CellTypeString},
+		{Ref: "D1", Value: " {{ x ", Type: model.CellTypeString},
+	} {
+		sheet.AddCell(c)
+	}
+	want := &model.Book{Sheets: []*model.Sheet{sheet}}
+	gxl, err := usecase.NewConvertUsecase(config.NewBaseConfig()).ConvertBook(want)
+	if err != nil {
+		t.Fatalf("ConvertBook: %v", err)
+	}
+	if diff := cmp.Diff(want, renderTemplate(t, gxl)); diff != "" {
+		t.Errorf("round trip mismatch (-want +got):\n%s\ntemplate:\n%s", diff, gxl)
+	}
+}
+
+func TestConvertBook_TextAGridCannotHold(t *testing.T) {
+	for _, value := range []string{"a|b", "**not bold**", " {{ x }} "} {
+		sheet := model.NewSheet("Text")
+		sheet.AddCell(&model.Cell{Ref: "B2", Value: value, Type: model.CellTypeString})
+		_, err := usecase.NewConvertUsecase(config.NewBaseConfig()).ConvertBook(&model.Book{Sheets: []*model.Sheet{sheet}})
+		if err == nil || !strings.Contains(err.Error(), `sheet "Text", cell B2`) {
+			t.Errorf("ConvertBook(%q) err = %v, want an error naming the cell", value, err)
+		}
+	}
+}
+
+func TestConvert_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.xlsx")
+	want := convertFixture()
+	if err := parser.WriteBookToFile(want, path); err != nil {
+		t.Fatalf("WriteBookToFile: %v", err)
+	}
+	conf := config.NewBaseConfig()
+	conf.Logger = util.NewLogger(util.LoggerConfig{Component: "test", Service: "convert", Level: "ERROR", Output: "stderr"})
+	gxl, err := usecase.NewConvertUsecase(conf).Convert(path)
+	if err != nil {
+		t.Fatalf("Convert: %v", err)
+	}
+	if diff := cmp.Diff(want, renderTemplate(t, gxl)); diff != "" {
+		t.Errorf("round trip mismatch (-want +got):\n%s\ntemplate:\n%s", diff, gxl)
+	}
+
+	if _, err := usecase.NewConvertUsecase(conf).Convert(filepath.Join(t.TempDir(), "missing.xlsx")); err == nil {
+		t.Error("expected an error for a missing workbook")
+	}
+}
@@ -0,0 +1,97 @@
+package usecase_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ryo-arima/goxcel/pkg/model"
+	"github.com/ryo-arima/goxcel/pkg/usecase"
+)
+
+func TestMergeData_DeepMerge(t *testing.T) {
+	dst := map[string]any{
+		"company": map[string]any{"name": "Acme", "address": map[string]any{"city": "Tokyo", "zip": "100"}},
+		"tags":    []any{"a", "b"},
+	}
+	src := map[string]any{
+		"company": map[string]any{"address": map[string]any{"city": "Osaka"}},
+		"tags":    []any{"c"},
+	}
+	got := usecase.MergeData(dst, src)
+	want := map[string]any{
+		"company": map[string]any{"name": "Acme", "address": map[string]any{"city": "Osaka", "zip": "100"}},
+		"tags":    []any{"c"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("merge mismatch (-want +got):\n%s", diff)
+	}
+	if dst["company"].(map[string]any)["address"].(map[string]any)["city"] != "Tokyo" {
+		t.Errorf("dst was modified: %v", dst)
+	}
+}
+
+func TestParseSetExpression_AndSetDataValue(t *testing.T) {
+	data := map[string]any{"invoice": "replaced"}
+	for _, tc := range []struct {
+		expr string
+		raw  bool
+	}{
+		{expr: "invoice.total=120"},
+		{expr: "invoice.paid=true"},
+		{expr: "invoice.lines=[1, 2]"},
+		{expr: "invoice.number=0012", raw: true},
+		{expr: "invoice.note="},
+	} {
+		path, value, err := usecase.ParseSetExpression(tc.expr, tc.raw)
+		if err != nil {
+			t.Fatalf("ParseSetExpression(%q): %v", tc.expr, err)
+		}
+		if data, err = usecase.SetDataValue(data, path, value); err != nil {
+			t.Fatalf("SetDataValue(%q): %v", path, err)
+		}
+	}
+	want := map[string]any{"invoice": map[string]any{
+		"total":  120,
+		"paid":   true,
+		"lines":  []any{1, 2},
+		"number": "0012",
+		"note":   "",
+	}}
+	if diff := cmp.Diff(want, data); diff != "" {
+		t.Fatalf("data mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, _, err := usecase.ParseSetExpression("novalue", false); err == nil {
+		t.Error("expected error for override without '='")
+	}
+	if _, err := usecase.SetDataValue(data, "a..b", 1); err == nil {
+		t.Error("expected error for empty path segment")
+	}
+}
+
+// TestParseSetExpressionForParams tests that values of string and date parameters stay text
+func TestParseSetExpressionForParams(t *testing.T) {
+	params := []model.ParamTag{
+		{Name: "invoice.code", Type: model.ParamTypeString},
+		{Name: "invoice.date", Type: model.ParamTypeDate},
+		{Name: "invoice.qty", Type: model.ParamTypeInteger},
+	}
+	for _, tc := range []struct {
+		expr string
+		want any
+	}{
+		{expr: "invoice.code=007", want: "007"},
+		{expr: "invoice.code = true", want: " true"},
+		{expr: "invoice.date=2024-05-01", want: "2024-05-01"},
+		{expr: "invoice.qty=007", want: 7},
+		{expr: "other=007", want: 7},
+	} {
+		_, value, err := usecase.ParseSetExpressionForParams(tc.expr, params)
+		if err != nil {
+			t.Fatalf("ParseSetExpressionForParams(%q): %v", tc.expr, err)
+		}
+		if diff := cmp.Diff(tc.want, value); diff != "" {
+			t.Errorf("%q: value mismatch (-want +got):\n%s", tc.expr, diff)
+		}
+	}
+}
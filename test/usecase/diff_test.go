@@ -0,0 +1,177 @@
+package usecase_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ryo-arima/goxcel/pkg/config"
+	"github.com/ryo-arima/goxcel/pkg/model"
+	"github.com/ryo-arima/goxcel/pkg/usecase"
+)
+
+func TestDiff_Equal(t *testing.T) {
+	got, err := usecase.NewDiffUsecase(config.NewBaseConfig()).Diff(convertFixture(), convertFixture())
+	if err != nil {
+		t.Fatalf("Diff: %v", err)
+	}
+	if !got.Equal() {
+		t.Errorf("expected no differences, got %v", got.Changes)
+	}
+}
+
+func TestDiff_Changes(t *testing.T) {
+	a, b := convertFixture(), convertFixture()
+	a.Sheets[0].Config.RowHeights = []model.RowHeight{{Row: 1, Height: 28}}
+	invoice := b.Sheets[0]
+	invoice.Config.ColumnWidths[1].Width = 14
+	invoice.Config.FreezePane = "A2"
+	invoice.Merges = []model.Merge{{Range: "D1:F1"}}
+	for _, cell := range invoice.Cells {
+		switch cell.Ref {
+		case "B2":
+			cell.Value = "3"
+		case "C3":
+			cell.Value = "=B3*C2*2"
+		case "A5":
+			cell.Type = model.CellTypeNumber
+		case "A1":
+			style := *cell.Style
+			style.Bold, style.FillColor = false, "FFFFFF"
+			cell.Style = &style
+		}
+	}
+	invoice.AddCell(&model.Cell{Ref: "A6", Value: "new", Type: model.CellTypeString})
+	b.Sheets = []*model.Sheet{invoice, model.NewSheet("Summary")}
+
+	got, err := usecase.NewDiffUsecase(config.NewBaseConfig()).Diff(a, b)
+	if err != nil {
+		t.Fatalf("Diff: %v", err)
+	}
+	want := []usecase.Change{
+		{Op: "changed", Sheet: "Invoice", Target: "sheet", Field: "freeze_pane", B: "A2"},
+		{Op: "changed", Sheet: "Invoice", Target: "sheet", Field: "used_range", A: "A1:E5", B: "A1:E6"},
+		{Op: "changed", Sheet: "Invoice", Target: "column", Ref: "C", Field: "width", A: "12.5", B: "14"},
+		{Op: "removed", Sheet: "Invoice", Target: "row", Ref: "1", Field: "height", A: "28"},
+		{Op: "removed", Sheet: "Invoice", Target: "cell", Ref: "A1", Field: "style.bold", A: "true"},
+		{Op: "changed", Sheet: "Invoice", Target: "cell", Ref: "A1", Field: "style.fill_color", A: "#DDEEFF", B: "#FFFFFF"},
+		{Op: "changed", Sheet: "Invoice", Target: "cell", Ref: "B2", Field: "value", A: "2", B: "3"},
+		{Op: "changed", Sheet: "Invoice", Target: "cell", Ref: "C3", Field: "formula", A: "=B3*C2", B: "=B3*C2*2"},
+		{Op: "changed", Sheet: "Invoice", Target: "cell", Ref: "A5", Field: "type", A: "string", B: "number"},
+		{Op: "added", Sheet: "Invoice", Target: "cell", Ref: "A6", Field: "value", B: "new"},
+		{Op: "removed", Sheet: "Invoice", Target: "merge", Ref: "D1:E1"},
+		{Op: "added", Sheet: "Invoice", Target: "merge", Ref: "D1:F1"},
+		{Op: "removed", Sheet: "Notes & More", Target: "sheet"},
+		{Op: "added", Sheet: "Summary", Target: "sheet"},
+	}
+	if diff := cmp.Diff(want, got.Changes); diff != "" {
+		t.Errorf("changes mismatch (-want +got):\n%s", diff)
+	}
+
+	// The text form of each kind of change
+	for i, line := range []string{
+		`~ Invoice freeze_pane: (none) -> "A2"`,
+		`~ Invoice used_range: "A1:E5" -> "A1:E6"`,
+		`~ Invoice column C width: "12.5" -> "14"`,
+		`- Invoice row 1 height: "28"`,
+		`- Invoice!A1 style.bold: "true"`,
+		`~ Invoice!A1 style.fill_color: "#DDEEFF" -> "#FFFFFF"`,
+	} {
+		if got := got.Changes[i].String(); got != line {
+			t.Errorf("change %d = %s, want %s", i, got, line)
+		}
+	}
+	for i, line := range map[int]string{
+		9:  `+ Invoice!A6 value: "new"`,
+		10: `- Invoice merge D1:E1`,
+		12: `- sheet "Notes & More"`,
+		13: `+ sheet "Summary"`,
+	} {
+		if got := got.Changes[i].String(); got != line {
+			t.Errorf("change %d = %s, want %s", i, got, line)
+		}
+	}
+
+	if _, err := usecase.NewDiffUsecase(config.NewBaseConfig()).Diff(a, nil); err == nil {
+		t.Error("expected an error for a nil workbook")
+	}
+}
+
+func TestDiff_OneSidedCells(t *testing.T) {
+	a, b := convertFixture(), convertFixture()
+	invoice := b.Sheets[0]
+	var kept []*model.Cell
+	for _, cell := range invoice.Cells {
+		switch cell.Ref {
+		case "A1", "C3", "E5":
+		default:
+			kept = append(kept, cell)
+		}
+	}
+	invoice.Cells = kept
+	invoice.AddCell(&model.Cell{Ref: "D2", Value: "7", Type: model.CellTypeNumber, Style: &model.CellStyle{Bold: true}})
+	invoice.AddCell(&model.Cell{Ref: "E2", Type: model.CellTypeString, Style: &model.CellStyle{FillColor: "FFFF00"}})
+
+	got, err := usecase.NewDiffUsecase(config.NewBaseConfig()).Diff(a, b)
+	if err != nil {
+		t.Fatalf("Diff: %v", err)
+	}
+
+	// One change per cell, whatever its type and style
+	want := []usecase.Change{
+		{Op: "removed", Sheet: "Invoice", Target: "cell", Ref: "A1", Field: "value", A: "Item"},
+		{Op: "added", Sheet: "Invoice", Target: "cell", Ref: "D2", Field: "value", B: "7"},
+		{Op: "added", Sheet: "Invoice", Target: "cell", Ref: "E2"},
+		{Op: "removed", Sheet: "Invoice", Target: "cell", Ref: "C3", Field: "formula", A: "=B3*C2"},
+		{Op: "removed", Sheet: "Invoice", Target: "cell", Ref: "E5"},
+	}
+	if diff := cmp.Diff(want, got.Changes); diff != "" {
+		t.Errorf("changes mismatch (-want +got):\n%s", diff)
+	}
+	for i, line := range []string{
+		`- Invoice!A1 value: "Item"`,
+		`+ Invoice!D2 value: "7"`,
+		`+ Invoice!E2`,
+		`- Invoice!C3 formula: "=B3*C2"`,
+		`- Invoice!E5`,
+	} {
+		if i < len(got.Changes) && got.Changes[i].String() != line {
+			t.Errorf("change %d = %s, want %s", i, got.Changes[i].String(), line)
+		}
+	}
+}
+
+func TestDiff_SheetPositions(t *testing.T) {
+	book := func(names ...string) *model.Book {
+		b := &model.Book{}
+		for _, name := range names {
+			b.Sheets = append(b.Sheets, model.NewSheet(name))
+		}
+		return b
+	}
+	for _, tt := range []struct {
+		name string
+		a, b *model.Book
+		want []usecase.Change
+	}{
+		{"removed first", book("Cover", "Jan", "Feb", "Mar"), book("Jan", "Feb", "Mar"),
+			[]usecase.Change{{Op: "removed", Sheet: "Cover", Target: "sheet"}}},
+		{"added and removed", book("Jan", "Feb", "Mar"), book("Summary", "Jan", "Mar", "Apr"), []usecase.Change{
+			{Op: "removed", Sheet: "Feb", Target: "sheet"},
+			{Op: "added", Sheet: "Summary", Target: "sheet"},
+			{Op: "added", Sheet: "Apr", Target: "sheet"},
+		}},
+		{"moved to the end", book("Summary", "Jan", "Feb", "Mar"), book("Jan", "Feb", "Mar", "Summary"), []usecase.Change{
+			{Op: "changed", Sheet: "Summary", Target: "sheet", Field: "position", A: "1", B: "4"},
+		}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := usecase.NewDiffUsecase(config.NewBaseConfig()).Diff(tt.a, tt.b)
+			if err != nil {
+				t.Fatalf("Diff: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got.Changes); diff != "" {
+				t.Errorf("changes mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
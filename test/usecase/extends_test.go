@@ -0,0 +1,133 @@
+package usecase_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ryo-arima/goxcel/pkg/config"
+	"github.com/ryo-arima/goxcel/pkg/model"
+	parser "github.com/ryo-arima/goxcel/pkg/repository"
+	"github.com/ryo-arima/goxcel/pkg/usecase"
+)
+
+func renderTemplateFile(t *testing.T, name string, data map[string]any) (*model.Book, error) {
+	t.Helper()
+	conf := config.NewBaseConfigWithFile(filepath.Join("..", ".testdata", name))
+	gxl, err := parser.NewGxlRepository(conf).ReadGxl()
+	if err != nil {
+		t.Fatalf("ReadGxl: %v", err)
+	}
+	return usecase.NewBookUsecase(conf).Render(context.Background(), &gxl, data)
+}
+
+func sheetValues(s *model.Sheet) map[string]string {
+	values := map[string]string{}
+	for _, c := range s.Cells {
+		values[c.Ref] = c.Value
+	}
+	return values
+}
+
+// TestExtends_OverridesBlocks tests that child blocks replace base placeholders and child sheets follow
+func TestExtends_OverridesBlocks(t *testing.T) {
+	data := map[string]any{
+		"title": "Q1",
+		"rows": []any{
+			map[string]any{"region": "East", "amount": 10},
+			map[string]any{"region": "West", "amount": 20},
+		},
+	}
+	for _, tc := range []struct {
+		file   string
+		header string
+	}{
+		{file: "extends_sales.gxl", header: "Acme Corp"},
+		{file: "extends_sales_team.gxl", header: "Team"},
+	} {
+		t.Run(tc.file, func(t *testing.T) {
+			book, err := renderTemplateFile(t, tc.file, data)
+			if err != nil {
+				t.Fatalf("Render: %v", err)
+			}
+			if diff := cmp.Diff([]string{"Report", "Notes"}, sheetNames(book)); diff != "" {
+				t.Fatalf("sheet names mismatch (-want +got):\n%s", diff)
+			}
+			want := map[string]string{
+				"A1": tc.header, "B1": "Q1",
+				"A2": "East", "B2": "10",
+				"A3": "West", "B3": "20",
+				"A4": "Confidential",
+			}
+			if diff := cmp.Diff(want, sheetValues(book.Sheets[0])); diff != "" {
+				t.Fatalf("cells mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// TestExtends_RebasesImages tests that images of the base template resolve from the base's directory
+func TestExtends_RebasesImages(t *testing.T) {
+	book, err := renderTemplateFile(t, "extends_sales.gxl", map[string]any{"title": "Q1"})
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if len(book.Sheets[0].Images) != 1 {
+		t.Fatalf("images = %d, want 1", len(book.Sheets[0].Images))
+	}
+	want, err := filepath.Abs(filepath.Join("..", ".testdata", "extends", "logo.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := book.Sheets[0].Images[0].Source; got != want {
+		t.Errorf("image source = %q, want %q", got, want)
+	}
+}
+
+// TestExtends_MergesSchema tests that a base template's schema applies unless the child declares one
+func TestExtends_MergesSchema(t *testing.T) {
+	baseDir, err := filepath.Abs(filepath.Join("..", ".testdata", "extends"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := filepath.Join("..", ".testdata", "extends", "schema_base.gxl")
+	for _, tc := range []struct {
+		name   string
+		schema string
+		want   string
+	}{
+		{name: "inherited", want: filepath.Join(baseDir, "report.schema.json")},
+		{name: "overridden", schema: "child.schema.json", want: "child.schema.json"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			gxl := &model.GXL{
+				HeaderTag: model.HeaderTag{Schema: tc.schema},
+				BookTag:   model.BookTag{Extends: base},
+			}
+			merged, err := usecase.NewBookUsecase(config.NewBaseConfig()).ResolveExtends(gxl)
+			if err != nil {
+				t.Fatalf("ResolveExtends: %v", err)
+			}
+			if merged.HeaderTag.Schema != tc.want {
+				t.Errorf("schema = %q, want %q", merged.HeaderTag.Schema, tc.want)
+			}
+		})
+	}
+}
+
+// TestExtends_Errors tests unknown blocks and circular inheritance
+func TestExtends_Errors(t *testing.T) {
+	if _, err := renderTemplateFile(t, filepath.Join("extends", "cycle_a.gxl"), nil); err == nil || !contains(err.Error(), "circular import detected") {
+		t.Errorf("err = %v, want circular import error", err)
+	}
+
+	gxl := &model.GXL{
+		BookTag: model.BookTag{Extends: filepath.Join("..", ".testdata", "extends", "base.gxl")},
+		Blocks:  []model.BlockTag{{Name: "sidebar"}},
+	}
+	_, err := usecase.NewBookUsecase(config.NewBaseConfig()).Render(context.Background(), gxl, nil)
+	if err == nil || !contains(err.Error(), "block sidebar not defined") {
+		t.Errorf("err = %v, want unknown block error", err)
+	}
+}
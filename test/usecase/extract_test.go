@@ -0,0 +1,241 @@
+package usecase_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ryo-arima/goxcel/pkg/config"
+	"github.com/ryo-arima/goxcel/pkg/model"
+	parser "github.com/ryo-arima/goxcel/pkg/repository"
+	"github.com/ryo-arima/goxcel/pkg/usecase"
+	"github.com/ryo-arima/goxcel/pkg/util"
+)
+
+// extractTemplate is an order form with single fields, loops over rows and columns and
+// one sheet per region
+const extractTemplate = `<Book>
+<Sheet name="Order">
+  <Grid>
+    | **Order {{ order.id }}** | Date: {{ order.date:date }} |
+    | Customer | {{ customer.name }} |
+    | Code | {{ customer.code:string }} |
+    | Rush | {{ order.rush }} |
+  </Grid>
+  <Anchor ref="A7" />
+  <Grid>
+    | # | Item | Qty | Price |
+  </Grid>
+  <For each="line in lines">
+    <Grid>
+      | {{ loop.number }} | {{ line.name }} | {{ line.qty }} | {{ line.price }} |
+    </Grid>
+  </For>
+  <Grid>
+    | Total | | | {{ total }} |
+  </Grid>
+  <Table>
+    <Row each="tag in tags">
+      <Col>{{ tag }}</Col>
+    </Row>
+  </Table>
+</Sheet>
+<For each="region in regions">
+  <Sheet name="Region {{ region.name }}">
+    <Grid ref="B2">
+      | {{ region.sales }} |
+    </Grid>
+  </Sheet>
+</For>
+</Book>`
+
+// extractData is the data rendered with extractTemplate, in the types extraction reads back
+func extractData() map[string]any {
+	return map[string]any{
+		"order":    map[string]any{"id": float64(42), "date": "2024-05-01", "rush": true},
+		"customer": map[string]any{"name": "Acme Ltd.", "code": "00123"},
+		"lines": []any{
+			map[string]any{"name": "Widget", "qty": float64(2), "price": 9.5},
+			map[string]any{"name": "Gadget", "qty": float64(1), "price": float64(120)},
+			map[string]any{"name": "Gizmo", "qty": float64(10), "price": 0.25},
+		},
+		"total": 141.5,
+		"tags":  []any{"new", "priority"},
+		"regions": []any{
+			map[string]any{"name": "East", "sales": float64(100)},
+			map[string]any{"name": "West", "sales": float64(250)},
+		},
+	}
+}
+
+func renderExtractTemplate(t *testing.T, data map[string]any) (*model.GXL, *model.Book) {
+	t.Helper()
+	conf := config.NewBaseConfig()
+	tmpl, err := parser.ReadGxlFromReader(strings.NewReader(extractTemplate), conf.Logger)
+	if err != nil {
+		t.Fatalf("ReadGxlFromReader: %v", err)
+	}
+	book, err := usecase.NewBookUsecase(conf).Render(context.Background(), &tmpl, data)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	return &tmpl, book
+}
+
+func TestExtractBook_RoundTrip(t *testing.T) {
+	want := extractData()
+	tmpl, book := renderExtractTemplate(t, want)
+	got, err := usecase.NewExtractUsecase(config.NewBaseConfig()).ExtractBook(tmpl, book)
+	if err != nil {
+		t.Fatalf("ExtractBook: %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("extracted data mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestExtractBook_FilledIn(t *testing.T) {
+	conf := config.NewBaseConfig()
+	tmpl, err := parser.ReadGxlFromReader(strings.NewReader(`<Book><Sheet name="Form">
+  <Grid>
+    | Name | {{ name }} |
+    | Note | {{ note }} |
+  </Grid>
+  <For each="row in rows">
+    <Grid>
+      | {{ row.item }} | {{ row.qty:number }} |
+    </Grid>
+  </For>
+</Sheet></Book>`), conf.Logger)
+	if err != nil {
+		t.Fatalf("ReadGxlFromReader: %v", err)
+	}
+	book, err := usecase.NewBookUsecase(conf).Render(context.Background(), &tmpl, map[string]any{})
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+
+	// The blank form is filled in by hand; the loop stops at the first empty row
+	for _, cell := range []*model.Cell{
+		{Ref: "B1", Value: "Ann", Type: model.CellTypeString},
+		{Ref: "A3", Value: "Bolt", Type: model.CellTypeString},
+		{Ref: "B3", Value: "100", Type: model.CellTypeNumber},
+		{Ref: "A4", Value: "Nut", Type: model.CellTypeString},
+		{Ref: "B4", Value: "many", Type: model.CellTypeString},
+		{Ref: "A6", Value: "Signed", Type: model.CellTypeString},
+	} {
+		book.Sheets[0].AddCell(cell)
+	}
+
+	got, err := usecase.NewExtractUsecase(conf).ExtractBook(&tmpl, book)
+	if err != nil {
+		t.Fatalf("ExtractBook: %v", err)
+	}
+	want := map[string]any{
+		"name": "Ann",
+		"rows": []any{
+			map[string]any{"item": "Bolt", "qty": float64(100)},
+			map[string]any{"item": "Nut", "qty": "many"},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("extracted data mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestExtractBook_LoopBeforeFooter(t *testing.T) {
+	conf := config.NewBaseConfig()
+	tmpl, err := parser.ReadGxlFromReader(strings.NewReader(`<Book><Sheet name="Lines">
+  <Grid>
+    | Item | Qty |
+  </Grid>
+  <For each="line in lines">
+    <Grid>
+      | {{ line.name }} | {{ line.qty }} |
+    </Grid>
+  </For>
+  <Grid>
+    | Total | {{ total }} |
+  </Grid>
+</Sheet></Book>`), conf.Logger)
+	if err != nil {
+		t.Fatalf("ReadGxlFromReader: %v", err)
+	}
+
+	// The footer row is not read as one more item, with or without items before it
+	for _, want := range []map[string]any{
+		{"total": float64(0)},
+		{
+			"lines": []any{
+				map[string]any{"name": "Widget", "qty": float64(2)},
+				map[string]any{"name": "Gadget", "qty": float64(1)},
+			},
+			"total": float64(3),
+		},
+	} {
+		data := map[string]any{"lines": []any{}}
+		for k, v := range want {
+			data[k] = v
+		}
+		book, err := usecase.NewBookUsecase(conf).Render(context.Background(), &tmpl, data)
+		if err != nil {
+			t.Fatalf("Render: %v", err)
+		}
+		got, err := usecase.NewExtractUsecase(conf).ExtractBook(&tmpl, book)
+		if err != nil {
+			t.Fatalf("ExtractBook: %v", err)
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("extracted data mismatch (-want +got):\n%s", diff)
+		}
+	}
+}
+
+func TestExtractBook_InvalidGridRef(t *testing.T) {
+	gxl := &model.GXL{Sheets: []model.SheetTag{{Name: "S", Nodes: []any{
+		model.GridTag{Ref: "1A", Rows: []model.GridRowTag{{Cells: []string{"{{ name }}"}}}},
+	}}}}
+	book := &model.Book{Sheets: []*model.Sheet{model.NewSheet("S")}}
+	book.Sheets[0].AddCell(&model.Cell{Ref: "A1", Value: "Ann", Type: model.CellTypeString})
+
+	// The grid is not read at some other position
+	_, err := usecase.NewExtractUsecase(config.NewBaseConfig()).ExtractBook(gxl, book)
+	if err == nil || !strings.Contains(err.Error(), `invalid grid ref "1A"`) {
+		t.Errorf("err = %v, want an invalid grid ref error", err)
+	}
+}
+
+func TestExtract_File(t *testing.T) {
+	dir := t.TempDir()
+	want := extractData()
+	_, book := renderExtractTemplate(t, want)
+	xlsxPath := filepath.Join(dir, "order.xlsx")
+	if err := parser.WriteBookToFile(book, xlsxPath); err != nil {
+		t.Fatalf("WriteBookToFile: %v", err)
+	}
+	tmplPath := filepath.Join(dir, "order.gxl")
+	if err := os.WriteFile(tmplPath, []byte(extractTemplate), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	logPath := filepath.Join(dir, "extract.log")
+	conf := config.NewBaseConfig()
+	conf.Logger = util.NewLogger(util.LoggerConfig{Component: "test", Service: "extract", Level: "WARN", Output: logPath})
+	got, err := usecase.NewExtractUsecase(conf).Extract(tmplPath, xlsxPath)
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("extracted data mismatch (-want +got):\n%s", diff)
+	}
+	if logs, _ := os.ReadFile(logPath); len(logs) > 0 {
+		t.Errorf("unexpected warnings:\n%s", logs)
+	}
+
+	if _, err := usecase.NewExtractUsecase(conf).Extract(tmplPath, filepath.Join(dir, "missing.xlsx")); err == nil {
+		t.Error("expected an error for a missing workbook")
+	}
+}
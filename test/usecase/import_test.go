@@ -2,14 +2,19 @@ package usecase_test
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io/fs"
 	"path/filepath"
+	"strings"
 	"testing"
+	"testing/fstest"
 
+	"github.com/google/go-cmp/cmp"
 	"github.com/ryo-arima/goxcel/pkg/config"
 	"github.com/ryo-arima/goxcel/pkg/model"
 	parser "github.com/ryo-arima/goxcel/pkg/repository"
 	"github.com/ryo-arima/goxcel/pkg/usecase"
-	"github.com/ryo-arima/goxcel/pkg/util"
 )
 
 // TestImport_BasicExpansion tests that Import expands nodes from external file
@@ -31,23 +36,24 @@ func TestImport_BasicExpansion(t *testing.T) {
 	// Render the book with import resolution
 	bookUc := usecase.NewBookUsecase(conf)
 	ctx := context.Background()
-	book, err := bookUc.RenderBook(ctx, &gxl, map[string]any{})
+	book, err := bookUc.Render(ctx, &gxl, map[string]any{})
 	if err != nil {
 		t.Fatalf("RenderBook: %v", err)
 	}
 	
-	if len(book.Sheets) != 1 {
-		t.Fatalf("rendered sheets=%d, want 1", len(book.Sheets))
+	// The import creates its own sheet, ahead of the sheet defined in the main file
+	if len(book.Sheets) != 2 {
+		t.Fatalf("rendered sheets=%d, want 2", len(book.Sheets))
+	}
+	if book.Sheets[0].Name != "Headers" || book.Sheets[1].Name != "Report" {
+		t.Fatalf("sheet names=%q, %q, want Headers, Report", book.Sheets[0].Name, book.Sheets[1].Name)
 	}
 	
 	sheet := book.Sheets[0]
 	
-	// Should have cells from both main file and imported file
 	// Imported file has a 3x2 grid (header + 1 row)
-	// Main file has a 2x2 grid
-	// Total should be at least 5 cells
-	if len(sheet.Cells) < 5 {
-		t.Errorf("cells=%d, want at least 5 (from main + imported)", len(sheet.Cells))
+	if len(sheet.Cells) < 6 {
+		t.Errorf("cells=%d, want at least 6 (from the imported grid)", len(sheet.Cells))
 	}
 	
 	// Check that imported content is present
@@ -66,6 +72,8 @@ func TestImport_BasicExpansion(t *testing.T) {
 
 // TestImport_CircularDetection tests that circular imports are detected
 func TestImport_CircularDetection(t *testing.T) {
+	// Sheet B of import_circular_b.gxl includes a fragment of import_circular_a.gxl, which
+	// includes a fragment of import_circular_b.gxl again
 	path := filepath.Join("..", ".testdata", "import_circular_a.gxl")
 	conf := config.NewBaseConfigWithFile(path)
 	
@@ -78,7 +86,7 @@ func TestImport_CircularDetection(t *testing.T) {
 	// Try to render - should fail with circular import error
 	bookUc := usecase.NewBookUsecase(conf)
 	ctx := context.Background()
-	_, err = bookUc.RenderBook(ctx, &gxl, map[string]any{})
+	_, err = bookUc.Render(ctx, &gxl, map[string]any{})
 	if err == nil {
 		t.Fatal("expected circular import error, got nil")
 	}
@@ -126,7 +134,7 @@ func TestImport_RelativePathResolution(t *testing.T) {
 	// Render should successfully resolve relative import
 	bookUc := usecase.NewBookUsecase(conf)
 	ctx := context.Background()
-	_, err = bookUc.RenderBook(ctx, &gxl, map[string]any{})
+	_, err = bookUc.Render(ctx, &gxl, map[string]any{})
 	if err != nil {
 		t.Fatalf("RenderBook with relative import failed: %v", err)
 	}
@@ -148,7 +156,7 @@ func TestImport_MultipleImports(t *testing.T) {
 	
 	bookUc := usecase.NewBookUsecase(conf)
 	ctx := context.Background()
-	book, err := bookUc.RenderBook(ctx, &gxl, map[string]any{})
+	book, err := bookUc.Render(ctx, &gxl, map[string]any{})
 	if err != nil {
 		t.Fatalf("RenderBook: %v", err)
 	}
@@ -173,3 +181,299 @@ func findSubstr(s, substr string) bool {
 	}
 	return false
 }
+
+// TestImport_ScopedDataAndRename tests <Import as="..." data="..."> and sheet="*"
+func TestImport_ScopedDataAndRename(t *testing.T) {
+	path := filepath.Join("..", ".testdata", "import_scoped.gxl")
+	conf := config.NewBaseConfigWithFile(path)
+	gxl, err := parser.NewGxlRepository(conf).ReadGxl()
+	if err != nil {
+		t.Fatalf("ReadGxl: %v", err)
+	}
+
+	data := map[string]any{
+		"company": "Acme",
+		"quarters": map[string]any{
+			"q1": map[string]any{"label": "Q1", "total": 100, "company": "Q1 Corp"},
+			"q2": map[string]any{"label": "Q2", "total": 200},
+		},
+	}
+	book, err := usecase.NewBookUsecase(conf).Render(context.Background(), &gxl, data)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+
+	var names []string
+	for _, s := range book.Sheets {
+		names = append(names, s.Name)
+	}
+	if diff := cmp.Diff([]string{"Q1 Summary", "Q2 Summary", "Headers"}, names); diff != "" {
+		t.Fatalf("sheet names mismatch (-want +got):\n%s", diff)
+	}
+
+	// The scoped sheets do not see the top-level company
+	for i, want := range []map[string]string{
+		{"B1": "Q1", "B2": "100", "B3": "Q1 Corp"},
+		{"B1": "Q2", "B2": "200", "B3": ""},
+	} {
+		got := map[string]string{}
+		for _, c := range book.Sheets[i].Cells {
+			if _, ok := want[c.Ref]; ok {
+				got[c.Ref] = c.Value
+			}
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("sheet %d cells mismatch (-want +got):\n%s", i, diff)
+		}
+	}
+}
+
+// TestImport_DataPathErrors tests that invalid data paths are reported
+func TestImport_DataPathErrors(t *testing.T) {
+	src := filepath.Join("..", ".testdata", "import_quarter.gxl")
+	for _, tc := range []struct {
+		data    map[string]any
+		wantErr string
+	}{
+		{data: map[string]any{}, wantErr: "not found"},
+		{data: map[string]any{"q": []any{1}}, wantErr: "must resolve to an object"},
+	} {
+		gxl := &model.GXL{BookNodes: []model.BookNode{{
+			Type:   model.BookNodeTypeImport,
+			Import: &model.ImportTag{Src: src, Sheet: "Summary", Data: "q"},
+		}}}
+		_, err := usecase.NewBookUsecase(config.NewBaseConfig()).Render(context.Background(), gxl, tc.data)
+		if err == nil || !contains(err.Error(), tc.wantErr) {
+			t.Errorf("err = %v, want containing %q", err, tc.wantErr)
+		}
+	}
+}
+
+// TestInclude_FragmentAtCursor tests that <Include> splices a fragment at the current position
+func TestInclude_FragmentAtCursor(t *testing.T) {
+	path := filepath.Join("..", ".testdata", "include_main.gxl")
+	conf := config.NewBaseConfigWithFile(path)
+	gxl, err := parser.NewGxlRepository(conf).ReadGxl()
+	if err != nil {
+		t.Fatalf("ReadGxl: %v", err)
+	}
+
+	data := map[string]any{
+		"number":   "INV-001",
+		"total":    42,
+		"customer": map[string]any{"name": "Acme", "city": "Tokyo"},
+	}
+	book, err := usecase.NewBookUsecase(conf).Render(context.Background(), &gxl, data)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+
+	got := map[string]string{}
+	for _, c := range book.Sheets[0].Cells {
+		got[c.Ref] = c.Value
+	}
+	want := map[string]string{
+		"A1": "Invoice", "B1": "INV-001",
+		"A2": "Customer", "B2": "Acme",
+		"A3": "City", "B3": "Tokyo",
+		"A4": "Total", "B4": "42",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("cells mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// TestInclude_Errors tests missing fragments, invalid scopes and circular includes
+func TestInclude_Errors(t *testing.T) {
+	src := filepath.Join("..", ".testdata", "parts", "invoice_parts.gxl")
+	for _, tc := range []struct {
+		tag     model.IncludeTag
+		wantErr string
+	}{
+		{tag: model.IncludeTag{Src: src, Fragment: "Missing"}, wantErr: "not found"},
+		{tag: model.IncludeTag{Src: src, Fragment: "InvoiceHeader", With: "nope"}, wantErr: "must resolve to an object"},
+		{tag: model.IncludeTag{Src: src, Fragment: "Loop"}, wantErr: "circular import detected"},
+	} {
+		gxl := &model.GXL{Sheets: []model.SheetTag{{Name: "S", Nodes: []any{tc.tag}}}}
+		_, err := usecase.NewBookUsecase(config.NewBaseConfig()).Render(context.Background(), gxl, map[string]any{})
+		if err == nil || !contains(err.Error(), tc.wantErr) {
+			t.Errorf("%s: err = %v, want containing %q", tc.tag.Fragment, err, tc.wantErr)
+		}
+	}
+}
+
+// TestImport_FromFS tests that imports, includes and base templates are read from config.FS
+func TestImport_FromFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"reports/main.gxl": {Data: []byte(`<Book extends="layout/base.gxl">
+  <Block name="body"><Grid>| {{ total }} |</Grid></Block>
+  <Import src="../shared/summary.gxl" sheet="Summary" />
+</Book>`)},
+		"reports/layout/base.gxl": {Data: []byte(`<Book name="Report">
+  <Sheet name="Main">
+    <Block name="body" />
+    <Include src="parts.gxl" fragment="Footer" />
+  </Sheet>
+</Book>`)},
+		"reports/layout/parts.gxl": {Data: []byte(`<Book><Fragment name="Footer"><Grid>| Footer |</Grid></Fragment></Book>`)},
+		"shared/summary.gxl":       {Data: []byte(`<Book><Sheet name="Summary"><Grid>| Summary |</Grid></Sheet></Book>`)},
+		"escape.gxl":               {Data: []byte(`<Book><Import src="../outside.gxl" sheet="S" /></Book>`)},
+	}
+
+	conf := config.NewBaseConfig()
+	conf.FS = fsys
+	conf.FilePath = "reports/main.gxl"
+	conf.BaseDir = "reports"
+	gxl, err := parser.NewGxlRepository(conf).ReadGxl()
+	if err != nil {
+		t.Fatalf("ReadGxl: %v", err)
+	}
+	book, err := usecase.NewBookUsecase(conf).Render(context.Background(), &gxl, map[string]any{"total": 5})
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if len(book.Sheets) != 2 {
+		t.Fatalf("sheets = %d, want 2", len(book.Sheets))
+	}
+	want := map[string]string{"A1": "5", "A2": "Footer"}
+	if diff := cmp.Diff(want, sheetValues(book.Sheets[0])); diff != "" {
+		t.Errorf("main sheet mismatch (-want +got):\n%s", diff)
+	}
+	if book.Sheets[1].Name != "Summary" {
+		t.Errorf("second sheet = %q, want Summary", book.Sheets[1].Name)
+	}
+
+	conf.FilePath = "escape.gxl"
+	conf.BaseDir = "."
+	gxl, err = parser.NewGxlRepository(conf).ReadGxl()
+	if err != nil {
+		t.Fatalf("ReadGxl: %v", err)
+	}
+	_, err = usecase.NewBookUsecase(conf).Render(context.Background(), &gxl, nil)
+	if err == nil || !strings.Contains(err.Error(), `path "../outside.gxl" is outside the template filesystem`) {
+		t.Errorf("err = %v, want outside-filesystem error", err)
+	}
+}
+
+// countingFS counts how often each file of an fs.FS is opened
+type countingFS struct {
+	fs.FS
+	opens map[string]int
+}
+
+func (c *countingFS) Open(name string) (fs.File, error) {
+	c.opens[name]++
+	return c.FS.Open(name)
+}
+
+// TestInclude_ParsesFragmentFileOnce tests that a fragment included in every iteration of a
+// <For> is read once, both when rendering and when extracting
+func TestInclude_ParsesFragmentFileOnce(t *testing.T) {
+	fsys := &countingFS{FS: fstest.MapFS{
+		"parts.gxl": {Data: []byte(`<Book><Fragment name="Line"><Grid>| {{ it.name }} |</Grid></Fragment></Book>`)},
+	}, opens: map[string]int{}}
+	conf := config.NewBaseConfig()
+	conf.FS = fsys
+	gxl := &model.GXL{Sheets: []model.SheetTag{{Name: "S", Nodes: []any{
+		model.ForTag{Each: "it in items", Body: []any{model.IncludeTag{Src: "parts.gxl", Fragment: "Line"}}},
+	}}}}
+	var items []any
+	for i := 0; i < 20; i++ {
+		items = append(items, map[string]any{"name": fmt.Sprintf("item %d", i)})
+	}
+
+	book, err := usecase.NewBookUsecase(conf).Render(context.Background(), gxl, map[string]any{"items": items})
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if got := len(book.Sheets[0].Cells); got != 20 {
+		t.Fatalf("cells = %d, want 20", got)
+	}
+	if fsys.opens["parts.gxl"] != 1 {
+		t.Errorf("parts.gxl opened %d times while rendering, want 1", fsys.opens["parts.gxl"])
+	}
+
+	fsys.opens = map[string]int{}
+	data, err := usecase.NewExtractUsecase(conf).ExtractBook(gxl, book)
+	if err != nil {
+		t.Fatalf("ExtractBook: %v", err)
+	}
+	if got := len(data["items"].([]any)); got != 20 {
+		t.Errorf("extracted items = %d, want 20", got)
+	}
+	if fsys.opens["parts.gxl"] != 1 {
+		t.Errorf("parts.gxl opened %d times while extracting, want 1", fsys.opens["parts.gxl"])
+	}
+}
+
+// TestImport_FileLimitCountsDistinctFiles tests that MaxImportFiles counts each file once, and
+// that the imports of an imported file, which are not rendered, do not count at all
+func TestImport_FileLimitCountsDistinctFiles(t *testing.T) {
+	conf := config.NewBaseConfig()
+	conf.FS = fstest.MapFS{
+		"line.gxl":    {Data: []byte(`<Book><Fragment name="Line"><Grid>| {{ it }} |</Grid></Fragment></Book>`)},
+		"total.gxl":   {Data: []byte(`<Book><Fragment name="Total"><Grid>| Total |</Grid></Fragment></Book>`)},
+		"summary.gxl": {Data: []byte(`<Book><Import src="shared.gxl" sheet="Shared" /><Sheet name="Summary"><Grid>| Summary |</Grid></Sheet></Book>`)},
+		"shared.gxl":  {Data: []byte(`<Book><Sheet name="Shared"><Grid>| Shared |</Grid></Sheet></Book>`)},
+	}
+	conf.Limits.MaxImportFiles = 1
+	items := make([]any, 20)
+	for i := range items {
+		items[i] = i
+	}
+	data := map[string]any{"items": items}
+
+	gxl := &model.GXL{Sheets: []model.SheetTag{{Name: "S", Nodes: []any{
+		model.ForTag{Each: "it in items", Body: []any{model.IncludeTag{Src: "line.gxl", Fragment: "Line"}}},
+	}}}}
+	if _, err := usecase.NewBookUsecase(conf).Render(context.Background(), gxl, data); err != nil {
+		t.Fatalf("Render with one file included 20 times: %v", err)
+	}
+
+	gxl = &model.GXL{Imports: []model.ImportTag{{Src: "summary.gxl", Sheet: "Summary"}}}
+	if _, err := usecase.NewBookUsecase(conf).Render(context.Background(), gxl, data); err != nil {
+		t.Fatalf("Render with an import whose own imports are not rendered: %v", err)
+	}
+
+	gxl = &model.GXL{Sheets: []model.SheetTag{{Name: "S", Nodes: []any{
+		model.IncludeTag{Src: "line.gxl", Fragment: "Line"},
+		model.IncludeTag{Src: "total.gxl", Fragment: "Total"},
+	}}}}
+	_, err := usecase.NewBookUsecase(conf).Render(context.Background(), gxl, data)
+	var limitErr *config.LimitError
+	if !errors.As(err, &limitErr) || limitErr.Limit != config.LimitImportFiles {
+		t.Errorf("err = %v, want import file limit error for two distinct files", err)
+	}
+}
+
+// TestImport_OwnImportsOfImportedFileAreNotRead tests that the imports of an imported file are
+// not read: one that points back to the importing file or to a missing file does not fail the
+// render
+func TestImport_OwnImportsOfImportedFileAreNotRead(t *testing.T) {
+	fsys := &countingFS{FS: fstest.MapFS{
+		"main.gxl": {Data: []byte(`<Book><Import src="shared.gxl" sheet="Shared" /><Import src="other.gxl" sheet="Other" /></Book>`)},
+		"shared.gxl": {Data: []byte(`<Book><Import src="main.gxl" sheet="Main" /><Import src="missing.gxl" sheet="Gone" />
+<Sheet name="Shared"><Grid>| Shared |</Grid></Sheet></Book>`)},
+		"other.gxl": {Data: []byte(`<Book><Import src="shared.gxl" sheet="Shared" /><Sheet name="Other"><Grid>| Other |</Grid></Sheet></Book>`)},
+	}, opens: map[string]int{}}
+	conf := config.NewBaseConfig()
+	conf.FS = fsys
+	conf.FilePath = "main.gxl"
+	gxl, err := parser.NewGxlRepository(conf).ReadGxl()
+	if err != nil {
+		t.Fatalf("ReadGxl: %v", err)
+	}
+	fsys.opens = map[string]int{}
+
+	book, err := usecase.NewBookUsecase(conf).Render(context.Background(), &gxl, nil)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if len(book.Sheets) != 2 || book.Sheets[0].Name != "Shared" || book.Sheets[1].Name != "Other" {
+		t.Errorf("sheets = %d, want Shared and Other", len(book.Sheets))
+	}
+	want := map[string]int{"shared.gxl": 1, "other.gxl": 1}
+	if diff := cmp.Diff(want, fsys.opens); diff != "" {
+		t.Errorf("opened files mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,88 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ryo-arima/goxcel/pkg/config"
+	"github.com/ryo-arima/goxcel/pkg/model"
+	"github.com/ryo-arima/goxcel/pkg/usecase"
+)
+
+// TestParams_DefaultsApplied tests that defaults fill missing values without modifying the caller's data
+func TestParams_DefaultsApplied(t *testing.T) {
+	data := map[string]any{
+		"invoice": map[string]any{"number": "INV-1"},
+		"items":   []any{},
+	}
+	book, err := renderTemplateFile(t, "params_invoice.gxl", data)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	want := map[string]string{"A1": "INV-1", "B1": "draft", "C1": "0.1"}
+	if diff := cmp.Diff(want, sheetValues(book.Sheets[0])); diff != "" {
+		t.Fatalf("cells mismatch (-want +got):\n%s", diff)
+	}
+	if _, ok := data["invoice"].(map[string]any)["status"]; ok {
+		t.Errorf("caller data was modified: %v", data)
+	}
+}
+
+// TestParams_Violations tests that all violations are reported together
+func TestParams_Violations(t *testing.T) {
+	data := map[string]any{
+		"invoice": map[string]any{"number": 42, "status": "void", "date": "31/12/2026"},
+		"taxRate": "high",
+	}
+	_, err := renderTemplateFile(t, "params_invoice.gxl", data)
+	var perr *usecase.ParamError
+	if !errors.As(err, &perr) {
+		t.Fatalf("err = %v, want *usecase.ParamError", err)
+	}
+	want := []string{
+		"invoice.number: expected string, got number",
+		`invoice.status: "void" is not one of draft, sent, paid`,
+		"invoice.date: expected date, got string",
+		"items: required",
+		"taxRate: expected number, got string",
+	}
+	if diff := cmp.Diff(want, perr.Problems); diff != "" {
+		t.Fatalf("problems mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// TestParams_InvalidDefault tests that a default that does not match its type is reported
+func TestParams_InvalidDefault(t *testing.T) {
+	gxl := &model.GXL{HeaderTag: model.HeaderTag{Params: []model.ParamTag{{Name: "n", Type: model.ParamTypeInteger, Default: "x"}}}}
+	_, err := usecase.NewBookUsecase(config.NewBaseConfig()).Render(context.Background(), gxl, map[string]any{})
+	if err == nil || !contains(err.Error(), `n: invalid default "x"`) {
+		t.Fatalf("err = %v, want invalid default error", err)
+	}
+}
+
+// TestDescribe_JSONSchema tests the JSON Schema produced for nested parameters
+func TestDescribe_JSONSchema(t *testing.T) {
+	path := filepath.Join("..", ".testdata", "params_invoice.gxl")
+	u := usecase.NewDescribeUsecase(config.NewBaseConfigWithFile(path))
+	desc, err := u.Describe(path)
+	if err != nil {
+		t.Fatalf("Describe: %v", err)
+	}
+	if len(desc.Params) != 5 || desc.Name != "Invoice" {
+		t.Fatalf("unexpected description: %+v", desc)
+	}
+
+	schema := u.JSONSchema(desc)
+	if diff := cmp.Diff([]string{"invoice", "items"}, schema["required"]); diff != "" {
+		t.Errorf("required mismatch (-want +got):\n%s", diff)
+	}
+	invoice := schema["properties"].(map[string]any)["invoice"].(map[string]any)
+	status := invoice["properties"].(map[string]any)["status"]
+	want := map[string]any{"type": "string", "default": "draft", "enum": []any{"draft", "sent", "paid"}}
+	if diff := cmp.Diff(want, status); diff != "" {
+		t.Errorf("status schema mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,147 @@
+package usecase_test
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ryo-arima/goxcel/pkg/config"
+	"github.com/ryo-arima/goxcel/pkg/usecase"
+)
+
+func loadInvoiceSchema(t *testing.T) any {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join("..", ".testdata", "invoice.schema.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema any
+	if err := json.Unmarshal(b, &schema); err != nil {
+		t.Fatal(err)
+	}
+	return schema
+}
+
+// TestSchema_ValidData tests that YAML-style Go values (ints, nested maps) are accepted
+func TestSchema_ValidData(t *testing.T) {
+	data := map[string]any{
+		"invoice": map[string]any{"number": "INV-1", "date": "2026-01-31"},
+		"items":   []any{map[string]any{"description": "Widget", "amount": 3}},
+	}
+	if err := usecase.NewSchemaUsecase(config.NewBaseConfig()).Validate(loadInvoiceSchema(t), data); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+}
+
+// TestSchema_ReportsAllViolations tests that every violation is reported with its JSON pointer
+func TestSchema_ReportsAllViolations(t *testing.T) {
+	data := map[string]any{
+		"invoice": map[string]any{"number": "42", "date": "31/01/2026"},
+		"items": []any{
+			map[string]any{"description": "Widget", "amount": -1},
+			map[string]any{"amount": "3", "colour/size": "red"},
+		},
+	}
+	err := usecase.NewSchemaUsecase(config.NewBaseConfig()).Validate(loadInvoiceSchema(t), data)
+	var serr *usecase.SchemaError
+	if !errors.As(err, &serr) {
+		t.Fatalf("err = %v, want *usecase.SchemaError", err)
+	}
+	want := []usecase.SchemaViolation{
+		{Pointer: "/invoice/date", Message: `"31/01/2026" is not a valid date`},
+		{Pointer: "/invoice/number", Message: `"42" does not match pattern "^INV-[0-9]+$"`},
+		{Pointer: "/items/0/amount", Message: "-1 is less than minimum 0"},
+		{Pointer: "/items/1", Message: `missing required property "description"`},
+		{Pointer: "/items/1/amount", Message: "expected number, got string"},
+		{Pointer: "/items/1/colour~1size", Message: `additional property "colour/size" is not allowed`},
+	}
+	if diff := cmp.Diff(want, serr.Violations); diff != "" {
+		t.Fatalf("violations mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// TestSchema_Combinators tests anyOf, oneOf, not, const, enum and if/then/else
+func TestSchema_Combinators(t *testing.T) {
+	schema := map[string]any{
+		"properties": map[string]any{
+			"id":     map[string]any{"anyOf": []any{map[string]any{"type": "string"}, map[string]any{"type": "integer"}}},
+			"kind":   map[string]any{"enum": []any{"a", "b"}},
+			"ver":    map[string]any{"const": 2},
+			"name":   map[string]any{"not": map[string]any{"const": ""}},
+			"amount": map[string]any{"oneOf": []any{map[string]any{"type": "number"}, map[string]any{"type": "integer"}}},
+		},
+		"if":   map[string]any{"properties": map[string]any{"kind": map[string]any{"const": "b"}}},
+		"then": map[string]any{"required": []any{"extra"}},
+	}
+	data := map[string]any{"id": 1.5, "kind": "b", "ver": 2.0, "name": "", "amount": 3}
+	err := usecase.NewSchemaUsecase(config.NewBaseConfig()).Validate(schema, data)
+	var serr *usecase.SchemaError
+	if !errors.As(err, &serr) {
+		t.Fatalf("err = %v, want *usecase.SchemaError", err)
+	}
+	want := []usecase.SchemaViolation{
+		{Pointer: "/amount", Message: "value must match exactly one schema in oneOf (matched 2)"},
+		{Pointer: "/id", Message: "value does not match any schema in anyOf"},
+		{Pointer: "/name", Message: "value must not match the schema in not"},
+		{Pointer: "", Message: `missing required property "extra"`},
+	}
+	if diff := cmp.Diff(want, serr.Violations); diff != "" {
+		t.Fatalf("violations mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// TestSchema_RejectsUnsupportedKeywords tests that keywords the validator does not implement
+// fail validation instead of being ignored
+func TestSchema_RejectsUnsupportedKeywords(t *testing.T) {
+	data := map[string]any{"invoice": map[string]any{"number": "INV-1", "extra": true}}
+	for _, tc := range []struct {
+		schema  map[string]any
+		wantErr string
+	}{
+		{
+			schema: map[string]any{"properties": map[string]any{"invoice": map[string]any{
+				"properties":            map[string]any{"number": map[string]any{"type": "string"}},
+				"unevaluatedProperties": false,
+			}}},
+			wantErr: `schema: keyword "unevaluatedProperties" at #/properties/invoice is not supported`,
+		},
+		{
+			schema:  map[string]any{"type": "array", "unevaluatedItems": false},
+			wantErr: `keyword "unevaluatedItems" at # is not supported`,
+		},
+		{
+			schema:  map[string]any{"dependentSchemas": map[string]any{"invoice": map[string]any{"required": []any{"total"}}}},
+			wantErr: `keyword "dependentSchemas" at # is not supported`,
+		},
+		{
+			schema:  map[string]any{"$defs": map[string]any{"item": map[string]any{"$anchor": "item"}}},
+			wantErr: `keyword "$anchor" at #/$defs/item is not supported`,
+		},
+		{
+			schema:  map[string]any{"allOf": []any{map[string]any{"$dynamicRef": "#meta"}}},
+			wantErr: `keyword "$dynamicRef" at #/allOf/0 is not supported`,
+		},
+		{
+			schema:  map[string]any{"items": map[string]any{"$ref": "https://example.com/item.json"}},
+			wantErr: `$ref "https://example.com/item.json" at #/items is not supported`,
+		},
+	} {
+		err := usecase.NewSchemaUsecase(config.NewBaseConfig()).Validate(tc.schema, data)
+		if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+			t.Errorf("err = %v, want containing %q", err, tc.wantErr)
+		}
+	}
+
+	// Annotations and a root $id are accepted
+	schema := map[string]any{
+		"$id": "https://example.com/invoice.json", "title": "Invoice", "description": "An invoice",
+		"properties": map[string]any{"invoice": map[string]any{"type": "object", "default": map[string]any{}, "examples": []any{}}},
+	}
+	if err := usecase.NewSchemaUsecase(config.NewBaseConfig()).Validate(schema, data); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+}
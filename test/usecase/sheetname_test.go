@@ -0,0 +1,97 @@
+package usecase_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ryo-arima/goxcel/pkg/config"
+	"github.com/ryo-arima/goxcel/pkg/model"
+	"github.com/ryo-arima/goxcel/pkg/usecase"
+)
+
+func sheetNames(b *model.Book) []string {
+	var names []string
+	for _, s := range b.Sheets {
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+func TestBookUsecase_Render_SheetNameErrorPolicy(t *testing.T) {
+	cases := []struct {
+		name    string
+		sheets  []string
+		wantErr string
+	}{
+		{name: "empty", sheets: []string{""}, wantErr: "empty"},
+		{name: "invalid char", sheets: []string{"Q1/Q2"}, wantErr: "invalid character"},
+		{name: "too long", sheets: []string{strings.Repeat("x", 32)}, wantErr: "max 31"},
+		{name: "apostrophe", sheets: []string{"'quoted'"}, wantErr: "apostrophe"},
+		{name: "duplicate", sheets: []string{"Data", "data"}, wantErr: "duplicate"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gxl := &model.GXL{}
+			for _, n := range tc.sheets {
+				gxl.Sheets = append(gxl.Sheets, model.SheetTag{Name: n})
+			}
+			uc := usecase.NewBookUsecase(config.NewBaseConfig())
+			_, err := uc.Render(context.Background(), gxl, nil)
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("err = %v, want containing %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestBookUsecase_Render_SheetNameSuffixPolicy(t *testing.T) {
+	gxl := &model.GXL{Sheets: []model.SheetTag{
+		{Name: "Sheet"},
+		{Name: "Sheet"},
+		{Name: "sheet"},
+		{Name: "Q1/Q2"},
+		{Name: ""},
+		{Name: strings.Repeat("y", 31)},
+		{Name: strings.Repeat("y", 31)},
+	}}
+	conf := config.NewBaseConfig()
+	conf.SheetNamePolicy = config.SheetNamePolicySuffix
+	book, err := usecase.NewBookUsecase(conf).Render(context.Background(), gxl, nil)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	want := []string{
+		"Sheet",
+		"Sheet (2)",
+		"sheet (3)",
+		"Q1_Q2",
+		"Sheet (4)",
+		strings.Repeat("y", 31),
+		strings.Repeat("y", 27) + " (2)",
+	}
+	if diff := cmp.Diff(want, sheetNames(book)); diff != "" {
+		t.Fatalf("sheet names mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestBookUsecase_Render_SheetNameMustache(t *testing.T) {
+	gxl := &model.GXL{Sheets: []model.SheetTag{{Name: "Sales {{ region }}"}}}
+	book, err := usecase.NewBookUsecase(config.NewBaseConfig()).Render(context.Background(), gxl, map[string]any{"region": "EMEA"})
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if diff := cmp.Diff([]string{"Sales EMEA"}, sheetNames(book)); diff != "" {
+		t.Fatalf("sheet names mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestBookUsecase_Render_SheetNameUnknownPolicy(t *testing.T) {
+	// The policy is rejected even when the template creates no sheets
+	conf := config.NewBaseConfig()
+	conf.SheetNamePolicy = "rename"
+	if _, err := usecase.NewBookUsecase(conf).Render(context.Background(), &model.GXL{}, nil); err == nil {
+		t.Fatal("expected an error for an unknown sheet name policy")
+	}
+}